@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// downloadedAtFile records when a job's download finished. yt-dlp sets the
+	// media file's mtime to the upload date, so file mtimes say nothing about
+	// how long a file has been sitting in tempDir.
+	downloadedAtFile = ".downloaded_at"
+
+	// jobMaxAge is how long a finished job directory may linger (e.g. after a
+	// client disconnect or a crash) before the janitor removes it
+	jobMaxAge = time.Hour
+
+	// janitorInterval is how often the janitor sweeps tempDir
+	janitorInterval = 10 * time.Minute
+)
+
+// liveJobDirs are the job directories still being downloaded into or streamed
+// from. The janitor never removes them, however old their recorded time is.
+var (
+	liveJobDirs      = make(map[string]bool)
+	liveJobDirsMutex sync.Mutex
+)
+
+// markJobDirLive protects dir from the janitor until unmarkJobDir
+func markJobDirLive(dir string) {
+	liveJobDirsMutex.Lock()
+	defer liveJobDirsMutex.Unlock()
+	liveJobDirs[dir] = true
+}
+
+// unmarkJobDir makes dir eligible for cleanup again
+func unmarkJobDir(dir string) {
+	liveJobDirsMutex.Lock()
+	defer liveJobDirsMutex.Unlock()
+	delete(liveJobDirs, dir)
+}
+
+// recordDownloadTime writes the download completion time into the job directory
+func recordDownloadTime(dir string, at time.Time) {
+	path := filepath.Join(dir, downloadedAtFile)
+	if err := os.WriteFile(path, []byte(at.UTC().Format(time.RFC3339)), 0644); err != nil {
+		log.Printf("Warning: Failed to record download time in %s: %v", dir, err)
+	}
+}
+
+// jobTime returns when the job in dir finished downloading. Jobs without a
+// recorded time (still downloading, or failed) fall back to the directory's
+// own mtime, which is unaffected by yt-dlp's mtime on the files inside it.
+func jobTime(dir string) (time.Time, error) {
+	if data, err := os.ReadFile(filepath.Join(dir, downloadedAtFile)); err == nil {
+		if at, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data))); err == nil {
+			return at, nil
+		}
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// sweepJobDirs removes job directories in root whose job finished before
+// cutoff, skipping those still in use
+func sweepJobDirs(root string, cutoff time.Time) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		log.Printf("Warning: Janitor could not read %s: %v", root, err)
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "job-") {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		at, err := jobTime(dir)
+		if err != nil || at.After(cutoff) {
+			continue
+		}
+		// Checked under the lock so a request can't pick the directory up mid-removal
+		liveJobDirsMutex.Lock()
+		if !liveJobDirs[dir] {
+			if err := os.RemoveAll(dir); err != nil {
+				log.Printf("Warning: Failed to clean up job directory %s: %v", dir, err)
+			}
+		}
+		liveJobDirsMutex.Unlock()
+	}
+}
+
+// startJanitor periodically removes job directories older than jobMaxAge
+func startJanitor() {
+	go func() {
+		for {
+			sweepJobDirs(tempDir, time.Now().Add(-jobMaxAge))
+			time.Sleep(janitorInterval)
+		}
+	}()
+}
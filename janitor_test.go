@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// oldJobDir creates a job directory in root holding a media file, with the
+// download recorded at recorded (zero: not recorded) and the file's mtime set
+// to fileTime as yt-dlp does with the upload date
+func oldJobDir(t *testing.T, root string, recorded time.Time, fileTime time.Time) string {
+	t.Helper()
+	dir, err := os.MkdirTemp(root, "job-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(file, []byte("media"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, fileTime, fileTime); err != nil {
+		t.Fatal(err)
+	}
+	if !recorded.IsZero() {
+		recordDownloadTime(dir, recorded)
+	}
+	return dir
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestSweepUsesRecordedTime(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	uploaded := now.AddDate(-10, 0, 0)
+
+	// Downloaded a minute ago, but the file carries its ten-year-old upload date
+	fresh := oldJobDir(t, root, now.Add(-time.Minute), uploaded)
+	// Downloaded two hours ago, file mtime is recent
+	stale := oldJobDir(t, root, now.Add(-2*time.Hour), now)
+	// Nothing recorded: the directory's own mtime counts
+	unrecorded := oldJobDir(t, root, time.Time{}, now)
+	if err := os.Chtimes(unrecorded, now.Add(-2*time.Hour), now.Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// Not a job directory
+	other := filepath.Join(root, "keep")
+	if err := os.Mkdir(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(other, uploaded, uploaded); err != nil {
+		t.Fatal(err)
+	}
+
+	sweepJobDirs(root, now.Add(-jobMaxAge))
+
+	if !exists(fresh) {
+		t.Error("removed a fresh download because of its file mtime")
+	}
+	if exists(stale) {
+		t.Error("kept a download recorded two hours ago")
+	}
+	if exists(unrecorded) {
+		t.Error("kept an old job without a recorded time")
+	}
+	if !exists(other) {
+		t.Error("removed a directory that is not a job")
+	}
+}
+
+func TestSweepSkipsLiveJobDirs(t *testing.T) {
+	useTempDir(t)
+	dir, err := newJobDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A long download or a slow client: old recorded time, still in use
+	recordDownloadTime(dir, time.Now().Add(-3*time.Hour))
+
+	sweepJobDirs(tempDir, time.Now().Add(-jobMaxAge))
+	if !exists(dir) {
+		t.Fatal("removed a job directory still in use")
+	}
+
+	removeJobDir(dir)
+	liveJobDirsMutex.Lock()
+	live := liveJobDirs[dir]
+	liveJobDirsMutex.Unlock()
+	if exists(dir) || live {
+		t.Errorf("job directory not cleaned up on release (exists %v, live %v)", exists(dir), live)
+	}
+}
+
+func TestDownloadSharedRefreshesJobTime(t *testing.T) {
+	if !dedupeDownloads {
+		t.Skip("DEDUPE_DOWNLOADS=0")
+	}
+	useTempDir(t)
+	download := func(ctx context.Context, dir string) (string, error) {
+		path := filepath.Join(dir, "video.mp4")
+		return path, os.WriteFile(path, []byte("media"), 0644)
+	}
+
+	path, release, err := downloadShared(context.Background(), "refresh", download)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	dir := filepath.Dir(path)
+	recordDownloadTime(dir, time.Now().Add(-2*time.Hour))
+
+	// A second request for the same file starts streaming it now
+	_, releaseSecond, err := downloadShared(context.Background(), "refresh", download)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseSecond()
+	if at, err := jobTime(dir); err != nil || time.Since(at) > time.Minute {
+		t.Errorf("job time %v, %v; want refreshed to now", at, err)
+	}
+}
@@ -1,32 +1,50 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"youtube-api-server/pkg/downloader"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"youtube-api-server/pkg/downloader"
 )
 
 type MetadataResponse struct {
-	Success        bool                      `json:"success"`
-	Metadata       *downloader.VideoMetadata `json:"metadata,omitempty"`
-	DownloadURL    string                    `json:"download_url,omitempty"` // Direct YouTube download URL
-	Error          string                    `json:"error,omitempty"`
+	Success     bool                      `json:"success"`
+	Metadata    *downloader.VideoMetadata `json:"metadata,omitempty"`
+	DownloadURL string                    `json:"download_url,omitempty"` // Direct YouTube download URL
+	AudioURL    string                    `json:"audio_url,omitempty"`    // Only set for adaptive formats
+	Error       string                    `json:"error,omitempty"`
 }
 
 type DownloadResponse struct {
-	Success      bool                      `json:"success"`
-	DownloadURL  string                    `json:"download_url,omitempty"`
-	FilePath     string                    `json:"file_path,omitempty"` // Expected filename
-	Metadata     *downloader.VideoMetadata `json:"metadata,omitempty"`
-	Error        string                    `json:"error,omitempty"`
+	Success       bool                      `json:"success"`
+	DownloadURL   string                    `json:"download_url,omitempty"`
+	FilePath      string                    `json:"file_path,omitempty"` // Expected filename
+	Metadata      *downloader.VideoMetadata `json:"metadata,omitempty"`
+	Formats       []downloader.FormatInfo   `json:"formats,omitempty"`        // Available formats, worst to best
+	EstimatedSize int64                     `json:"estimated_size,omitempty"` // Bytes for the requested quality; omitted if unknown
+	Error         string                    `json:"error,omitempty"`
+}
+
+type StreamURLResponse struct {
+	Success   bool       `json:"success"`
+	VideoURL  string     `json:"video_url,omitempty"`
+	AudioURL  string     `json:"audio_url,omitempty"`  // Only set for adaptive formats
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // When the URLs stop working
+	Error     string     `json:"error,omitempty"`
 }
 
 type DownloadRequest struct {
@@ -36,9 +54,144 @@ type DownloadRequest struct {
 	Codec      string `json:"codec,omitempty"`      // avc1, vp9, etc.
 }
 
+type AudioDownloadRequest struct {
+	URL     string `json:"url"`
+	Format  string `json:"format,omitempty"`  // mp3, m4a, opus, etc.
+	Bitrate string `json:"bitrate,omitempty"` // 128k, 192k, etc.
+}
+
 // Store for temporary downloaded files (cleaned up after streaming)
 var tempDir = "./temp_downloads"
 
+// sharedDownload is one in-flight or finished download shared by identical requests
+type sharedDownload struct {
+	done chan struct{} // Closed once path/err are set
+	dir  string        // Job directory holding the download
+	path string
+	err  error
+	refs int // Requests still using the file; the last one removes the job directory
+
+	// cancel stops the download once every request waiting for it went away
+	cancel context.CancelFunc
+}
+
+// activeDownloads dedupes concurrent identical downloads, keyed by URL+format.
+// Disable with DEDUPE_DOWNLOADS=0
+var (
+	activeDownloads      = make(map[string]*sharedDownload)
+	activeDownloadsMutex sync.Mutex
+	dedupeDownloads      = os.Getenv("DEDUPE_DOWNLOADS") != "0"
+)
+
+// newJobDir creates a unique subdirectory of tempDir for one download job, so
+// concurrent jobs never see each other's files. It stays safe from the janitor
+// until removeJobDir.
+func newJobDir() (string, error) {
+	dir, err := os.MkdirTemp(tempDir, "job-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create job directory: %w", err)
+	}
+	markJobDirLive(dir)
+	return dir, nil
+}
+
+// downloadFunc downloads into dir and returns the file's path; cancelling ctx aborts it
+type downloadFunc func(ctx context.Context, dir string) (string, error)
+
+// runJob downloads into a fresh job directory
+func runJob(ctx context.Context, download downloadFunc) (string, string, error) {
+	dir, err := newJobDir()
+	if err != nil {
+		return "", "", err
+	}
+	path, err := download(ctx, dir)
+	if err == nil {
+		recordDownloadTime(dir, time.Now())
+	}
+	return dir, path, err
+}
+
+// downloadShared runs download once for concurrent requests with the same key.
+// download receives its own job directory under tempDir. Every caller gets the
+// same file and must call release when done with it; the job directory is
+// removed after the last release. A caller whose ctx is cancelled (e.g. the
+// client disconnected) stops waiting with ctx's error; once every caller is
+// gone the download itself is cancelled and its job directory removed.
+func downloadShared(ctx context.Context, key string, download downloadFunc) (string, func(), error) {
+	if !dedupeDownloads {
+		dir, path, err := runJob(ctx, download)
+		return path, func() { removeJobDir(dir) }, err
+	}
+
+	activeDownloadsMutex.Lock()
+	shared, ok := activeDownloads[key]
+	if !ok {
+		// The download outlives the request that started it while others wait for it
+		jobCtx, cancel := context.WithCancel(context.Background())
+		shared = &sharedDownload{done: make(chan struct{}), cancel: cancel}
+		activeDownloads[key] = shared
+		go func() {
+			defer cancel()
+			dir, path, err := runJob(jobCtx, download)
+
+			activeDownloadsMutex.Lock()
+			shared.dir, shared.path, shared.err = dir, path, err
+			close(shared.done)
+			abandoned := shared.refs == 0
+			activeDownloadsMutex.Unlock()
+
+			if abandoned {
+				removeJobDir(dir)
+			}
+		}()
+	}
+	shared.refs++
+	activeDownloadsMutex.Unlock()
+
+	release := func() {
+		activeDownloadsMutex.Lock()
+		defer activeDownloadsMutex.Unlock()
+		shared.refs--
+		if shared.refs > 0 {
+			return
+		}
+		// Requests arriving after this point start a fresh download
+		if activeDownloads[key] == shared {
+			delete(activeDownloads, key)
+		}
+		select {
+		case <-shared.done:
+			removeJobDir(shared.dir)
+		default:
+			// Nobody is waiting anymore; the job removes its directory once it stops
+			shared.cancel()
+		}
+	}
+
+	select {
+	case <-shared.done:
+		if shared.err == nil {
+			// Another request is now streaming the file; restart its clock for the janitor
+			recordDownloadTime(shared.dir, time.Now())
+		}
+		return shared.path, release, shared.err
+	case <-ctx.Done():
+		release()
+		return "", func() {}, ctx.Err()
+	}
+}
+
+// removeJobDir deletes a job directory and everything in it, logging failures
+func removeJobDir(dir string) {
+	if dir == "" {
+		return
+	}
+	defer unmarkJobDir(dir)
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("Warning: Failed to clean up job directory %s: %v", dir, err)
+	}
+}
+
 func init() {
 	// Create temp directory if it doesn't exist
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
@@ -71,6 +224,9 @@ func main() {
 		}
 	}()
 
+	// Remove job directories left behind by disconnected clients or crashes
+	startJanitor()
+
 	router := gin.Default()
 
 	// CORS middleware
@@ -80,16 +236,22 @@ func main() {
 	config.AllowHeaders = []string{"Content-Type", "Authorization"}
 	router.Use(cors.New(config))
 
+	// Per-IP rate limits: downloads are expensive, metadata and health checks are cheap
+	downloadLimit := newRateLimiter(intFromEnv("RATE_LIMIT_PER_MINUTE", 10)).middleware()
+	lightLimit := newRateLimiter(intFromEnv("LIGHT_RATE_LIMIT_PER_MINUTE", 60)).middleware()
+
 	// API routes
 	api := router.Group("/api")
 	{
-		api.GET("/metadata", getMetadataHandler)
-		api.POST("/download", downloadStreamHandler)
-		api.POST("/download-info", downloadInfoHandler)
+		api.GET("/metadata", lightLimit, getMetadataHandler)
+		api.POST("/download", downloadLimit, downloadStreamHandler)
+		api.POST("/download-info", downloadLimit, downloadInfoHandler)
+		api.POST("/download-audio", downloadLimit, downloadAudioStreamHandler)
+		api.GET("/stream-url", downloadLimit, streamURLHandler)
 	}
 
 	// Health check
-	router.GET("/health", func(c *gin.Context) {
+	router.GET("/health", lightLimit, func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
@@ -123,117 +285,100 @@ func getMetadataHandler(c *gin.Context) {
 		return
 	}
 
+	format := c.DefaultQuery("format", "best")
+	if err := downloader.ValidateFormatSelector(format); err != nil {
+		c.JSON(400, MetadataResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Invalid format: %v", err),
+		})
+		return
+	}
+
 	// Fetch metadata
 	metadata, err := downloader.GetVideoMetadata(url)
 	if err != nil {
-		c.JSON(500, MetadataResponse{
+		c.JSON(errorStatus(c, err), MetadataResponse{
 			Success: false,
 			Error:   fmt.Sprintf("Failed to fetch metadata: %v", err),
 		})
 		return
 	}
 
-	// Get direct download URL from YouTube
-	downloadURL, err := getDirectDownloadURL(url)
-	if err != nil {
-		log.Printf("Warning: Could not get direct download URL: %v", err)
-		// Continue without download URL - metadata is still useful
+	// Upcoming premieres have no media yet; the metadata's scheduled_at and
+	// seconds_until_start let clients show a countdown instead
+	var downloadURL, audioURL string
+	if !metadata.IsUpcoming {
+		// Get direct download URLs from YouTube for the requested format
+		downloadURL, audioURL, err = getDirectDownloadURL(url, format)
+		if err != nil {
+			log.Printf("Warning: Could not get direct download URL: %v", err)
+			// Continue without download URL - metadata is still useful
+		}
 	}
 
 	c.JSON(200, MetadataResponse{
 		Success:     true,
 		Metadata:    metadata,
 		DownloadURL: downloadURL,
+		AudioURL:    audioURL,
 	})
 }
 
-// getDirectDownloadURL gets the direct download URL from YouTube using yt-dlp
-func getDirectDownloadURL(url string) (string, error) {
-	// Ensure binaries are installed
-	if err := ensureBinariesInstalled(); err != nil {
-		return "", fmt.Errorf("failed to ensure binaries are installed: %w", err)
+// streamURLHandler returns direct media URLs so clients can play the video
+// without the server proxying the bytes
+func streamURLHandler(c *gin.Context) {
+	url := c.Query("url")
+	if url == "" {
+		c.JSON(400, StreamURLResponse{
+			Success: false,
+			Error:   "URL parameter is required",
+		})
+		return
 	}
 
-	// Get yt-dlp path - we need to find it
-	ytdlpPath := findYTDLPPath()
-	if ytdlpPath == "" {
-		return "", fmt.Errorf("yt-dlp not found")
-	}
-
-	// Try different clients to get the download URL
-	clients := []string{"android", "android_embedded", "android_music", "ios", "tv_embedded", "web"}
-	var lastErr error
-
-	for _, client := range clients {
-		// Use yt-dlp with -g flag to get direct URL with comprehensive bot detection bypass
-		// -g: Print video URL instead of downloading
-		// -f best: Get best quality format
-		cmd := exec.Command(ytdlpPath,
-			"-g",
-			"-f", "best",
-			"--no-playlist",
-			"--no-warnings",
-			"--extractor-args", fmt.Sprintf("youtube:player_client=%s", client),
-			"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-			"--referer", "https://www.youtube.com/",
-			"--add-header", "Accept-Language:en-US,en;q=0.9",
-			"--add-header", "Accept:text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
-			"--add-header", "Accept-Encoding:gzip, deflate, br",
-			"--add-header", "Connection:keep-alive",
-			"--add-header", "Upgrade-Insecure-Requests:1",
-			"--add-header", "Sec-Fetch-Dest:document",
-			"--add-header", "Sec-Fetch-Mode:navigate",
-			"--add-header", "Sec-Fetch-Site:none",
-			"--add-header", "Sec-Fetch-User:?1",
-			"--add-header", "DNT:1",
-			"--sleep-interval", "1",
-			"--max-sleep-interval", "3",
-			"--no-check-certificate",
-			url,
-		)
-
-		output, err := cmd.Output()
-		if err != nil {
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				lastErr = fmt.Errorf("yt-dlp error with client %s: %s", client, string(exitErr.Stderr))
-				continue
-			}
-			lastErr = fmt.Errorf("failed to execute yt-dlp: %w", err)
-			continue
-		}
+	// Validate YouTube URL
+	if !isValidYouTubeURL(url) {
+		c.JSON(400, StreamURLResponse{
+			Success: false,
+			Error:   "Invalid YouTube URL",
+		})
+		return
+	}
 
-		// yt-dlp may return multiple URLs (video + audio), take the first one
-		urls := strings.Split(strings.TrimSpace(string(output)), "\n")
-		if len(urls) > 0 && urls[0] != "" {
-			return urls[0], nil
-		}
+	format := c.DefaultQuery("format", "best")
+	if err := downloader.ValidateFormatSelector(format); err != nil {
+		c.JSON(400, StreamURLResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Invalid format: %v", err),
+		})
+		return
 	}
 
-	// If all clients failed, try without specifying a client
-	cmd := exec.Command(ytdlpPath,
-		"-g",
-		"-f", "best",
-		"--no-playlist",
-		"--no-warnings",
-		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-		"--referer", "https://www.youtube.com/",
-		"--sleep-interval", "1",
-		"--max-sleep-interval", "3",
-		url,
-	)
-
-	output, err := cmd.Output()
-	if err == nil {
-		urls := strings.Split(strings.TrimSpace(string(output)), "\n")
-		if len(urls) > 0 && urls[0] != "" {
-			return urls[0], nil
-		}
+	urls, err := downloader.GetStreamURL(c.Request.Context(), url, format)
+	if err != nil {
+		c.JSON(errorStatus(c, err), StreamURLResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to get stream URL: %v", err),
+		})
+		return
 	}
 
-	if lastErr != nil {
-		return "", lastErr
+	c.JSON(200, StreamURLResponse{
+		Success:   true,
+		VideoURL:  urls.VideoURL,
+		AudioURL:  urls.AudioURL,
+		ExpiresAt: urls.ExpiresAt,
+	})
+}
+
+// getDirectDownloadURL gets the direct download URL(s) from YouTube using yt-dlp.
+// An empty format falls back to best, a single pre-muxed file; adaptive
+// formats also return the audio URL.
+func getDirectDownloadURL(url string, format string) (string, string, error) {
+	if format == "" {
+		format = "best"
 	}
-	return "", fmt.Errorf("no download URL found")
+	return downloader.GetDirectURL(url, format)
 }
 
 // findYTDLPPath finds the yt-dlp binary path
@@ -298,48 +443,51 @@ func downloadStreamHandler(c *gin.Context) {
 		return
 	}
 
-	// Set defaults
-	if req.Format == "" {
-		req.Format = "mp4"
-	}
-	if req.Resolution == "" {
-		req.Resolution = "720"
-	}
-	if req.Codec == "" {
-		req.Codec = "avc1"
+	// Set defaults and enforce the allowed formats
+	if err := req.applyDefaults(); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
 	}
 
+	// A client disconnect cancels the request context, which stops the
+	// metadata lookup and the download (once no other request shares it)
+	ctx := c.Request.Context()
+
 	// Fetch metadata first to get video title for filename
-	metadata, err := downloader.GetVideoMetadata(req.URL)
-	var filename string
-	if err == nil && metadata != nil {
-		// Use video title as filename (sanitized)
-		filename = sanitizeFilename(metadata.Title) + "." + req.Format
-	} else {
-		// Fallback to timestamp-based filename
-		filename = fmt.Sprintf("video_%d.%s", time.Now().UnixNano(), req.Format)
-	}
-
-	// Download video to temp directory
-	filePath, err := downloader.DownloadVideoToDir(
-		req.URL,
-		req.Format,
-		req.Resolution,
-		req.Codec,
-		tempDir,
-	)
+	metadataCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	metadata, err := downloader.GetVideoMetadataWithContext(metadataCtx, req.URL)
+	cancel()
+	if err != nil {
+		// Fall back to an ID-based filename
+		metadata = nil
+	}
+	filename := expectedFilename(req, metadata)
+
+	// Download video to temp directory, sharing the download with identical concurrent requests
+	key := strings.Join([]string{req.URL, req.Format, req.Resolution, req.Codec}, "|")
+	filePath, release, err := downloadShared(ctx, key, func(ctx context.Context, dir string) (string, error) {
+		result, err := downloader.DownloadVideoWithOptions(ctx, req.URL, downloader.VideoOptions{
+			Format:     req.Format,
+			Resolution: req.Resolution,
+			Codec:      req.Codec,
+			OutputDir:  dir,
+		})
+		if err != nil {
+			return "", err
+		}
+		return result.Path, nil
+	})
+	// Clean up the job directory after streaming
+	defer release()
+	if ctx.Err() != nil {
+		log.Printf("Client disconnected, stopped serving %s", req.URL)
+		return
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("Failed to download video: %v", err)})
+		c.JSON(errorStatus(c, err), gin.H{"error": fmt.Sprintf("Failed to download video: %v", err)})
 		return
 	}
 
-	// Clean up temp file after streaming
-	defer func() {
-		if err := os.Remove(filePath); err != nil {
-			log.Printf("Warning: Failed to clean up temp file %s: %v", filePath, err)
-		}
-	}()
-
 	// Open the file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -365,6 +513,98 @@ func downloadStreamHandler(c *gin.Context) {
 	c.DataFromReader(200, fileInfo.Size(), "application/octet-stream", file, nil)
 }
 
+// downloadAudioStreamHandler converts the best audio to the requested format and
+// pipes it to the client as it is produced, without a temporary file. A client
+// disconnect cancels the request context, which stops yt-dlp and ffmpeg.
+func downloadAudioStreamHandler(c *gin.Context) {
+	var req AudioDownloadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+
+	if req.URL == "" {
+		c.JSON(400, gin.H{"error": "URL is required"})
+		return
+	}
+
+	// Validate YouTube URL
+	if !isValidYouTubeURL(req.URL) {
+		c.JSON(400, gin.H{"error": "Invalid YouTube URL"})
+		return
+	}
+
+	if req.Format == "" {
+		req.Format = "mp3"
+	}
+	contentType := downloader.AudioContentType(req.Format)
+	if contentType == "" {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("Format %q cannot be streamed", req.Format)})
+		return
+	}
+	if allowedFormats != nil && !allowedFormats[strings.ToLower(req.Format)] {
+		c.JSON(400, gin.H{"error": fmt.Sprintf("format %q is not allowed", req.Format)})
+		return
+	}
+
+	// A client disconnect cancels the request context, which stops the
+	// metadata lookup and the stream
+	ctx := c.Request.Context()
+
+	// Fetch metadata first to get video title for filename
+	metadataCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	metadata, err := downloader.GetVideoMetadataWithContext(metadataCtx, req.URL)
+	cancel()
+	if err != nil {
+		// Fall back to an ID-based filename
+		metadata = nil
+	}
+	filename := expectedFilename(DownloadRequest{URL: req.URL, Format: req.Format}, metadata)
+
+	// Headers can only be sent once, so defer them until audio actually
+	// arrives; failures before that still get a JSON error with a proper status
+	out := &lazyHeaderWriter{c: c, setHeaders: func() {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Transfer-Encoding", "binary")
+		c.Status(200)
+	}}
+	err = downloader.StreamAudio(ctx, req.URL, out, downloader.AudioStreamOptions{
+		Format:  req.Format,
+		Bitrate: req.Bitrate,
+	})
+	if err == nil {
+		return
+	}
+	if ctx.Err() != nil {
+		log.Printf("Audio stream of %s cancelled: client disconnected", req.URL)
+		return
+	}
+	if out.started {
+		// Too late for an error response; the client sees a truncated file
+		log.Printf("Audio stream of %s failed after it started: %v", req.URL, err)
+		return
+	}
+	c.JSON(errorStatus(c, err), gin.H{"error": fmt.Sprintf("Failed to stream audio: %v", err)})
+}
+
+// lazyHeaderWriter calls setHeaders before the first byte reaches the response
+type lazyHeaderWriter struct {
+	c          *gin.Context
+	setHeaders func()
+	started    bool
+}
+
+func (w *lazyHeaderWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.setHeaders()
+	}
+	n, err := w.c.Writer.Write(p)
+	w.c.Writer.Flush()
+	return n, err
+}
+
 // downloadInfoHandler returns metadata and download info without actually downloading
 func downloadInfoHandler(c *gin.Context) {
 	var req DownloadRequest
@@ -393,21 +633,19 @@ func downloadInfoHandler(c *gin.Context) {
 		return
 	}
 
-	// Set defaults
-	if req.Format == "" {
-		req.Format = "mp4"
-	}
-	if req.Resolution == "" {
-		req.Resolution = "720"
-	}
-	if req.Codec == "" {
-		req.Codec = "avc1"
+	// Set defaults and enforce the allowed formats
+	if err := req.applyDefaults(); err != nil {
+		c.JSON(400, DownloadResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	// Fetch metadata
-	metadata, err := downloader.GetVideoMetadata(req.URL)
+	// Fetch metadata and the available formats in one yt-dlp call
+	metadata, formats, err := downloader.GetVideoMetadataWithFormats(req.URL)
 	if err != nil {
-		c.JSON(500, DownloadResponse{
+		c.JSON(errorStatus(c, err), DownloadResponse{
 			Success: false,
 			Error:   fmt.Sprintf("Failed to fetch metadata: %v", err),
 		})
@@ -415,49 +653,106 @@ func downloadInfoHandler(c *gin.Context) {
 	}
 
 	// Generate expected filename
-	var filename string
-	if metadata != nil {
-		filename = sanitizeFilename(metadata.Title) + "." + req.Format
-	} else {
-		filename = fmt.Sprintf("video_%d.%s", time.Now().UnixNano(), req.Format)
-	}
+	filename := expectedFilename(req, metadata)
 
 	c.JSON(200, DownloadResponse{
 		Success:  true,
 		Metadata: metadata,
 		FilePath: filename, // Expected filename
+		Formats:  formats,
+		EstimatedSize: downloader.EstimateSize(formats, downloader.VideoOptions{
+			Format:     req.Format,
+			Resolution: req.Resolution,
+			Codec:      req.Codec,
+		}),
 	})
 }
 
-// sanitizeFilename removes invalid characters from filename
-func sanitizeFilename(name string) string {
-	// Remove invalid characters for filenames
-	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|", "\n", "\r"}
-	result := name
-	for _, char := range invalidChars {
-		result = strings.ReplaceAll(result, char, "_")
-	}
-	// Limit length
-	if len(result) > 100 {
-		result = result[:100]
+// errorStatus maps a downloader error to its HTTP status code.
+// Rate limited errors also set a Retry-After header so clients back off.
+func errorStatus(c *gin.Context, err error) int {
+	switch {
+	case errors.Is(err, downloader.ErrRateLimited):
+		if retryAfter := downloader.RetryAfter(err); retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+		return 429
+	case errors.Is(err, downloader.ErrGeoBlocked), errors.Is(err, downloader.ErrAgeRestricted):
+		return 403
+	case errors.Is(err, downloader.ErrUnavailable):
+		return 404
+	case errors.Is(err, downloader.ErrNotYetAvailable):
+		return 425
+	case errors.Is(err, downloader.ErrDiskFull), errors.Is(err, downloader.ErrInsufficientDiskSpace):
+		return 507
+	default:
+		return 500
 	}
-	return strings.TrimSpace(result)
 }
 
-func isValidYouTubeURL(url string) bool {
-	validPatterns := []string{
-		"youtube.com/watch",
-		"youtu.be/",
-		"youtube.com/embed/",
-		"youtube.com/v/",
-		"youtube.com/shorts/",
-	}
+// youtubeHosts are the hosts isValidYouTubeURL accepts (lowercase, without port)
+var youtubeHosts = map[string]bool{
+	"youtube.com":       true,
+	"www.youtube.com":   true,
+	"m.youtube.com":     true,
+	"music.youtube.com": true,
+	"youtu.be":          true,
+	"www.youtu.be":      true,
+}
+
+// youtubeIDPattern matches a YouTube video ID
+var youtubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// isValidYouTubeURL reports whether rawURL is an http(s) (or scheme-less) link
+// to a single YouTube video (see youtubeVideoID)
+func isValidYouTubeURL(rawURL string) bool {
+	_, ok := youtubeVideoID(rawURL)
+	return ok
+}
 
-	urlLower := strings.ToLower(url)
-	for _, pattern := range validPatterns {
-		if strings.Contains(urlLower, pattern) {
-			return true
+// youtubeVideoID returns the video ID of a youtube.com/watch?v=<id>,
+// youtu.be/<id>, or youtube.com/{embed,v,shorts,live}/<id> link on one of
+// youtubeHosts
+func youtubeVideoID(rawURL string) (string, bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		// Accept links pasted without a scheme, e.g. "youtu.be/<id>"
+		rawURL = "https://" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.User != nil {
+		return "", false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if !youtubeHosts[host] {
+		return "", false
+	}
+
+	var id string
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	switch {
+	case strings.HasSuffix(host, "youtu.be"):
+		if len(segments) == 1 {
+			id = segments[0]
 		}
+	case len(segments) == 1 && segments[0] == "watch":
+		id = parsed.Query().Get("v")
+	case len(segments) == 2 && (segments[0] == "embed" || segments[0] == "v" || segments[0] == "shorts" || segments[0] == "live"):
+		id = segments[1]
+	}
+	if !youtubeIDPattern.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// expectedFilename is the name a download of req is served as. Both download
+// handlers use it so download-info always predicts the streamed name; without
+// metadata the name falls back to the video ID from the URL.
+func expectedFilename(req DownloadRequest, metadata *downloader.VideoMetadata) string {
+	if metadata == nil {
+		id, _ := youtubeVideoID(req.URL)
+		metadata = &downloader.VideoMetadata{ID: id}
 	}
-	return false
+	return downloader.ExpectedFilename(metadata, req.Format)
 }
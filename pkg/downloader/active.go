@@ -0,0 +1,69 @@
+package downloader
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DownloadInfo describes an in-flight download, as returned by ActiveDownloads
+type DownloadInfo struct {
+	ID        uint64           `json:"id"` // Unique for the lifetime of the process
+	URL       string           `json:"url"`
+	Progress  DownloadProgress `json:"progress"` // Latest progress; Stage is "Queued" until it starts
+	StartedAt time.Time        `json:"started_at"`
+}
+
+var (
+	activeMutex  sync.Mutex
+	activeNextID uint64
+	active       = make(map[uint64]*DownloadInfo)
+)
+
+// ActiveDownloads returns the downloads currently running in this process,
+// oldest first, e.g. for an admin dashboard
+func ActiveDownloads() []DownloadInfo {
+	activeMutex.Lock()
+	defer activeMutex.Unlock()
+
+	downloads := make([]DownloadInfo, 0, len(active))
+	for _, info := range active {
+		downloads = append(downloads, *info)
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].ID < downloads[j].ID
+	})
+	return downloads
+}
+
+// trackDownload registers a download of url in ActiveDownloads. The returned
+// callback records progress before passing it on to progressCb (which may be
+// nil); call done when the download finishes.
+func trackDownload(url string, progressCb ProgressCallback) (ProgressCallback, func()) {
+	activeMutex.Lock()
+	activeNextID++
+	id := activeNextID
+	info := &DownloadInfo{
+		ID:        id,
+		URL:       url,
+		Progress:  DownloadProgress{Stage: "Queued"},
+		StartedAt: time.Now(),
+	}
+	active[id] = info
+	activeMutex.Unlock()
+
+	tracked := func(progress DownloadProgress) {
+		activeMutex.Lock()
+		info.Progress = progress
+		activeMutex.Unlock()
+		if progressCb != nil {
+			progressCb(progress)
+		}
+	}
+	done := func() {
+		activeMutex.Lock()
+		delete(active, id)
+		activeMutex.Unlock()
+	}
+	return tracked, done
+}
@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrackDownload(t *testing.T) {
+	var forwarded []DownloadProgress
+	progress, done := trackDownload("https://www.youtube.com/watch?v=aaaaaaaaaaa", func(p DownloadProgress) {
+		forwarded = append(forwarded, p)
+	})
+	_, doneOther := trackDownload("https://www.youtube.com/watch?v=bbbbbbbbbbb", nil)
+
+	downloads := ActiveDownloads()
+	if len(downloads) != 2 || downloads[0].URL != "https://www.youtube.com/watch?v=aaaaaaaaaaa" || downloads[0].ID >= downloads[1].ID {
+		t.Fatalf("ActiveDownloads() = %+v, want both downloads oldest first", downloads)
+	}
+	if downloads[0].Progress.Stage != "Queued" || time.Since(downloads[0].StartedAt) > time.Minute {
+		t.Errorf("new download %+v, want queued and started now", downloads[0])
+	}
+
+	progress(DownloadProgress{Stage: "downloading", Percentage: 42})
+	if got := ActiveDownloads()[0].Progress; got.Percentage != 42 {
+		t.Errorf("progress %+v not recorded", got)
+	}
+	if len(forwarded) != 1 {
+		t.Errorf("progress forwarded %d times, want once", len(forwarded))
+	}
+
+	done()
+	doneOther()
+	if downloads := ActiveDownloads(); len(downloads) != 0 {
+		t.Errorf("finished downloads still listed: %+v", downloads)
+	}
+}
+
+func TestActiveDownloadsDuringDownload(t *testing.T) {
+	release := filepath.Join(t.TempDir(), "release")
+	useFakeYTDLP(t, `echo "[download]  50.0% of 2.00MiB at 1.00MiB/s ETA 00:01"
+while [ ! -f '`+release+`' ]; do sleep 0.05; done
+`+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+		errc <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var seen DownloadInfo
+	for time.Now().Before(deadline) {
+		if downloads := ActiveDownloads(); len(downloads) == 1 && downloads[0].Progress.Percentage == 50 {
+			seen = downloads[0]
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	os.WriteFile(release, nil, 0644)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	if seen.URL != "https://www.youtube.com/watch?v=aaaaaaaaaaa" || seen.Progress.Stage != "downloading" {
+		t.Errorf("in-flight download %+v, want it at 50%%", seen)
+	}
+	if downloads := ActiveDownloads(); len(downloads) != 0 {
+		t.Errorf("finished download still listed: %+v", downloads)
+	}
+}
@@ -0,0 +1,45 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// AgeGateFallback retries age-restricted downloads with player clients that
+// often serve them without login (see ageGateClients). Enabled by default.
+// Can be changed using SetAgeGateFallback()
+var AgeGateFallback = true
+
+// ageGateClients are the YouTube player clients tried, in order, when the
+// default client reports an age restriction
+var ageGateClients = []string{"tv_embedded", "mweb"}
+
+// SetAgeGateFallback enables or disables the age-restriction client fallback
+func SetAgeGateFallback(enabled bool) {
+	AgeGateFallback = enabled
+}
+
+// runAgeGateDownload runs a yt-dlp download; if it fails with ErrAgeRestricted and
+// AgeGateFallback is enabled, it is retried with each of ageGateClients.
+// The original error is returned if every fallback fails too.
+func runAgeGateDownload(ctx context.Context, selector string, temp string, url string, progressCb ProgressCallback, extra ...string) (*commandOutput, error) {
+	cmd := ytdlpDownloadCommand(ctx, selector, temp, url, extra...)
+	output, err := streamCommand(ctx, cmd, progressCb, "downloading")
+	if err == nil || !AgeGateFallback || !errors.Is(err, ErrAgeRestricted) {
+		return output, err
+	}
+
+	for _, client := range ageGateClients {
+		if ctx.Err() != nil {
+			break
+		}
+		args := append(append([]string{}, extra...),
+			"--extractor-args", fmt.Sprintf("youtube:player_client=%s", client))
+		retry, retryErr := streamCommand(ctx, ytdlpDownloadCommand(ctx, selector, temp, url, args...), progressCb, "downloading")
+		if retryErr == nil {
+			return retry, nil
+		}
+	}
+	return output, err
+}
@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// ageGatedScript fails with an age restriction unless yt-dlp runs with one
+// of the player clients in allowed
+func ageGatedScript(logLine string, allowed string) string {
+	return logLine + `
+case "$*" in *player_client=` + allowed + `*) ` + writeOutput("mp4") + `; exit 0;; esac
+echo "ERROR: [youtube] aaaaaaaaaaa: Sign in to confirm your age. This video may be inappropriate for some users." >&2
+exit 1`
+}
+
+func TestAgeGateFallbackSucceeds(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, ageGatedScript(logLine, "mweb"))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &AgeGateFallback, true)
+
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	var clients []string
+	for _, call := range calls() {
+		client := ""
+		for i, arg := range call {
+			if arg == "--extractor-args" && strings.HasPrefix(call[i+1], "youtube:player_client=") {
+				client = strings.TrimPrefix(call[i+1], "youtube:player_client=")
+			}
+		}
+		clients = append(clients, client)
+	}
+	if strings.Join(clients, ",") != ",tv_embedded,mweb" {
+		t.Errorf("clients tried %q, want the default, then tv_embedded, then mweb", clients)
+	}
+}
+
+func TestAgeGateFallbackDisabled(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, ageGatedScript(logLine, "mweb"))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &AgeGateFallback, true)
+	SetAgeGateFallback(false)
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if !errors.Is(err, ErrAgeRestricted) {
+		t.Errorf("err = %v, want ErrAgeRestricted", err)
+	}
+	if len(calls()) != 1 {
+		t.Errorf("yt-dlp ran %d times with the fallback disabled", len(calls()))
+	}
+}
+
+func TestAgeGateFallbackAllFail(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, ageGatedScript(logLine, "none"))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &AgeGateFallback, true)
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if !errors.Is(err, ErrAgeRestricted) {
+		t.Errorf("err = %v, want the original ErrAgeRestricted", err)
+	}
+	if got := len(calls()); got != 1+len(ageGateClients) {
+		t.Errorf("yt-dlp ran %d times, want %d", got, 1+len(ageGateClients))
+	}
+}
@@ -0,0 +1,127 @@
+package downloader
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AggregateProgress combines the progress of several downloads into one overall
+// progress stream, e.g. for playlists. Each item is weighted by its size once
+// known (from DownloadProgress.TotalBytes or SetEstimate); items of unknown
+// size count as the average known size.
+//
+// Example:
+//
+//	agg := downloader.NewAggregateProgress(len(urls), func(p downloader.DownloadProgress) {
+//	    fmt.Printf("%s: %.1f%%\n", p.Stage, p.Percentage)
+//	})
+//	for i, url := range urls {
+//	    opts := downloader.VideoOptions{Progress: agg.Item(i)}
+//	    downloader.DownloadVideoWithOptions(ctx, url, opts)
+//	    agg.Complete(i)
+//	}
+type AggregateProgress struct {
+	mu       sync.Mutex
+	callback ProgressCallback
+	items    []aggregateItem
+}
+
+// aggregateItem is the state of one item of an AggregateProgress
+type aggregateItem struct {
+	totalBytes int64   // Estimated size, 0 if unknown
+	downloaded int64   // Bytes downloaded so far
+	fraction   float64 // 0..1, never decreases
+}
+
+// NewAggregateProgress returns an aggregator for count items reporting to callback
+func NewAggregateProgress(count int, callback ProgressCallback) *AggregateProgress {
+	return &AggregateProgress{callback: callback, items: make([]aggregateItem, count)}
+}
+
+// SetEstimate sets the expected size of item index (0-based), e.g. from metadata,
+// before its download reports one
+func (a *AggregateProgress) SetEstimate(index int, totalBytes int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if index >= 0 && index < len(a.items) && totalBytes > 0 {
+		a.items[index].totalBytes = totalBytes
+	}
+}
+
+// Item returns the ProgressCallback to pass to the download of item index (0-based)
+func (a *AggregateProgress) Item(index int) ProgressCallback {
+	return func(progress DownloadProgress) {
+		a.update(index, progress, false)
+	}
+}
+
+// Complete marks item index (0-based) as finished, whether it succeeded,
+// failed or was skipped, so the overall progress still reaches 100%
+func (a *AggregateProgress) Complete(index int) {
+	a.update(index, DownloadProgress{Stage: "Completed", Percentage: 100}, true)
+}
+
+// update records progress for one item and emits the overall progress
+func (a *AggregateProgress) update(index int, progress DownloadProgress, complete bool) {
+	a.mu.Lock()
+	if index < 0 || index >= len(a.items) {
+		a.mu.Unlock()
+		return
+	}
+
+	item := &a.items[index]
+	if progress.TotalBytes > 0 && !complete {
+		item.totalBytes = progress.TotalBytes
+	}
+	if progress.BytesDownloaded > item.downloaded {
+		item.downloaded = progress.BytesDownloaded
+	}
+	if fraction := progress.Percentage / 100; fraction > item.fraction {
+		item.fraction = fraction
+	}
+	if complete || item.fraction > 1 {
+		item.fraction = 1
+	}
+
+	overall := a.overall()
+	overall.Stage = fmt.Sprintf("Item %d/%d: %s", index+1, len(a.items), progress.Stage)
+	callback := a.callback
+	a.mu.Unlock()
+
+	if callback != nil {
+		callback(overall)
+	}
+}
+
+// overall computes the combined progress; a.mu must be held
+func (a *AggregateProgress) overall() DownloadProgress {
+	var known, knownTotal int64
+	for _, item := range a.items {
+		if item.totalBytes > 0 {
+			known++
+			knownTotal += item.totalBytes
+		}
+	}
+	// Items of unknown size weigh as much as the average known item
+	unknownWeight := 1.0
+	if known > 0 {
+		unknownWeight = float64(knownTotal) / float64(known)
+	}
+
+	var progress DownloadProgress
+	var weighted, totalWeight float64
+	for _, item := range a.items {
+		weight := unknownWeight
+		if item.totalBytes > 0 {
+			weight = float64(item.totalBytes)
+		}
+		weighted += weight * item.fraction
+		totalWeight += weight
+		progress.BytesDownloaded += item.downloaded
+	}
+	progress.TotalBytes = knownTotal
+	if totalWeight > 0 {
+		progress.Percentage = weighted / totalWeight * 100
+	}
+	return progress
+}
@@ -0,0 +1,110 @@
+package downloader
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+)
+
+// recordProgress returns a ProgressCallback collecting every update and a
+// function returning them
+func recordProgress() (ProgressCallback, func() []DownloadProgress) {
+	var mu sync.Mutex
+	var updates []DownloadProgress
+	return func(p DownloadProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			updates = append(updates, p)
+		}, func() []DownloadProgress {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]DownloadProgress(nil), updates...)
+		}
+}
+
+func TestAggregateProgressWeighted(t *testing.T) {
+	callback, updates := recordProgress()
+	agg := NewAggregateProgress(2, callback)
+	agg.SetEstimate(0, 300)
+	agg.SetEstimate(1, 100)
+
+	// Half of the 300 byte item is 150 of 400 bytes overall
+	agg.Item(0)(DownloadProgress{Stage: "downloading", Percentage: 50, BytesDownloaded: 150})
+	last := updates()[len(updates())-1]
+	if math.Abs(last.Percentage-37.5) > 0.01 || last.TotalBytes != 400 || last.BytesDownloaded != 150 {
+		t.Errorf("after half of item 1: %+v, want 37.5%% of 400 bytes", last)
+	}
+	if last.Stage != "Item 1/2: downloading" {
+		t.Errorf("Stage = %q", last.Stage)
+	}
+
+	// Progress never goes backwards, e.g. when the next stream of a merge starts
+	agg.Item(0)(DownloadProgress{Stage: "downloading", Percentage: 10})
+	if got := updates()[len(updates())-1].Percentage; got < 37.5 {
+		t.Errorf("progress went back to %.1f%%", got)
+	}
+}
+
+func TestAggregateProgressReaches100(t *testing.T) {
+	callback, updates := recordProgress()
+	agg := NewAggregateProgress(3, callback)
+
+	// Item 1 reports its size; item 2 is skipped without any progress; item 3
+	// reports a size once it finishes
+	agg.Item(0)(DownloadProgress{Stage: "downloading", Percentage: 40, TotalBytes: 1000, BytesDownloaded: 400})
+	agg.Item(0)(DownloadProgress{Stage: "downloading", Percentage: 100, TotalBytes: 1000, BytesDownloaded: 1000})
+	agg.Complete(0)
+	agg.Complete(1)
+	agg.Item(2)(DownloadProgress{Stage: "downloading", Percentage: 100, TotalBytes: 500, BytesDownloaded: 500})
+	agg.Complete(2)
+
+	all := updates()
+	for i := 1; i < len(all); i++ {
+		if all[i].Percentage < all[i-1].Percentage-1e-9 {
+			t.Errorf("progress decreased from %.2f%% to %.2f%%", all[i-1].Percentage, all[i].Percentage)
+		}
+	}
+	if last := all[len(all)-1]; math.Abs(last.Percentage-100) > 1e-9 {
+		t.Errorf("final progress %.2f%%, want 100%%", last.Percentage)
+	}
+}
+
+func TestAggregateProgressIgnoresOutOfRange(t *testing.T) {
+	callback, updates := recordProgress()
+	agg := NewAggregateProgress(1, callback)
+	agg.Item(5)(DownloadProgress{Percentage: 50})
+	agg.Complete(-1)
+	agg.SetEstimate(3, 100)
+	if len(updates()) != 0 {
+		t.Errorf("updates for unknown items: %+v", updates())
+	}
+}
+
+func TestDownloadPlaylistOverallProgress(t *testing.T) {
+	logLine, _ := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, `echo "[download]  50.0% of 2.00KiB at 1.00KiB/s ETA 00:01"
+echo "[download] 100% of 2.00KiB"
+`+writeOutput("mp4")))
+	useFakeFFMPEG(t, "exit 0")
+	callback, updates := recordProgress()
+
+	_, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: t.TempDir(), Progress: callback},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := updates()
+	if len(all) == 0 {
+		t.Fatal("no progress reported")
+	}
+	if last := all[len(all)-1]; math.Abs(last.Percentage-100) > 1e-9 || last.Stage != "Item 3/3: Completed" {
+		t.Errorf("final progress %+v, want 100%% for item 3", last)
+	}
+	for _, p := range all {
+		if p.Percentage > 100+1e-9 {
+			t.Errorf("progress above 100%%: %+v", p)
+		}
+	}
+}
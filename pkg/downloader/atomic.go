@@ -0,0 +1,54 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// partialMarker is inserted before the extension of files still being written
+const partialMarker = ".partial"
+
+// partialPath returns the sibling path a file is written to before being renamed
+// into place. The extension is kept so ffmpeg still infers the container from it.
+func partialPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + partialMarker + ext
+}
+
+// commitPartial atomically renames a finished partial file to its final path
+func commitPartial(partial string, path string) error {
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("failed to rename %s into place: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// removeNewTemplateFiles removes the files yt-dlp wrote for an output
+// template since existing was listed (see templateFiles). Files that were
+// already there, e.g. an earlier download saved under the same Filename, are
+// kept.
+func removeNewTemplateFiles(temp string, existing []string) {
+	for _, match := range templateFiles(temp) {
+		if !slices.Contains(existing, match) {
+			os.Remove(match)
+		}
+	}
+}
+
+// convertToFile runs ffmpeg with the arguments built for dst, writing to a
+// partial sibling of output that is renamed into place only on success, so an
+// interrupted conversion never leaves a complete-looking output behind
+func convertToFile(ctx context.Context, output string, progressCb ProgressCallback, args func(dst string) []string) error {
+	partial := partialPath(output)
+	ffmpeg := newCommand(ctx, FFMPEGPath, args(partial)...)
+	if _, err := streamCommand(ctx, ffmpeg, progressCb, "converting"); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return commitPartial(partial, output)
+}
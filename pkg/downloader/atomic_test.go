@@ -0,0 +1,158 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartialPath(t *testing.T) {
+	tests := map[string]string{
+		"/out/video.mp4":    "/out/video.partial.mp4",
+		"/out/my.song.mp3":  "/out/my.song.partial.mp3",
+		"/out/no-extension": "/out/no-extension.partial",
+		"relative/a b.webm": "relative/a b.partial.webm",
+	}
+	for path, want := range tests {
+		if got := partialPath(path); got != want {
+			t.Errorf("partialPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestConvertToFileFailureLeavesNoFile(t *testing.T) {
+	// ffmpeg writes half the output, then fails
+	useFakeFFMPEG(t, `for a in "$@"; do last="$a"; done
+printf 'half' > "$last"
+echo "Conversion failed!" >&2
+exit 1`)
+	dir := t.TempDir()
+	output := filepath.Join(dir, "video.mp4")
+
+	err := convertToFile(context.Background(), output, nil, func(dst string) []string {
+		if dst == output {
+			t.Errorf("ffmpeg writes to the final path %s", dst)
+		}
+		return []string{"-i", "in.webm", dst}
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("left behind %v", entries)
+	}
+}
+
+func TestConvertToFileSuccess(t *testing.T) {
+	useFakeFFMPEG(t, writeLastArg)
+	output := filepath.Join(t.TempDir(), "video.mp4")
+
+	if err := convertToFile(context.Background(), output, nil, func(dst string) []string {
+		return []string{"-i", "in.webm", dst}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "converted" {
+		t.Errorf("output = %q", data)
+	}
+	if _, err := os.Stat(partialPath(output)); !os.IsNotExist(err) {
+		t.Error("partial file left behind")
+	}
+}
+
+func TestDownloadFailedConvertLeavesNoFinalFile(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("webm"))
+	useFakeFFMPEG(t, ffmpegScript(`for a in "$@"; do last="$a"; done
+printf 'half' > "$last"
+exit 1`))
+	dir := t.TempDir()
+
+	_, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "mp3",
+		OutputDir: dir,
+	})
+	if err == nil {
+		t.Fatal("expected the conversion to fail")
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".mp3") {
+			t.Errorf("failed conversion left %s", entry.Name())
+		}
+	}
+}
+
+func TestDownloadFailedFetchLeavesNoFile(t *testing.T) {
+	// yt-dlp writes part of the file under its final name (--no-part), then fails
+	useFakeYTDLP(t, writeOutput("mp4")+`
+echo "ERROR: unable to download video data: HTTP Error 403: Forbidden" >&2
+exit 1`)
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg))
+
+	for _, opts := range []VideoOptions{
+		{OutputDir: t.TempDir()},
+		{OutputDir: t.TempDir(), Filename: "My video"},
+	} {
+		if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", opts); err == nil {
+			t.Fatal("expected the download to fail")
+		}
+		if entries, _ := os.ReadDir(opts.OutputDir); len(entries) != 0 {
+			t.Errorf("failed video download (%+v) left %v", opts, entries)
+		}
+	}
+
+	// The audio download writes m4a, which needs no conversion to m4a
+	useFakeYTDLP(t, writeOutput("m4a")+"\nexit 1")
+	dir := t.TempDir()
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{Format: "m4a", OutputDir: dir}); err == nil {
+		t.Fatal("expected the download to fail")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("failed audio download left %v", entries)
+	}
+}
+
+func TestDownloadCancelledFetchLeavesNoFile(t *testing.T) {
+	started := filepath.Join(t.TempDir(), "started")
+	useFakeYTDLP(t, writeOutput("mp4")+`
+touch '`+started+`'
+exec sleep 10`)
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg))
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		for i := 0; i < 500; i++ {
+			if _, err := os.Stat(started); err == nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	if _, err := DownloadVideoWithOptions(ctx, "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: dir}); err == nil {
+		t.Fatal("expected the cancelled download to fail")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("cancelled download left %v", entries)
+	}
+}
+
+func TestDownloadFailureKeepsExistingFile(t *testing.T) {
+	useFakeYTDLP(t, "exit 1")
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg))
+	dir := t.TempDir()
+	earlier := filepath.Join(dir, "My video.mp4")
+	os.WriteFile(earlier, []byte("complete"), 0644)
+
+	// An earlier download saved under the same Filename is not removed
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: dir, Filename: "My video"}); err == nil {
+		t.Fatal("expected the download to fail")
+	}
+	if data, _ := os.ReadFile(earlier); string(data) != "complete" {
+		t.Errorf("existing file changed: %q", data)
+	}
+}
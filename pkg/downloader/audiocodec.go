@@ -0,0 +1,155 @@
+package downloader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// defaultAudioCodecs maps an audio output format to the ffmpeg codec used
+// when no codec is requested. Can be changed using SetDefaultAudioCodecByFormat()
+var defaultAudioCodecs = map[string]string{
+	"mp3":  "libmp3lame",
+	"m4a":  "aac",
+	"aac":  "aac",
+	"opus": "libopus",
+	"ogg":  "libvorbis",
+	"flac": "flac",
+	"wav":  "pcm_s16le",
+	"webm": "libopus",
+}
+
+// fallbackAudioCodec is used for formats without a default, as DownloadAudio
+// always did before defaults were per format
+const fallbackAudioCodec = "libmp3lame"
+
+// compatibleAudioCodecs lists the ffmpeg codecs each audio format can hold.
+// Formats not listed here are not validated.
+var compatibleAudioCodecs = map[string][]string{
+	"mp3":  {"libmp3lame", "libshine", "mp3"},
+	"m4a":  {"aac", "libfdk_aac", "alac"},
+	"aac":  {"aac", "libfdk_aac"},
+	"opus": {"libopus", "opus"},
+	"ogg":  {"libvorbis", "vorbis", "libopus", "opus", "flac"},
+	"flac": {"flac"},
+	"wav":  {"pcm_s16le", "pcm_s24le", "pcm_s32le", "pcm_f32le", "pcm_u8"},
+	"webm": {"libopus", "opus", "libvorbis", "vorbis"},
+}
+
+// SetDefaultAudioCodecByFormat sets the ffmpeg codec DownloadAudio uses for
+// format when no codec is given.
+//
+// Example:
+//
+//	downloader.SetDefaultAudioCodecByFormat("m4a", "libfdk_aac")
+func SetDefaultAudioCodecByFormat(format string, codec string) error {
+	format = strings.ToLower(format)
+	if format == "" || codec == "" {
+		return fmt.Errorf("format and codec are required")
+	}
+	if err := validateAudioCodec(format, codec); err != nil {
+		return err
+	}
+	defaultAudioCodecs[format] = codec
+	return nil
+}
+
+// DefaultAudioCodec returns the ffmpeg codec used for format when none is
+// requested, or "" if the format has no default
+func DefaultAudioCodec(format string) string {
+	return defaultAudioCodecs[strings.ToLower(format)]
+}
+
+// resolveAudioCodec returns codec, or the format's default when codec is empty
+// (libmp3lame for formats without one), and rejects combinations the
+// container cannot hold
+func resolveAudioCodec(format string, codec string) (string, error) {
+	if codec == "" {
+		codec = DefaultAudioCodec(format)
+		if codec == "" {
+			codec = fallbackAudioCodec
+		}
+	}
+	if err := validateAudioCodec(format, codec); err != nil {
+		return "", err
+	}
+	return codec, nil
+}
+
+// validateAudioCodec returns an error if format is known and cannot hold codec.
+// "copy" keeps the source stream and is always accepted.
+func validateAudioCodec(format string, codec string) error {
+	if codec == "copy" {
+		return nil
+	}
+	allowed, known := compatibleAudioCodecs[strings.ToLower(format)]
+	if !known {
+		return nil
+	}
+	for _, candidate := range allowed {
+		if candidate == codec {
+			return nil
+		}
+	}
+	return fmt.Errorf("audio codec %q is not compatible with format %q (use one of: %s)",
+		codec, format, strings.Join(allowed, ", "))
+}
+
+// losslessAudioCodecs are codecs where a bitrate is meaningless
+var losslessAudioCodecs = map[string]bool{
+	"flac":      true,
+	"alac":      true,
+	"pcm_s16le": true,
+	"pcm_s24le": true,
+	"pcm_s32le": true,
+	"pcm_f32le": true,
+	"pcm_u8":    true,
+}
+
+// isLosslessAudioCodec reports whether codec is lossless
+func isLosslessAudioCodec(codec string) bool {
+	return losslessAudioCodecs[codec]
+}
+
+// resolveAudioBitrate returns the bitrate to encode with: bitrate, or the 128k
+// default for lossy codecs. Lossless codecs (flac, wav, alac) get no bitrate
+// and reject an explicit one; "copy" gets no default since nothing is encoded.
+func resolveAudioBitrate(codec string, bitrate string) (string, error) {
+	if codec == "copy" {
+		return bitrate, nil
+	}
+	if isLosslessAudioCodec(codec) {
+		if bitrate != "" {
+			return "", fmt.Errorf("bitrate %q cannot be used with lossless codec %q", bitrate, codec)
+		}
+		return "", nil
+	}
+	if bitrate == "" {
+		return "128k", nil
+	}
+	return bitrate, nil
+}
+
+// codecSampleRates lists the only sample rates some encoders accept.
+// Codecs not listed here accept any rate validateAudioLayout allows.
+var codecSampleRates = map[string][]int{
+	"libopus":    {8000, 12000, 16000, 24000, 48000},
+	"libmp3lame": {8000, 11025, 12000, 16000, 22050, 24000, 32000, 44100, 48000},
+}
+
+// validateAudioLayout returns an error if sampleRate (Hz) or channels are not
+// sensible for codec. Zero keeps the source's value.
+func validateAudioLayout(codec string, sampleRate int, channels int) error {
+	if sampleRate != 0 {
+		if sampleRate < 8000 || sampleRate > 192000 {
+			return fmt.Errorf("sample rate %d Hz is out of range (8000-192000)", sampleRate)
+		}
+		if rates, ok := codecSampleRates[codec]; ok && !slices.Contains(rates, sampleRate) {
+			return fmt.Errorf("audio codec %q does not support a sample rate of %d Hz", codec, sampleRate)
+		}
+	}
+	if channels < 0 || channels > 8 {
+		return fmt.Errorf("channel count %d is out of range (1-8)", channels)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResolveAudioCodec(t *testing.T) {
+	tests := []struct {
+		format, codec string
+		want          string
+		wantErr       bool
+	}{
+		{"mp3", "", "libmp3lame", false},
+		{"M4A", "", "aac", false},
+		{"opus", "", "libopus", false},
+		{"flac", "", "flac", false},
+		{"wav", "", "pcm_s16le", false},
+		{"mp3", "libshine", "libshine", false},
+		{"m4a", "alac", "alac", false},
+		{"mp3", "aac", "", true},
+		{"flac", "libmp3lame", "", true},
+		{"webm", "", "libopus", false},
+		{"webm", "aac", "", true},
+		{"mka", "", "libmp3lame", false},     // Formats without a default keep the old default
+		{"mka", "libopus", "libopus", false}, // and allow any codec
+		// Stream copy keeps the source audio, whatever the format
+		{"m4a", "copy", "copy", false},
+		{"mp3", "copy", "copy", false},
+		{"mka", "copy", "copy", false},
+	}
+	for _, tt := range tests {
+		got, err := resolveAudioCodec(tt.format, tt.codec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveAudioCodec(%q, %q) = %q, %v; want %q (error %v)", tt.format, tt.codec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetDefaultAudioCodecByFormat(t *testing.T) {
+	original := defaultAudioCodecs["m4a"]
+	t.Cleanup(func() { defaultAudioCodecs["m4a"] = original })
+
+	if err := SetDefaultAudioCodecByFormat("M4A", "libfdk_aac"); err != nil {
+		t.Fatal(err)
+	}
+	if got := DefaultAudioCodec("m4a"); got != "libfdk_aac" {
+		t.Errorf("DefaultAudioCodec(m4a) = %q, want libfdk_aac", got)
+	}
+	if err := SetDefaultAudioCodecByFormat("m4a", "libopus"); err == nil {
+		t.Error("incompatible codec accepted")
+	}
+	if err := SetDefaultAudioCodecByFormat("", "aac"); err == nil {
+		t.Error("empty format accepted")
+	}
+}
+
+func TestResolveAudioBitrate(t *testing.T) {
+	tests := []struct {
+		codec, bitrate string
+		want           string
+		wantErr        bool
+	}{
+		{"libmp3lame", "", "128k", false},
+		{"aac", "256k", "256k", false},
+		{"flac", "", "", false},
+		{"flac", "320k", "", true},
+		{"copy", "", "", false},
+		{"copy", "192k", "192k", false},
+	}
+	for _, tt := range tests {
+		got, err := resolveAudioBitrate(tt.codec, tt.bitrate)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveAudioBitrate(%q, %q) = %q, %v", tt.codec, tt.bitrate, got, err)
+		}
+	}
+}
+
+func TestValidateAudioLayout(t *testing.T) {
+	tests := []struct {
+		codec      string
+		sampleRate int
+		channels   int
+		ok         bool
+	}{
+		{"aac", 0, 0, true},
+		{"aac", 44100, 2, true},
+		{"libopus", 16000, 1, true},
+		{"libopus", 44100, 2, false}, // Opus has no 44.1 kHz mode
+		{"libmp3lame", 96000, 2, false},
+		{"flac", 96000, 6, true},
+		{"aac", 4000, 2, false},
+		{"aac", 384000, 2, false},
+		{"aac", 48000, 9, false},
+		{"aac", 48000, -1, false},
+	}
+	for _, tt := range tests {
+		if err := validateAudioLayout(tt.codec, tt.sampleRate, tt.channels); (err == nil) != tt.ok {
+			t.Errorf("validateAudioLayout(%q, %d, %d) = %v", tt.codec, tt.sampleRate, tt.channels, err)
+		}
+	}
+}
+
+func TestAudioConvertArgsLayout(t *testing.T) {
+	got := audioConvertArgs(audioConversion{
+		Input: "in.webm", Output: "out.wav", Format: "wav", Codec: "pcm_s16le",
+		SampleRate: 16000, Channels: 1,
+	})
+	want := []string{"-i", "in.webm", "-vn", "-acodec", "pcm_s16le", "-ar", "16000", "-ac", "1", "-max_muxing_queue_size", "1024", "-y", "out.wav"}
+	if !slices.Equal(got, want) {
+		t.Errorf("args %q, want %q", got, want)
+	}
+
+	got = audioConvertArgs(audioConversion{Input: "in.webm", Output: "out.mp3", Format: "mp3", Codec: "libmp3lame", Bitrate: "192k", SampleRate: 44100})
+	if !hasArgs(got, "-ab", "192k", "-ar", "44100") || slices.Contains(got, "-ac") {
+		t.Errorf("args %q, want -ar 44100 and no -ac", got)
+	}
+}
+
+func TestDownloadAudioSampleRate(t *testing.T) {
+	logLine, downloads := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("webm"))
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg))
+
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format: "m4a", OutputDir: t.TempDir(), SampleRate: 44100, Channels: 2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if args := ffmpegCalls()[0]; !hasArgs(args, "-ar", "44100", "-ac", "2") {
+		t.Errorf("ffmpeg args %v, want -ar 44100 -ac 2", args)
+	}
+
+	// Invalid layouts are rejected before downloading
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format: "opus", OutputDir: t.TempDir(), SampleRate: 44100,
+	}); err == nil {
+		t.Error("44.1 kHz opus accepted")
+	}
+	if len(downloads()) != 1 {
+		t.Errorf("downloaded %d times, want once", len(downloads()))
+	}
+}
+
+func TestDownloadAudioCopyAndWebm(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("m4a"))
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg))
+
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format: "m4a", Codec: "copy", OutputDir: t.TempDir(),
+	}); err != nil {
+		t.Fatalf("stream copy: %v", err)
+	}
+	if args := ffmpegCalls()[0]; !hasArgs(args, "-acodec", "copy") || slices.Contains(args, "-ab") {
+		t.Errorf("ffmpeg args %v, want -acodec copy without a bitrate", args)
+	}
+
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format: "webm", OutputDir: t.TempDir(),
+	}); err != nil {
+		t.Fatalf("webm without a codec: %v", err)
+	}
+	if args := ffmpegCalls()[1]; !hasArgs(args, "-acodec", "libopus") {
+		t.Errorf("ffmpeg args %v, want libopus for webm", args)
+	}
+
+	// Trimming silence needs a re-encode
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format: "m4a", Codec: "copy", OutputDir: t.TempDir(), TrimSilence: true,
+	}); err == nil || !strings.Contains(err.Error(), "TrimSilence") {
+		t.Errorf("err = %v, want TrimSilence rejected with codec copy", err)
+	}
+}
@@ -0,0 +1,145 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// audioStreamMuxers are the ffmpeg output flags for each audio format when
+// writing to a pipe. mp4 based formats need a fragmented layout since the
+// output can't be seeked back to write the index.
+var audioStreamMuxers = map[string][]string{
+	"mp3":  {"-f", "mp3"},
+	"m4a":  {"-f", "ipod", "-movflags", "frag_keyframe+empty_moov"},
+	"aac":  {"-f", "adts"},
+	"opus": {"-f", "opus"},
+	"ogg":  {"-f", "ogg"},
+	"flac": {"-f", "flac"},
+	"wav":  {"-f", "wav"},
+}
+
+// audioContentTypes are the MIME types of the streamable audio formats
+var audioContentTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"m4a":  "audio/mp4",
+	"aac":  "audio/aac",
+	"opus": "audio/ogg",
+	"ogg":  "audio/ogg",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+}
+
+// AudioContentType returns the MIME type of an audio format StreamAudio can
+// produce, or "" if the format can't be streamed
+func AudioContentType(format string) string {
+	return audioContentTypes[strings.ToLower(format)]
+}
+
+// AudioStreamOptions configures StreamAudio; empty fields use the DownloadAudio defaults
+type AudioStreamOptions struct {
+	Format  string // Output format (default: mp3)
+	Codec   string // Optional ffmpeg codec (default: the format's default codec)
+	Bitrate string // Optional bitrate (default: 128k, none for lossless codecs)
+}
+
+// StreamAudio downloads the best audio of url and writes it to w converted to
+// opts.Format as it arrives, without a temporary file: yt-dlp's output is piped
+// into ffmpeg and ffmpeg's output into w. Cancelling ctx (e.g. when an HTTP
+// client disconnects) stops both processes. Requires ffmpeg.
+func StreamAudio(ctx context.Context, url string, w io.Writer, opts AudioStreamOptions) error {
+	if err := ensureBinariesInstalled(); err != nil {
+		return fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+	if !ffmpegAvailable() {
+		return fmt.Errorf("%w: streaming converted audio", ErrFFMPEGRequired)
+	}
+
+	format := strings.ToLower(opts.Format)
+	if format == "" {
+		format = "mp3"
+	}
+	muxer, ok := audioStreamMuxers[format]
+	if !ok {
+		return fmt.Errorf("audio format %q cannot be streamed", opts.Format)
+	}
+	codec, err := resolveAudioCodec(format, opts.Codec)
+	if err != nil {
+		return err
+	}
+	bitrate, err := resolveAudioBitrate(codec, opts.Bitrate)
+	if err != nil {
+		return err
+	}
+	if err := requireEncoder(codec); err != nil {
+		return err
+	}
+
+	_, untrack := trackDownload(url, nil)
+	defer untrack()
+
+	release, err := acquireHostSlot(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Either process failing stops the other
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ytdlp := ytdlpDownloadCommand(ctx, "bestaudio", "-", url, "--quiet", "--no-warnings")
+	var ytdlpStderr bytes.Buffer
+	ytdlp.Stderr = &ytdlpStderr
+	audio, err := ytdlp.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create yt-dlp pipe: %w", err)
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", "pipe:0", "-vn", "-acodec", codec}
+	if bitrate != "" {
+		args = append(args, "-b:a", bitrate)
+	}
+	args = append(args, muxer...)
+	args = append(args, "pipe:1")
+	ffmpeg := newCommand(ctx, FFMPEGPath, args...)
+	var ffmpegStderr bytes.Buffer
+	ffmpeg.Stdin = audio
+	ffmpeg.Stdout = w
+	ffmpeg.Stderr = &ffmpegStderr
+
+	if err := ytdlp.Start(); err != nil {
+		return fmt.Errorf("failed to start yt-dlp: %w", err)
+	}
+	if err := ffmpeg.Start(); err != nil {
+		cancel()
+		ytdlp.Wait()
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	ffmpegErr := ffmpeg.Wait()
+	if ffmpegErr != nil {
+		cancel()
+	}
+	ytdlpErr := ytdlp.Wait()
+
+	// The client going away kills both processes; report that rather than the kill
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	// A failing yt-dlp leaves ffmpeg with bad input, so a recognized yt-dlp
+	// error is the real cause even if ffmpeg failed too
+	if ytdlpErr != nil && classifyError(ytdlpStderr.String()) != nil {
+		return fmt.Errorf("yt-dlp audio stream failed: %w", newDownloadError(ytdlp, "streaming", ytdlpStderr.String(), ytdlpErr))
+	}
+	if ffmpegErr != nil {
+		return fmt.Errorf("ffmpeg audio stream failed: %w", newDownloadError(ffmpeg, "converting", ffmpegStderr.String(), ffmpegErr))
+	}
+	if ytdlpErr != nil {
+		return fmt.Errorf("yt-dlp audio stream failed: %w", newDownloadError(ytdlp, "streaming", ytdlpStderr.String(), ytdlpErr))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// bitratePattern matches ffmpeg audio bitrates such as "128k" or "96000"
+var bitratePattern = regexp.MustCompile(`^([1-9]\d*)([kK]?)$`)
+
+// validateAudioBitrate returns an error unless bitrate is a plausible audio
+// bitrate between 8 and 512 kbps
+func validateAudioBitrate(bitrate string) error {
+	m := bitratePattern.FindStringSubmatch(bitrate)
+	if m == nil {
+		return fmt.Errorf("invalid bitrate %q (use e.g. \"128k\")", bitrate)
+	}
+	kbps, err := strconv.Atoi(m[1])
+	if err != nil {
+		return fmt.Errorf("invalid bitrate %q: %w", bitrate, err)
+	}
+	if m[2] == "" {
+		kbps /= 1000
+	}
+	if kbps < 8 || kbps > 512 {
+		return fmt.Errorf("bitrate %q is out of range (8k-512k)", bitrate)
+	}
+	return nil
+}
+
+// TranscodeAudioVariants encodes the audio of a local file once per bitrate,
+// e.g. for adaptive audio streaming, writing "<name>_<bitrate>.<ext>" files to
+// outputDir (default: the input's directory). Audio files keep their format;
+// the audio of video files is saved as m4a. All variants come from a single
+// ffmpeg run. Returns the paths in bitrate order.
+//
+// Example:
+//
+//	paths, err := downloader.TranscodeAudioVariants("song.m4a", []string{"64k", "128k", "256k"}, "./variants")
+func TranscodeAudioVariants(input string, bitrates []string, outputDir string) ([]string, error) {
+	return TranscodeAudioVariantsWithContext(context.Background(), input, bitrates, outputDir)
+}
+
+// TranscodeAudioVariantsWithContext is TranscodeAudioVariants with a caller-supplied context
+func TranscodeAudioVariantsWithContext(ctx context.Context, input string, bitrates []string, outputDir string) ([]string, error) {
+	if len(bitrates) == 0 {
+		return nil, fmt.Errorf("at least one bitrate is required")
+	}
+	seen := make(map[string]bool)
+	for _, bitrate := range bitrates {
+		if err := validateAudioBitrate(bitrate); err != nil {
+			return nil, err
+		}
+		if seen[strings.ToLower(bitrate)] {
+			return nil, fmt.Errorf("duplicate bitrate %q", bitrate)
+		}
+		seen[strings.ToLower(bitrate)] = true
+	}
+	if _, err := os.Stat(input); err != nil {
+		return nil, fmt.Errorf("input file not found: %w", err)
+	}
+
+	// Auto-install binaries if needed (only happens once)
+	if err := ensureBinariesInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+	if !ffmpegAvailable() {
+		return nil, fmt.Errorf("%w: transcoding audio", ErrFFMPEGRequired)
+	}
+
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(input), "."))
+	codec := DefaultAudioCodec(format)
+	if codec == "" {
+		format, codec = "m4a", DefaultAudioCodec("m4a")
+	}
+	if isLosslessAudioCodec(codec) {
+		return nil, fmt.Errorf("%s is lossless and has no bitrate; convert to a lossy format first", format)
+	}
+	if err := requireEncoder(codec); err != nil {
+		return nil, err
+	}
+
+	if outputDir == "" {
+		outputDir = filepath.Dir(input)
+	}
+	if err := makeOutputDir(outputDir); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	paths := audioVariantPaths(input, bitrates, outputDir, format)
+	partials := make([]string, len(paths))
+	for i, path := range paths {
+		partials[i] = partialPath(path)
+	}
+
+	cmd := newCommand(ctx, FFMPEGPath, audioVariantArgs(input, codec, bitrates, partials)...)
+	if _, err := streamCommand(ctx, cmd, nil, "converting"); err != nil {
+		for _, partial := range partials {
+			os.Remove(partial)
+		}
+		return nil, fmt.Errorf("ffmpeg audio transcode failed: %w", diskFullError(err))
+	}
+	for i := range paths {
+		if err := commitPartial(partials[i], paths[i]); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// audioVariantPaths returns "<outputDir>/<name>_<bitrate>.<format>" for each bitrate
+func audioVariantPaths(input string, bitrates []string, outputDir string, format string) []string {
+	name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	paths := make([]string, len(bitrates))
+	for i, bitrate := range bitrates {
+		paths[i] = filepath.Join(outputDir, fmt.Sprintf("%s_%s.%s", name, strings.ToLower(bitrate), format))
+	}
+	return paths
+}
+
+// audioVariantArgs builds one ffmpeg invocation with an audio-only output per bitrate
+func audioVariantArgs(input string, codec string, bitrates []string, outputs []string) []string {
+	args := []string{"-i", input, "-y"}
+	for i, bitrate := range bitrates {
+		args = append(args, "-map", "0:a:0", "-vn", "-c:a", codec, "-b:a", bitrate, outputs[i])
+	}
+	return args
+}
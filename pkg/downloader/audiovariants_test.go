@@ -0,0 +1,91 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestValidateAudioBitrate(t *testing.T) {
+	for _, bitrate := range []string{"8k", "128k", "320K", "512k", "96000"} {
+		if err := validateAudioBitrate(bitrate); err != nil {
+			t.Errorf("validateAudioBitrate(%q): %v", bitrate, err)
+		}
+	}
+	for _, bitrate := range []string{"", "0k", "4k", "1024k", "128kb", "1.5k", "-128k", "abc", "7999"} {
+		if err := validateAudioBitrate(bitrate); err == nil {
+			t.Errorf("validateAudioBitrate(%q) accepted", bitrate)
+		}
+	}
+}
+
+func TestAudioVariantArgs(t *testing.T) {
+	paths := audioVariantPaths("/music/song.m4a", []string{"64k", "128K"}, "/out", "m4a")
+	if want := []string{"/out/song_64k.m4a", "/out/song_128k.m4a"}; !slices.Equal(paths, want) {
+		t.Errorf("paths %v, want %v", paths, want)
+	}
+
+	got := audioVariantArgs("/music/song.m4a", "aac", []string{"64k", "128K"}, paths)
+	want := []string{"-i", "/music/song.m4a", "-y",
+		"-map", "0:a:0", "-vn", "-c:a", "aac", "-b:a", "64k", "/out/song_64k.m4a",
+		"-map", "0:a:0", "-vn", "-c:a", "aac", "-b:a", "128K", "/out/song_128k.m4a"}
+	if !slices.Equal(got, want) {
+		t.Errorf("args %q, want %q", got, want)
+	}
+}
+
+func TestTranscodeAudioVariants(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(logLine+`
+for a in "$@"; do case "$a" in *.part*) printf 'audio' > "$a";; esac; done`))
+	input := filepath.Join(t.TempDir(), "clip.mp4")
+	os.WriteFile(input, []byte("video"), 0644)
+	outputDir := filepath.Join(t.TempDir(), "variants")
+
+	paths, err := TranscodeAudioVariants(input, []string{"96k", "192k"}, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The audio of a video file is saved as m4a
+	want := []string{filepath.Join(outputDir, "clip_96k.m4a"), filepath.Join(outputDir, "clip_192k.m4a")}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("paths %v, want %v", paths, want)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("variant not written: %v", err)
+		}
+	}
+
+	// One ffmpeg run produces every variant
+	if n := len(calls()); n != 1 {
+		t.Fatalf("%d ffmpeg runs, want 1", n)
+	}
+	args := calls()[0]
+	if argValue(args, "-i") != input || !hasArgs(args, "-c:a", "aac", "-b:a", "96k") || !hasArgs(args, "-c:a", "aac", "-b:a", "192k") {
+		t.Errorf("ffmpeg args %v", args)
+	}
+}
+
+func TestTranscodeAudioVariantsRejectsBitrates(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(logLine))
+	input := filepath.Join(t.TempDir(), "song.mp3")
+	os.WriteFile(input, []byte("audio"), 0644)
+
+	for _, bitrates := range [][]string{nil, {"128k", "fast"}, {"128k", "128K"}} {
+		if _, err := TranscodeAudioVariants(input, bitrates, t.TempDir()); err == nil {
+			t.Errorf("bitrates %q accepted", bitrates)
+		}
+	}
+	// Lossless sources have no bitrate to vary
+	flac := filepath.Join(t.TempDir(), "song.flac")
+	os.WriteFile(flac, []byte("audio"), 0644)
+	if _, err := TranscodeAudioVariants(flac, []string{"128k"}, t.TempDir()); err == nil {
+		t.Error("lossless input accepted")
+	}
+	if n := len(calls()); n != 0 {
+		t.Errorf("ffmpeg ran %d times for rejected input", n)
+	}
+}
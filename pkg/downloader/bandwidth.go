@@ -0,0 +1,101 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"youtube-api-server/pkg/internal/installer"
+)
+
+// RateLimit caps download bandwidth in bytes per second (default: 0, unlimited).
+// It is passed to yt-dlp as --limit-rate and applies to readers wrapped with
+// NewRateLimitedReader, so downloads that bypass yt-dlp (thumbnails, binary
+// installs, or a GetStreamURL result fetched directly) obey the same cap.
+// Can be set using SetRateLimit()
+var RateLimit int64
+
+// SetRateLimit sets the download bandwidth cap in bytes per second; 0 removes it
+func SetRateLimit(bytesPerSecond int64) error {
+	if bytesPerSecond < 0 {
+		return fmt.Errorf("rate limit must not be negative: %d", bytesPerSecond)
+	}
+	RateLimit = bytesPerSecond
+	if bytesPerSecond == 0 {
+		installer.SetBodyWrapper(nil)
+	} else {
+		installer.SetBodyWrapper(func(r io.Reader) io.Reader {
+			return NewRateLimitedReader(r, bytesPerSecond)
+		})
+	}
+	return nil
+}
+
+// rateLimitArgs returns the yt-dlp flags applying RateLimit
+func rateLimitArgs() []string {
+	if RateLimit <= 0 {
+		return nil
+	}
+	return []string{"--limit-rate", strconv.FormatInt(RateLimit, 10)}
+}
+
+// NewRateLimitedReader returns a reader that reads from r at no more than
+// bytesPerSecond, in bursts of up to one second's worth of data.
+// A bytesPerSecond of 0 uses RateLimit; if that is 0 too, r is returned as-is.
+func NewRateLimitedReader(r io.Reader, bytesPerSecond int64) io.Reader {
+	return NewRateLimitedReaderWithContext(context.Background(), r, bytesPerSecond)
+}
+
+// NewRateLimitedReaderWithContext is NewRateLimitedReader whose waits end early,
+// with ctx's error, once ctx is done
+func NewRateLimitedReaderWithContext(ctx context.Context, r io.Reader, bytesPerSecond int64) io.Reader {
+	if bytesPerSecond <= 0 {
+		bytesPerSecond = RateLimit
+	}
+	if bytesPerSecond <= 0 {
+		return r
+	}
+	return &rateLimitedReader{ctx: ctx, r: r, rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), last: time.Now()}
+}
+
+// rateLimitedReader is a token bucket around an io.Reader. Tokens are bytes;
+// the bucket refills at rate and holds at most one second's worth.
+type rateLimitedReader struct {
+	ctx    context.Context
+	r      io.Reader
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > int(l.rate) {
+		p = p[:int(l.rate)]
+	}
+
+	n, err := l.r.Read(p)
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+
+	// Reading ahead of the budget puts the bucket in debt; wait until it is paid off
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		wait := time.NewTimer(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+		select {
+		case <-wait.C:
+		case <-l.ctx.Done():
+			wait.Stop()
+			return n, l.ctx.Err()
+		}
+		l.tokens = 0
+		l.last = time.Now()
+	}
+	return n, err
+}
@@ -0,0 +1,100 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"youtube-api-server/pkg/internal/installer"
+)
+
+func TestSetRateLimit(t *testing.T) {
+	setForTest(t, &RateLimit, 0)
+	t.Cleanup(func() { installer.SetBodyWrapper(nil) })
+	if err := SetRateLimit(-1); err == nil {
+		t.Error("negative rate limit accepted")
+	}
+	if err := SetRateLimit(1 << 20); err != nil || RateLimit != 1<<20 {
+		t.Errorf("SetRateLimit: err %v, RateLimit %d", err, RateLimit)
+	}
+	if args := rateLimitArgs(); !hasArgs(args, "--limit-rate", "1048576") {
+		t.Errorf("rateLimitArgs() = %v", args)
+	}
+	if err := SetRateLimit(0); err != nil || rateLimitArgs() != nil {
+		t.Errorf("removing the limit: err %v, args %v", err, rateLimitArgs())
+	}
+}
+
+func TestRateLimitedReaderThrottles(t *testing.T) {
+	const rate = 64 * 1024
+	data := bytes.Repeat([]byte("x"), rate+rate/2)
+
+	start := time.Now()
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, NewRateLimitedReader(bytes.NewReader(data), rate)); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatal("data changed by the limiter")
+	}
+	// The first second's worth is a burst; the remaining half second is throttled
+	if elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("1.5s worth of data took %v, want about 0.5s after the initial burst", elapsed)
+	}
+}
+
+func TestRateLimitedReaderUnlimited(t *testing.T) {
+	setForTest(t, &RateLimit, 0)
+	r := bytes.NewReader(nil)
+	if got := NewRateLimitedReader(r, 0); got != io.Reader(r) {
+		t.Error("reader wrapped without any limit")
+	}
+	RateLimit = 1000
+	if got := NewRateLimitedReader(r, 0); got == io.Reader(r) {
+		t.Error("RateLimit not applied")
+	}
+}
+
+func TestRateLimitedReaderCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	// Ten seconds worth of data at 1 KB/s
+	_, err := io.Copy(io.Discard, NewRateLimitedReaderWithContext(ctx, bytes.NewReader(make([]byte, 10*1024)), 1024))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled copy took %v", elapsed)
+	}
+}
+
+func TestFetchThumbnailRateLimited(t *testing.T) {
+	const rate = 32 * 1024
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("i"), 2*rate))
+	}))
+	defer server.Close()
+	setForTest(t, &RateLimit, rate)
+
+	dst := filepath.Join(t.TempDir(), "thumb.jpg")
+	start := time.Now()
+	if err := fetchThumbnail(context.Background(), server.URL+"/thumb.jpg", dst); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 700*time.Millisecond {
+		t.Errorf("two seconds worth of thumbnail took %v, want about 1s", elapsed)
+	}
+	if info, err := os.Stat(dst); err != nil || info.Size() != 2*rate {
+		t.Errorf("thumbnail not written: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package downloader
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// batchResumeReason is the SkippedItem reason of URLs a previous run completed
+const batchResumeReason = "already downloaded (state file)"
+
+// BatchOptions configures DownloadBatch.
+// The embedded VideoOptions apply to every URL and Progress receives the
+// overall progress of the batch.
+type BatchOptions struct {
+	VideoOptions
+
+	// StateFile, if set, records every URL that finished downloading, one per
+	// line like yt-dlp's --download-archive. URLs already in it are skipped,
+	// so re-running a batch after a crash resumes where it stopped.
+	StateFile string
+
+	// ManifestPath, if set, is where a JSON manifest of every URL is written
+	// once the batch is done (see PlaylistOptions.ManifestPath)
+	ManifestPath string
+
+	PlaylistSleep
+}
+
+// DownloadBatch downloads a list of videos one at a time. Like
+// DownloadPlaylist, failures are collected in PlaylistResult.Failed instead of
+// stopping the batch, and URLs completed by an earlier run with the same
+// StateFile are reported in PlaylistResult.Skipped.
+//
+// Example:
+//
+//	result, err := downloader.DownloadBatch(ctx, urls, downloader.BatchOptions{
+//	    VideoOptions: downloader.VideoOptions{OutputDir: "./batch"},
+//	    StateFile:    "./batch/.completed",
+//	})
+func DownloadBatch(ctx context.Context, urls []string, opts BatchOptions) (*PlaylistResult, error) {
+	if err := opts.validateSleep(); err != nil {
+		return nil, err
+	}
+
+	completed, err := loadBatchState(opts.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 6*time.Hour)
+	defer cancel()
+
+	info := &PlaylistInfo{Title: "batch", Entries: make([]PlaylistEntry, len(urls))}
+	for i, url := range urls {
+		info.Entries[i] = PlaylistEntry{URL: strings.TrimSpace(url)}
+	}
+
+	result, err := runPlaylist(ctx, info, opts.PlaylistSleep, opts.Progress, func(index int, entry PlaylistEntry, progress ProgressCallback) (string, error) {
+		if completed[entry.URL] {
+			return "", &SkippedError{Reason: batchResumeReason}
+		}
+
+		entryOpts := opts.VideoOptions
+		entryOpts.Progress = progress
+		download, err := DownloadVideoWithOptions(ctx, entry.URL, entryOpts)
+		if err != nil {
+			return "", err
+		}
+		if err := appendBatchState(opts.StateFile, entry.URL); err != nil {
+			// The download itself succeeded; a resumed run just fetches it again
+			fmt.Fprintf(os.Stderr, "[gostreampuller] ⚠ Warning: %v\n", err)
+		}
+		return download.Path, nil
+	})
+	return result, finishPlaylist(opts.ManifestPath, "", result, err)
+}
+
+// loadBatchState returns the URLs recorded in a batch state file. A missing
+// file (or no path) means nothing was completed yet.
+func loadBatchState(path string) (map[string]bool, error) {
+	completed := make(map[string]bool)
+	if path == "" {
+		return completed, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return completed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch state: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// A crash mid-write leaves at most a truncated last line, which just
+		// doesn't match its URL and gets downloaded again
+		if url := strings.TrimSpace(scanner.Text()); url != "" {
+			completed[url] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch state: %w", err)
+	}
+	return completed, nil
+}
+
+// appendBatchState records url as completed, syncing so the record survives a crash
+func appendBatchState(path string, url string) error {
+	if path == "" {
+		return nil
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := makeOutputDir(dir); err != nil {
+			return fmt.Errorf("failed to create batch state directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to update batch state: %w", err)
+	}
+	// Finish a line truncated by a crash so it doesn't swallow this record
+	line := url + "\n"
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = "\n" + line
+		}
+	}
+	if _, err := file.WriteString(line); err != nil {
+		file.Close()
+		return diskFullError(fmt.Errorf("failed to update batch state: %w", err))
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to update batch state: %w", err)
+	}
+	return file.Close()
+}
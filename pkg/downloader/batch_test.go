@@ -0,0 +1,118 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var batchURLs = []string{
+	"https://www.youtube.com/watch?v=aaaaaaaaaaa",
+	"https://www.youtube.com/watch?v=bbbbbbbbbbb",
+	"https://www.youtube.com/watch?v=ccccccccccc",
+}
+
+// batchDownloads returns the URLs yt-dlp was asked to download
+func batchDownloads(calls [][]string) []string {
+	var urls []string
+	for _, args := range calls {
+		if !slices.Contains(args, "--dump-json") && len(args) > 0 {
+			urls = append(urls, args[len(args)-1])
+		}
+	}
+	return urls
+}
+
+func TestDownloadBatchResumesAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state", ".completed")
+	started := filepath.Join(dir, "started")
+
+	// The first run dies while downloading the third URL
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+for a in "$@"; do url="$a"; done
+case "$url" in *ccccccccccc) touch '`+started+`'; exec sleep 10;; esac
+`+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		for i := 0; i < 500; i++ {
+			if _, err := os.Stat(started); err == nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	DownloadBatch(ctx, batchURLs, BatchOptions{VideoOptions: VideoOptions{OutputDir: dir}, StateFile: stateFile})
+	if _, err := os.Stat(started); err != nil {
+		t.Fatal("third download never started")
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(string(data)); !slices.Equal(got, batchURLs[:2]) {
+		t.Fatalf("state file %q, want the first two URLs", data)
+	}
+	// A crash mid-write can also leave a truncated line behind
+	file, _ := os.OpenFile(stateFile, os.O_WRONLY|os.O_APPEND, 0644)
+	file.WriteString("https://www.youtube.com/watch?v=ccc")
+	file.Close()
+
+	// The resumed run only downloads what is missing
+	logLine, calls = argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("mp4"))
+	result, err := DownloadBatch(context.Background(), batchURLs, BatchOptions{VideoOptions: VideoOptions{OutputDir: dir}, StateFile: stateFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := batchDownloads(calls()); !slices.Equal(got, batchURLs[2:]) {
+		t.Errorf("resumed run downloaded %v, want only the third URL", got)
+	}
+	if len(result.Succeeded) != 1 || len(result.Skipped) != 2 {
+		t.Fatalf("succeeded %v, skipped %+v", result.Succeeded, result.Skipped)
+	}
+	for i, skipped := range result.Skipped {
+		if skipped.URL != batchURLs[i] || skipped.Reason != batchResumeReason {
+			t.Errorf("skipped %+v, want %s already downloaded", skipped, batchURLs[i])
+		}
+	}
+
+	// Every URL is recorded now, so a third run downloads nothing
+	logLine, calls = argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("mp4"))
+	if result, err := DownloadBatch(context.Background(), batchURLs, BatchOptions{VideoOptions: VideoOptions{OutputDir: dir}, StateFile: stateFile}); err != nil || len(result.Skipped) != 3 {
+		t.Errorf("third run: %+v, %v", result, err)
+	}
+	if got := batchDownloads(calls()); len(got) != 0 {
+		t.Errorf("third run downloaded %v", got)
+	}
+}
+
+func TestLoadBatchState(t *testing.T) {
+	if completed, err := loadBatchState(""); err != nil || len(completed) != 0 {
+		t.Errorf("no state file: %v, %v", completed, err)
+	}
+	path := filepath.Join(t.TempDir(), "missing")
+	if completed, err := loadBatchState(path); err != nil || len(completed) != 0 {
+		t.Errorf("missing state file: %v, %v", completed, err)
+	}
+
+	for _, url := range batchURLs[:2] {
+		if err := appendBatchState(path, url); err != nil {
+			t.Fatal(err)
+		}
+	}
+	completed, err := loadBatchState(path)
+	if err != nil || len(completed) != 2 || !completed[batchURLs[0]] || !completed[batchURLs[1]] {
+		t.Errorf("loaded %v, %v", completed, err)
+	}
+}
@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// BinarySourceMode controls where yt-dlp and ffmpeg are looked up
+type BinarySourceMode string
+
+const (
+	// BinarySourceAuto prefers binaries in ~/.gostreampuller/bin and falls back to PATH (default)
+	BinarySourceAuto BinarySourceMode = "auto"
+
+	// BinarySourceLocalOnly only uses binaries in ~/.gostreampuller/bin (auto-installing
+	// them if allowed) and returns an error instead of falling back to PATH
+	BinarySourceLocalOnly BinarySourceMode = "local"
+
+	// BinarySourceSystemOnly only uses binaries from PATH and never auto-installs
+	BinarySourceSystemOnly BinarySourceMode = "system"
+)
+
+// BinarySource is where yt-dlp and ffmpeg are looked up (default: BinarySourceAuto).
+// Can be set using SetBinarySource()
+var BinarySource = BinarySourceAuto
+
+// SetBinarySource sets where yt-dlp and ffmpeg are looked up and re-resolves
+// their paths, replacing any set with SetYTDLPPath or SetFFMPEGPath
+func SetBinarySource(source BinarySourceMode) error {
+	switch source {
+	case BinarySourceAuto, BinarySourceLocalOnly, BinarySourceSystemOnly:
+	default:
+		return fmt.Errorf("unknown binary source %q (use auto, local or system)", source)
+	}
+
+	installMutex.Lock()
+	BinarySource = source
+	// Check the newly resolved binaries on next use
+	ytdlpInstallAttempted = false
+	ffmpegInstallAttempted = false
+	installMutex.Unlock()
+
+	ResetBinaryPaths()
+	return nil
+}
+
+// ensureBinariesFromSource is ensureBinaries for BinarySourceLocalOnly and
+// BinarySourceSystemOnly: missing binaries are an error rather than a fallback
+func ensureBinariesFromSource(checkYTDLP bool, checkFFMPEG bool) error {
+	missing := missingBinaries(checkYTDLP, checkFFMPEG)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if BinarySource == BinarySourceLocalOnly && os.Getenv("GOSTREAMPULLER_NO_AUTO_INSTALL") != "1" {
+		if err := autoInstallBinaries(!slices.Contains(missing, "yt-dlp"), !slices.Contains(missing, "ffmpeg")); err != nil {
+			return err
+		}
+		missing = missingBinaries(checkYTDLP, checkFFMPEG)
+		if len(missing) == 0 {
+			return nil
+		}
+	}
+
+	where := "PATH"
+	if BinarySource == BinarySourceLocalOnly {
+		where = localBinDir()
+	}
+	return fmt.Errorf("%s not found in %s (binary source %q)", strings.Join(missing, " and "), where, BinarySource)
+}
+
+// missingBinaries returns the names of the checked binaries that are not
+// available from BinarySource
+func missingBinaries(checkYTDLP bool, checkFFMPEG bool) []string {
+	var missing []string
+	for _, binary := range []struct {
+		check bool
+		name  string
+		path  string
+	}{
+		{checkYTDLP, "yt-dlp", YTDLPPath},
+		{checkFFMPEG, "ffmpeg", FFMPEGPath},
+	} {
+		// A bare name resolves through PATH, which LocalOnly must not use
+		fromPath := binary.path == binary.name
+		if binary.check && (!checkBinaryExists(binary.path) || (BinarySource == BinarySourceLocalOnly && fromPath)) {
+			missing = append(missing, binary.name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// binaryDirs sets up a home directory with a local bin dir and a PATH
+// directory, each holding the named fake binaries, and returns both dirs
+func binaryDirs(t *testing.T, local []string, system []string) (string, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	localDir := filepath.Join(home, ".gostreampuller", "bin")
+	systemDir := t.TempDir()
+	t.Setenv("PATH", systemDir)
+	for dir, names := range map[string][]string{localDir: local, systemDir: system} {
+		for _, name := range names {
+			path := fakeBinary(t, name, "exit 0")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Rename(path, filepath.Join(dir, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	noAutoInstall(t)
+	setForTest(t, &BinarySource, BinarySourceAuto)
+	setForTest(t, &YTDLPPath, YTDLPPath)
+	setForTest(t, &FFMPEGPath, FFMPEGPath)
+	return localDir, systemDir
+}
+
+func TestSetBinarySourceResolution(t *testing.T) {
+	localDir, _ := binaryDirs(t, []string{"yt-dlp", "ffmpeg"}, []string{"yt-dlp", "ffmpeg"})
+
+	if err := SetBinarySource(BinarySourceAuto); err != nil {
+		t.Fatal(err)
+	}
+	if YTDLPPath != filepath.Join(localDir, "yt-dlp") || FFMPEGPath != filepath.Join(localDir, "ffmpeg") {
+		t.Errorf("auto: %s, %s; want the local binaries", YTDLPPath, FFMPEGPath)
+	}
+
+	if err := SetBinarySource(BinarySourceSystemOnly); err != nil {
+		t.Fatal(err)
+	}
+	if YTDLPPath != "yt-dlp" || FFMPEGPath != "ffmpeg" {
+		t.Errorf("system: %s, %s; want PATH lookups", YTDLPPath, FFMPEGPath)
+	}
+	if err := ensureBinariesInstalled(); err != nil {
+		t.Errorf("system binaries on PATH: %v", err)
+	}
+
+	if err := SetBinarySource(BinarySourceLocalOnly); err != nil {
+		t.Fatal(err)
+	}
+	if YTDLPPath != filepath.Join(localDir, "yt-dlp") {
+		t.Errorf("local: %s; want the local binary", YTDLPPath)
+	}
+	if err := ensureBinariesInstalled(); err != nil {
+		t.Errorf("local binaries present: %v", err)
+	}
+
+	if err := SetBinarySource("bundled"); err == nil {
+		t.Error("unknown binary source accepted")
+	}
+}
+
+func TestBinarySourceLocalOnlyNoFallback(t *testing.T) {
+	localDir, _ := binaryDirs(t, nil, []string{"yt-dlp", "ffmpeg"})
+
+	// Auto falls back to PATH
+	if err := SetBinarySource(BinarySourceAuto); err != nil {
+		t.Fatal(err)
+	}
+	if YTDLPPath != "yt-dlp" {
+		t.Errorf("auto without local binaries: %s; want the PATH lookup", YTDLPPath)
+	}
+	if err := ensureBinariesInstalled(); err != nil {
+		t.Errorf("auto with binaries on PATH: %v", err)
+	}
+
+	// LocalOnly refuses the binaries on PATH
+	if err := SetBinarySource(BinarySourceLocalOnly); err != nil {
+		t.Fatal(err)
+	}
+	err := ensureBinariesInstalled()
+	if err == nil || !strings.Contains(err.Error(), "yt-dlp and ffmpeg") || !strings.Contains(err.Error(), localDir) {
+		t.Errorf("err = %v, want both binaries missing from %s", err, localDir)
+	}
+}
+
+func TestBinarySourceSystemOnlyMissing(t *testing.T) {
+	binaryDirs(t, []string{"yt-dlp", "ffmpeg"}, []string{"yt-dlp"})
+
+	if err := SetBinarySource(BinarySourceSystemOnly); err != nil {
+		t.Fatal(err)
+	}
+	// The local ffmpeg is ignored, and nothing is installed
+	err := ensureBinariesInstalled()
+	if err == nil || !strings.Contains(err.Error(), "ffmpeg not found in PATH") {
+		t.Errorf("err = %v, want ffmpeg missing from PATH", err)
+	}
+}
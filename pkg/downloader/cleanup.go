@@ -0,0 +1,88 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// TempFileMaxAge is how old a leftover .part/.ytdl/fragment file must be
+	// before CleanupTempFiles removes it (default: 1 hour).
+	// Younger files may belong to a download that is still running.
+	TempFileMaxAge = time.Hour
+
+	// CleanupBeforeDownload runs CleanupTempFiles on the output directory
+	// before every download (default: false)
+	CleanupBeforeDownload = false
+)
+
+// SetTempFileMaxAge sets the minimum age of files removed by CleanupTempFiles
+func SetTempFileMaxAge(age time.Duration) {
+	if age > 0 {
+		TempFileMaxAge = age
+	}
+}
+
+// SetCleanupBeforeDownload enables or disables cleanup of stale temp files
+// in the output directory before each download
+func SetCleanupBeforeDownload(enabled bool) {
+	CleanupBeforeDownload = enabled
+}
+
+// fragmentPattern matches yt-dlp fragment files like "video.f137.mp4.part-Frag12"
+var fragmentPattern = regexp.MustCompile(`-Frag\d+(\.part)?$`)
+
+// ytdlpTempMarker is inserted before the extension of files yt-dlp's
+// post-processors are still writing, e.g. "video.temp.mp4"
+const ytdlpTempMarker = ".temp"
+
+// isTempFile reports whether name looks like a yt-dlp/ffmpeg leftover
+func isTempFile(name string) bool {
+	return strings.HasSuffix(name, ".part") ||
+		strings.HasSuffix(name, ".ytdl") ||
+		strings.Contains(name, ytdlpTempMarker+".") ||
+		strings.Contains(name, partialMarker+".") ||
+		fragmentPattern.MatchString(name)
+}
+
+// CleanupTempFiles removes stale .part, .ytdl, .temp, .partial and fragment files left in dir
+// by interrupted or crashed downloads. Only files older than TempFileMaxAge
+// are removed; everything else in dir is left untouched.
+// The directory is not searched recursively.
+func CleanupTempFiles(dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	cutoff := time.Now().Add(-TempFileMaxAge)
+	var errs []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isTempFile(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to remove %d temp file(s): %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanupTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	files := map[string]bool{ // name -> should be removed
+		"video_1.mp4.part":             true,
+		"video_1.f137.mp4.ytdl":        true,
+		"video_1.f137.mp4.part-Frag12": true,
+		"video_1.partial.mp4":          true,
+		"video_1.temp.mp4":             true,
+		"video_1.temp.m4a":             true,
+		"temperature.mp4":              false,
+		"video_1.mp4":                  false,
+		"notes.txt":                    false,
+		"partial-results.json":         false,
+	}
+	for name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, old, old)
+	}
+	// A fresh temp file may belong to a running download
+	os.WriteFile(filepath.Join(dir, "running.mp4.part"), nil, 0644)
+	files["running.mp4.part"] = false
+	// Directories are never touched
+	os.Mkdir(filepath.Join(dir, "sub.part"), 0755)
+	files["sub.part"] = false
+
+	if err := CleanupTempFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+	for name, removed := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if removed && !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", name)
+		}
+		if !removed && err != nil {
+			t.Errorf("%s was removed: %v", name, err)
+		}
+	}
+}
+
+func TestCleanupTempFilesMissingDir(t *testing.T) {
+	if err := CleanupTempFiles(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,132 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// minCompressVideoKbps is the lowest video bitrate DownloadAndCompress
+	// encodes at; below it most videos are unwatchable
+	minCompressVideoKbps = 150
+
+	// compressOverhead is the share of the target size left for the video and
+	// audio streams; the rest covers the container
+	compressOverhead = 0.97
+)
+
+// DownloadAndCompress downloads a video and two-pass encodes it with libx264/aac
+// so the file fits in targetSizeMB (MiB), e.g. for upload-limited platforms.
+// Returns an error wrapping ErrTargetSizeTooSmall, before downloading when the
+// duration is known up front, if the target would need a video bitrate below
+// the minimum quality. If outputDir is empty, files are saved to the current
+// working directory.
+func DownloadAndCompress(url string, targetSizeMB int, outputDir string) (string, error) {
+	return DownloadAndCompressWithContext(context.Background(), url, targetSizeMB, outputDir)
+}
+
+// DownloadAndCompressWithContext is DownloadAndCompress with a caller-supplied context
+func DownloadAndCompressWithContext(ctx context.Context, url string, targetSizeMB int, outputDir string) (string, error) {
+	if targetSizeMB <= 0 {
+		return "", fmt.Errorf("target size must be positive, got %d MB", targetSizeMB)
+	}
+	if err := ensureBinariesInstalled(); err != nil {
+		return "", fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+	if !ffmpegAvailable() {
+		return "", fmt.Errorf("%w: compressing video", ErrFFMPEGRequired)
+	}
+	for _, encoder := range []string{"libx264", "aac"} {
+		if err := requireEncoder(encoder); err != nil {
+			return "", err
+		}
+	}
+
+	// Fail before downloading if the target is clearly out of reach
+	if metadata, err := GetVideoMetadataWithContext(ctx, url); err == nil && metadata.Duration > 0 {
+		if _, _, err := compressBitrates(float64(metadata.Duration), targetSizeMB); err != nil {
+			return "", err
+		}
+	}
+
+	result, err := DownloadVideoWithOptions(ctx, url, VideoOptions{OutputDir: outputDir})
+	if err != nil {
+		return "", err
+	}
+	source := result.Path
+
+	// Nothing to do if the download already fits
+	if info, err := os.Stat(source); err == nil && info.Size() <= int64(targetSizeMB)*1024*1024 {
+		return source, nil
+	}
+
+	duration, err := probeDuration(ctx, source)
+	if err != nil {
+		os.Remove(source)
+		return "", err
+	}
+	videoKbps, audioKbps, err := compressBitrates(duration, targetSizeMB)
+	if err != nil {
+		os.Remove(source)
+		return "", err
+	}
+
+	if err := twoPassEncode(ctx, source, source, videoKbps, audioKbps); err != nil {
+		os.Remove(source)
+		return "", fmt.Errorf("ffmpeg compression failed: %w", err)
+	}
+	return source, nil
+}
+
+// compressBitrates returns the video and audio bitrates (kbit/s) that fit a
+// video of duration seconds into targetSizeMB MiB
+func compressBitrates(duration float64, targetSizeMB int) (videoKbps int, audioKbps int, err error) {
+	if duration <= 0 {
+		return 0, 0, fmt.Errorf("invalid duration %.1fs", duration)
+	}
+
+	totalKbps := int(float64(targetSizeMB) * 8 * 1024 * 1024 / 1000 * compressOverhead / duration)
+	audioKbps = 128
+	if totalKbps < 4*audioKbps {
+		// Give the video a bigger share of small budgets
+		audioKbps = 64
+	}
+	videoKbps = totalKbps - audioKbps
+	if videoKbps < minCompressVideoKbps {
+		return 0, 0, fmt.Errorf("%w: %d MB for %.0fs allows %d kbit/s, need at least %d",
+			ErrTargetSizeTooSmall, targetSizeMB, duration, totalKbps, minCompressVideoKbps+audioKbps)
+	}
+	return videoKbps, audioKbps, nil
+}
+
+// twoPassEncode encodes input to output (which may be the same file) at the
+// given bitrates. The first pass only analyses the video.
+func twoPassEncode(ctx context.Context, input string, output string, videoKbps int, audioKbps int) error {
+	passLog := partialPath(output) + "-pass"
+	defer func() {
+		matches, _ := filepath.Glob(passLog + "*")
+		for _, match := range matches {
+			os.Remove(match)
+		}
+	}()
+
+	videoArgs := []string{"-c:v", "libx264", "-preset", "medium", "-b:v", fmt.Sprintf("%dk", videoKbps), "-passlogfile", passLog}
+
+	firstPass := append([]string{"-y", "-i", input}, videoArgs...)
+	firstPass = append(firstPass, "-pass", "1", "-an", "-f", "null", os.DevNull)
+	if _, err := streamCommand(ctx, newCommand(ctx, FFMPEGPath, firstPass...), nil, "converting"); err != nil {
+		return err
+	}
+
+	return convertToFile(ctx, output, nil, func(dst string) []string {
+		args := append([]string{"-y", "-i", input}, videoArgs...)
+		return append(args,
+			"-pass", "2",
+			"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", audioKbps),
+			"-movflags", "+faststart",
+			dst,
+		)
+	})
+}
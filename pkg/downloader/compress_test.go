@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompressBitrates(t *testing.T) {
+	tests := []struct {
+		duration     float64
+		targetSizeMB int
+		video, audio int
+	}{
+		{60, 8, 956, 128},
+		{300, 25, 550, 128},
+		// Small budgets leave more of the bitrate to the video
+		{120, 5, 275, 64},
+		{3600, 500, 1002, 128},
+	}
+	for _, tt := range tests {
+		video, audio, err := compressBitrates(tt.duration, tt.targetSizeMB)
+		if err != nil || video != tt.video || audio != tt.audio {
+			t.Errorf("compressBitrates(%.0fs, %d MB) = %d, %d, %v; want %d, %d", tt.duration, tt.targetSizeMB, video, audio, err, tt.video, tt.audio)
+		}
+		// The streams must fit the target
+		if size := float64(video+audio) * 1000 / 8 * tt.duration; size > float64(tt.targetSizeMB)*1024*1024 {
+			t.Errorf("%.0fs at %d+%d kbit/s is %.0f bytes, over %d MB", tt.duration, video, audio, size, tt.targetSizeMB)
+		}
+	}
+}
+
+func TestCompressBitratesUnachievable(t *testing.T) {
+	if _, _, err := compressBitrates(600, 10); !errors.Is(err, ErrTargetSizeTooSmall) {
+		t.Errorf("10 MB for 10 minutes: err = %v, want ErrTargetSizeTooSmall", err)
+	}
+	if _, _, err := compressBitrates(0, 10); err == nil || errors.Is(err, ErrTargetSizeTooSmall) {
+		t.Errorf("zero duration: err = %v, want an invalid duration error", err)
+	}
+}
+
+func TestDownloadAndCompressRejectsBadTarget(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+`echo '{"id": "aaaaaaaaaaa", "duration": 3600}'`)
+	useFakeFFMPEG(t, ffmpegScript("exit 0"))
+
+	if _, err := DownloadAndCompress("https://www.youtube.com/watch?v=aaaaaaaaaaa", 0, t.TempDir()); err == nil {
+		t.Error("zero target size accepted")
+	}
+	// An hour can't fit in 5 MB; this is known from the metadata alone
+	if _, err := DownloadAndCompress("https://www.youtube.com/watch?v=aaaaaaaaaaa", 5, t.TempDir()); !errors.Is(err, ErrTargetSizeTooSmall) {
+		t.Errorf("err = %v, want ErrTargetSizeTooSmall", err)
+	}
+	for _, call := range calls() {
+		if argValue(call, "-o") != "" {
+			t.Errorf("downloaded an unachievable target: %v", call)
+		}
+	}
+}
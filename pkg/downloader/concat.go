@@ -0,0 +1,189 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConcatVideos joins pre-downloaded videos, in order, into outputPath.
+// Inputs with matching streams (same codecs, resolution and audio layout) are
+// joined with ffmpeg's concat demuxer without re-encoding; otherwise every
+// input is scaled to the first one's resolution and re-encoded. All inputs
+// must have the same kinds of streams (e.g. all video with audio).
+func ConcatVideos(paths []string, outputPath string) error {
+	return ConcatVideosWithContext(context.Background(), paths, outputPath)
+}
+
+// ConcatVideosWithContext is ConcatVideos with a caller-supplied context
+func ConcatVideosWithContext(ctx context.Context, paths []string, outputPath string) error {
+	if len(paths) < 2 {
+		return fmt.Errorf("at least two videos are required, got %d", len(paths))
+	}
+	if outputPath == "" {
+		return fmt.Errorf("output path is required")
+	}
+	if err := ensureBinariesInstalled(); err != nil {
+		return fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+	if !ffmpegAvailable() {
+		return fmt.Errorf("%w: concatenating videos", ErrFFMPEGRequired)
+	}
+
+	infos := make([]*MediaInfo, len(paths))
+	for i, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("input %d: %w", i+1, err)
+		}
+		info, err := ProbeFile(ctx, path)
+		if err != nil {
+			return fmt.Errorf("input %d (%s): %w", i+1, filepath.Base(path), err)
+		}
+		infos[i] = info
+	}
+
+	copyOK, err := concatCompatible(infos)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(outputPath); dir != "." {
+		if err := makeOutputDir(dir); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	if copyOK {
+		list, err := os.CreateTemp("", "gostreampuller-concat-*.txt")
+		if err != nil {
+			return fmt.Errorf("failed to create concat list: %w", err)
+		}
+		defer os.Remove(list.Name())
+		listing, err := concatList(paths)
+		if err == nil {
+			_, err = list.WriteString(listing)
+		}
+		if closeErr := list.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write concat list: %w", err)
+		}
+
+		return convertToFile(ctx, outputPath, nil, func(dst string) []string {
+			return []string{"-f", "concat", "-safe", "0", "-i", list.Name(), "-c", "copy", "-y", dst}
+		})
+	}
+
+	codecs, ok := recodeCodecs[strings.ToLower(strings.TrimPrefix(filepath.Ext(outputPath), "."))]
+	if !ok {
+		codecs = recodeCodecs["mp4"]
+	}
+	for _, encoder := range codecs {
+		if err := requireEncoder(encoder); err != nil {
+			return err
+		}
+	}
+	return convertToFile(ctx, outputPath, nil, func(dst string) []string {
+		return concatFilterArgs(paths, infos[0], codecs, dst)
+	})
+}
+
+// concatList builds an ffmpeg concat demuxer list for paths. Paths are made
+// absolute, since the demuxer resolves them relative to the list file.
+func concatList(paths []string) (string, error) {
+	var b strings.Builder
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		// Quote for the demuxer: ' ends the string, so write it as '\''
+		fmt.Fprintf(&b, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	return b.String(), nil
+}
+
+// concatCompatible reports whether infos can be joined without re-encoding.
+// Returns an error if they cannot be joined at all because their kinds of
+// streams differ.
+func concatCompatible(infos []*MediaInfo) (bool, error) {
+	first := infos[0]
+	if !hasStream(first, "video") {
+		return false, fmt.Errorf("input 1 has no video stream")
+	}
+
+	copyOK := true
+	for i, info := range infos[1:] {
+		if hasStream(info, "video") != hasStream(first, "video") || hasStream(info, "audio") != hasStream(first, "audio") {
+			return false, fmt.Errorf("input %d does not have the same kinds of streams as input 1", i+2)
+		}
+		if len(info.Streams) != len(first.Streams) {
+			copyOK = false
+			continue
+		}
+		for j, stream := range info.Streams {
+			if stream != first.Streams[j] {
+				copyOK = false
+			}
+		}
+	}
+	return copyOK, nil
+}
+
+// hasStream reports whether info has a stream of kind ("video", "audio", ...)
+func hasStream(info *MediaInfo, kind string) bool {
+	for _, stream := range info.Streams {
+		if stream.CodecType == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// concatFilterArgs builds ffmpeg arguments joining paths with the concat
+// filter, scaling (letterboxed) every video to the first one's resolution
+func concatFilterArgs(paths []string, first *MediaInfo, codecs [2]string, output string) []string {
+	var width, height, sampleRate int
+	for _, stream := range first.Streams {
+		switch {
+		case stream.CodecType == "video" && width == 0:
+			width, height = stream.Width, stream.Height
+		case stream.CodecType == "audio" && sampleRate == 0:
+			sampleRate = stream.SampleRate
+		}
+	}
+	withAudio := hasStream(first, "audio")
+	if sampleRate == 0 {
+		sampleRate = 48000
+	}
+
+	var args []string
+	var filter, inputs strings.Builder
+	for i, path := range paths {
+		args = append(args, "-i", path)
+		fmt.Fprintf(&filter, "[%d:v:0]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1[v%d];",
+			i, width, height, width, height, i)
+		fmt.Fprintf(&inputs, "[v%d]", i)
+		if withAudio {
+			fmt.Fprintf(&filter, "[%d:a:0]aresample=%d,aformat=channel_layouts=stereo[a%d];", i, sampleRate, i)
+			fmt.Fprintf(&inputs, "[a%d]", i)
+		}
+	}
+	if withAudio {
+		fmt.Fprintf(&filter, "%sconcat=n=%d:v=1:a=1[v][a]", inputs.String(), len(paths))
+	} else {
+		fmt.Fprintf(&filter, "%sconcat=n=%d:v=1:a=0[v]", inputs.String(), len(paths))
+	}
+
+	args = append(args, "-filter_complex", filter.String(), "-map", "[v]", "-c:v", codecs[0])
+	if withAudio {
+		args = append(args, "-map", "[a]", "-c:a", codecs[1])
+	}
+	if codecs[0] == "libx264" {
+		args = append(args, "-preset", "veryfast", "-crf", "23")
+	}
+	return append(args, "-movflags", "+faststart", "-y", output)
+}
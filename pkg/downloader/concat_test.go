@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConcatList(t *testing.T) {
+	dir := t.TempDir()
+	listing, err := concatList([]string{filepath.Join(dir, "one.mp4"), filepath.Join(dir, "it's two.mp4")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "file '" + filepath.Join(dir, "one.mp4") + "'\n" +
+		"file '" + filepath.Join(dir, `it'\''s two.mp4`) + "'\n"
+	if listing != want {
+		t.Errorf("concatList = %q, want %q", listing, want)
+	}
+
+	// Relative paths are resolved, since the list lives elsewhere
+	listing, err = concatList([]string{"clip.mp4", "other.mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(listing), "\n") {
+		path := strings.TrimSuffix(strings.TrimPrefix(line, "file '"), "'")
+		if !filepath.IsAbs(path) {
+			t.Errorf("relative path in %q", line)
+		}
+	}
+}
+
+func TestConcatCompatible(t *testing.T) {
+	h264 := MediaStream{CodecType: "video", CodecName: "h264", Width: 1920, Height: 1080}
+	aac := MediaStream{CodecType: "audio", CodecName: "aac", SampleRate: 48000, Channels: 2}
+	vp9 := MediaStream{CodecType: "video", CodecName: "vp9", Width: 1920, Height: 1080}
+	vertical := MediaStream{CodecType: "video", CodecName: "h264", Width: 1080, Height: 1920}
+	media := func(streams ...MediaStream) *MediaInfo { return &MediaInfo{Streams: streams} }
+
+	tests := []struct {
+		name    string
+		infos   []*MediaInfo
+		copyOK  bool
+		wantErr bool
+	}{
+		{"identical", []*MediaInfo{media(h264, aac), media(h264, aac), media(h264, aac)}, true, false},
+		{"different codec", []*MediaInfo{media(h264, aac), media(vp9, aac)}, false, false},
+		{"different resolution", []*MediaInfo{media(h264, aac), media(vertical, aac)}, false, false},
+		{"extra stream", []*MediaInfo{media(h264, aac), media(h264, aac, aac)}, false, false},
+		{"video only", []*MediaInfo{media(h264), media(h264)}, true, false},
+		{"missing audio", []*MediaInfo{media(h264, aac), media(h264)}, false, true},
+		{"audio only", []*MediaInfo{media(aac), media(aac)}, false, true},
+	}
+	for _, tt := range tests {
+		copyOK, err := concatCompatible(tt.infos)
+		if copyOK != tt.copyOK || (err != nil) != tt.wantErr {
+			t.Errorf("%s: concatCompatible = %v, %v; want %v (error %v)", tt.name, copyOK, err, tt.copyOK, tt.wantErr)
+		}
+	}
+}
+
+// concatProbe is a fake ffprobe reporting 1080p h264/aac, or vertical video
+// for files with "vertical" in their name
+const concatProbe = `for a in "$@"; do last="$a"; done
+case "$last" in
+*vertical*) echo '{"format":{"format_name":"mov,mp4"},"streams":[{"codec_type":"video","codec_name":"h264","width":1080,"height":1920},{"codec_type":"audio","codec_name":"aac","sample_rate":"44100","channels":2}]}';;
+*) echo '{"format":{"format_name":"mov,mp4"},"streams":[{"codec_type":"video","codec_name":"h264","width":1920,"height":1080},{"codec_type":"audio","codec_name":"aac","sample_rate":"48000","channels":2}]}';;
+esac`
+
+// concatInputs creates empty input videos with the given names
+func concatInputs(t *testing.T, names ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestConcatVideosCopy(t *testing.T) {
+	listCopy := filepath.Join(t.TempDir(), "list.txt")
+	ffmpegLog, calls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+`
+prev=""; for a in "$@"; do [ "$prev" = "-i" ] && cp "$a" '`+listCopy+`'; prev="$a"; done
+`+writeLastArg), concatProbe)
+	paths := concatInputs(t, "a.mp4", "b.mp4")
+	output := filepath.Join(t.TempDir(), "joined.mp4")
+
+	if err := ConcatVideos(paths, output); err != nil {
+		t.Fatal(err)
+	}
+	args := calls()[0]
+	if !hasArgs(args, "-f", "concat", "-safe", "0") || !hasArgs(args, "-c", "copy") {
+		t.Errorf("ffmpeg args %v, want a stream copy with the concat demuxer", args)
+	}
+	if data, _ := os.ReadFile(listCopy); string(data) != "file '"+paths[0]+"'\nfile '"+paths[1]+"'\n" {
+		t.Errorf("concat list %q", data)
+	}
+	if _, err := os.Stat(argValue(args, "-i")); !os.IsNotExist(err) {
+		t.Error("concat list not removed")
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("output not written: %v", err)
+	}
+}
+
+func TestConcatVideosReencode(t *testing.T) {
+	ffmpegLog, calls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg), concatProbe)
+	paths := concatInputs(t, "wide.mp4", "vertical.mp4")
+
+	if err := ConcatVideos(paths, filepath.Join(t.TempDir(), "joined.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	args := calls()[0]
+	filter := argValue(args, "-filter_complex")
+	if !strings.Contains(filter, "scale=1920:1080") || !strings.Contains(filter, "aresample=48000") || !strings.Contains(filter, "concat=n=2:v=1:a=1") {
+		t.Errorf("filter %q, want both inputs scaled to the first one's 1080p", filter)
+	}
+	if !hasArgs(args, "-c:v", "libx264") || slices.Contains(args, "concat") {
+		t.Errorf("ffmpeg args %v, want a libx264 re-encode", args)
+	}
+}
+
+func TestConcatVideosValidatesInputs(t *testing.T) {
+	ffmpegLog, calls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog), concatProbe)
+	paths := concatInputs(t, "a.mp4")
+
+	if err := ConcatVideos(paths, filepath.Join(t.TempDir(), "joined.mp4")); err == nil {
+		t.Error("a single input accepted")
+	}
+	missing := append(paths, filepath.Join(t.TempDir(), "missing.mp4"))
+	if err := ConcatVideos(missing, filepath.Join(t.TempDir(), "joined.mp4")); err == nil || !strings.Contains(err.Error(), "input 2") {
+		t.Errorf("err = %v, want input 2 missing", err)
+	}
+	if len(calls()) != 0 {
+		t.Error("ffmpeg ran for invalid inputs")
+	}
+}
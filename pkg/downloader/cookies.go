@@ -0,0 +1,97 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	// CookiesFile is a Netscape-format cookies.txt passed to yt-dlp with --cookies.
+	// Can be set using SetCookiesFile()
+	CookiesFile string
+
+	// CookiesFromBrowser is the --cookies-from-browser value ("firefox",
+	// "chrome:Profile 1", ...). Can be set using SetCookiesFromBrowser()
+	CookiesFromBrowser string
+)
+
+// cookieBrowsers are the browsers yt-dlp can read cookies from
+var cookieBrowsers = map[string]bool{
+	"brave":    true,
+	"chrome":   true,
+	"chromium": true,
+	"edge":     true,
+	"firefox":  true,
+	"opera":    true,
+	"safari":   true,
+	"vivaldi":  true,
+	"whale":    true,
+}
+
+// SetCookiesFile sets the cookies.txt file used for every yt-dlp request.
+// An empty path stops sending cookies from a file.
+func SetCookiesFile(path string) error {
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("cookies file: %w", err)
+		}
+	}
+	CookiesFile = path
+	return nil
+}
+
+// SetCookiesFromBrowser makes yt-dlp read cookies directly from a browser's
+// cookie store, so no cookies.txt has to be maintained. profile is optional
+// (name or path of the browser profile). An empty browser disables it.
+//
+// Example:
+//
+//	downloader.SetCookiesFromBrowser("chrome", "Profile 1")
+func SetCookiesFromBrowser(browser string, profile string) error {
+	if browser == "" {
+		CookiesFromBrowser = ""
+		return nil
+	}
+	value, err := cookiesFromBrowserValue(browser, profile)
+	if err != nil {
+		return err
+	}
+	CookiesFromBrowser = value
+	return nil
+}
+
+// cookiesFromBrowserValue builds the --cookies-from-browser value BROWSER[:PROFILE]
+func cookiesFromBrowserValue(browser string, profile string) (string, error) {
+	browser = strings.ToLower(strings.TrimSpace(browser))
+	if !cookieBrowsers[browser] {
+		return "", fmt.Errorf("unsupported browser %q for cookies (use one of: brave, chrome, chromium, edge, firefox, opera, safari, vivaldi, whale)", browser)
+	}
+	if profile == "" {
+		return browser, nil
+	}
+	if strings.Contains(profile, "::") {
+		return "", fmt.Errorf("invalid browser profile %q", profile)
+	}
+	return browser + ":" + profile, nil
+}
+
+// cookieArgs returns the yt-dlp flags for the configured cookies
+func cookieArgs() []string {
+	var args []string
+	if CookiesFile != "" {
+		args = append(args, "--cookies", CookiesFile)
+	}
+	if CookiesFromBrowser != "" {
+		args = append(args, "--cookies-from-browser", CookiesFromBrowser)
+	}
+	return args
+}
+
+// ytdlpCommand returns a yt-dlp command for extracting or downloading media,
+// with the configured cookies and TLS settings applied
+func ytdlpCommand(ctx context.Context, args ...string) *exec.Cmd {
+	return newCommand(ctx, YTDLPPath, append(append(cookieArgs(), tlsArgs()...), args...)...)
+}
@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCookiesFromBrowserValue(t *testing.T) {
+	tests := []struct {
+		browser, profile string
+		want             string
+		wantErr          bool
+	}{
+		{"chrome", "", "chrome", false},
+		{" Firefox ", "", "firefox", false},
+		{"chrome", "Profile 1", "chrome:Profile 1", false},
+		{"firefox", "/home/me/.mozilla/firefox/abc.default", "firefox:/home/me/.mozilla/firefox/abc.default", false},
+		{"edge", "Default", "edge:Default", false},
+		{"netscape", "", "", true},
+		{"chrome", "evil::keyring", "", true},
+	}
+	for _, tt := range tests {
+		got, err := cookiesFromBrowserValue(tt.browser, tt.profile)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("cookiesFromBrowserValue(%q, %q) = %q, %v; want %q (error %v)", tt.browser, tt.profile, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetCookiesFromBrowser(t *testing.T) {
+	setForTest(t, &CookiesFromBrowser, "")
+	if err := SetCookiesFromBrowser("lynx", ""); err == nil {
+		t.Error("unsupported browser accepted")
+	}
+	if err := SetCookiesFromBrowser("Chrome", "Profile 1"); err != nil || CookiesFromBrowser != "chrome:Profile 1" {
+		t.Errorf("SetCookiesFromBrowser: err %v, CookiesFromBrowser %q", err, CookiesFromBrowser)
+	}
+	if err := SetCookiesFromBrowser("", "ignored"); err != nil || CookiesFromBrowser != "" {
+		t.Errorf("disabling: err %v, CookiesFromBrowser %q", err, CookiesFromBrowser)
+	}
+	if err := SetCookiesFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing cookies file accepted")
+	}
+}
+
+func TestCookieArgs(t *testing.T) {
+	setForTest(t, &CookiesFile, "")
+	setForTest(t, &CookiesFromBrowser, "")
+	if args := cookieArgs(); args != nil {
+		t.Errorf("cookieArgs() = %v without cookies", args)
+	}
+	CookiesFile = "cookies.txt"
+	CookiesFromBrowser = "firefox:work"
+	want := []string{"--cookies", "cookies.txt", "--cookies-from-browser", "firefox:work"}
+	if got := cookieArgs(); !slices.Equal(got, want) {
+		t.Errorf("cookieArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestDownloadCookiesFromBrowser(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &CookiesFromBrowser, "")
+	if err := SetCookiesFromBrowser("chrome", "Profile 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	if args := calls()[0]; argValue(args, "--cookies-from-browser") != "chrome:Profile 1" {
+		t.Errorf("--cookies-from-browser not passed: %v", args)
+	}
+}
+
+func TestLockedCookieDatabase(t *testing.T) {
+	useFakeYTDLP(t, `echo "ERROR: Could not copy Chrome cookie database. See https://github.com/yt-dlp/yt-dlp/issues/7271" >&2
+exit 1`)
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &CookiesFromBrowser, "chrome")
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if !errors.Is(err, ErrCookiesUnavailable) {
+		t.Errorf("err = %v, want ErrCookiesUnavailable", err)
+	}
+	if classified := classifyError("ERROR: sqlite3.OperationalError: database is locked"); classified == nil || !errors.Is(classified, ErrCookiesUnavailable) {
+		t.Errorf("locked database classified as %v", classified)
+	}
+}
@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// coverArtFormats are the audio formats that can carry embedded cover art
+var coverArtFormats = map[string]bool{
+	"mp3":  true,
+	"m4a":  true,
+	"flac": true,
+}
+
+// validateCoverArtFormat returns an error if format cannot hold cover art
+func validateCoverArtFormat(format string) error {
+	if !coverArtFormats[strings.ToLower(format)] {
+		return fmt.Errorf("cover art cannot be embedded in %q (use mp3, m4a or flac)", format)
+	}
+	return nil
+}
+
+// coverArtArgs are the yt-dlp flags that save the thumbnail next to the download as jpg.
+// yt-dlp's own --embed-thumbnail would be lost when the audio is re-encoded,
+// so the cover is embedded during our ffmpeg conversion instead.
+func coverArtArgs() []string {
+	return []string{"--write-thumbnail", "--convert-thumbnails", "jpg"}
+}
+
+// findCoverArt returns the jpg thumbnail yt-dlp wrote for the output template,
+// converting a leftover webp (some players can't read webp) when needed.
+// Returns "" if no thumbnail was written.
+func findCoverArt(ctx context.Context, temp string) (string, error) {
+	jpg := strings.Replace(temp, "%(ext)s", "jpg", 1)
+	if _, err := os.Stat(jpg); err == nil {
+		return jpg, nil
+	}
+
+	for _, ext := range []string{"webp", "png"} {
+		candidate := strings.Replace(temp, "%(ext)s", ext, 1)
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		defer os.Remove(candidate)
+		if err := convertImageToJPG(ctx, candidate, jpg); err != nil {
+			return "", err
+		}
+		return jpg, nil
+	}
+
+	return "", nil
+}
+
+// convertImageToJPG converts an image (e.g. webp) to jpg with ffmpeg
+func convertImageToJPG(ctx context.Context, src string, dst string) error {
+	cmd := newCommand(ctx, FFMPEGPath, "-i", src, "-q:v", "2", "-y", dst)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to convert thumbnail to jpg: %v: %s", err, lastLine(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCoverArtFormat(t *testing.T) {
+	for _, format := range []string{"mp3", "M4A", "flac"} {
+		if err := validateCoverArtFormat(format); err != nil {
+			t.Errorf("validateCoverArtFormat(%q) = %v", format, err)
+		}
+	}
+	for _, format := range []string{"opus", "wav", ""} {
+		if err := validateCoverArtFormat(format); err == nil {
+			t.Errorf("validateCoverArtFormat(%q) accepted", format)
+		}
+	}
+}
+
+func TestFindCoverArtConvertsWebp(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, logLine+"\n"+writeLastArg)
+	temp := filepath.Join(t.TempDir(), "audio.%(ext)s")
+	webp := strings.Replace(temp, "%(ext)s", "webp", 1)
+	if err := os.WriteFile(webp, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cover, err := findCoverArt(context.Background(), temp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(temp, "%(ext)s", "jpg", 1); cover != want {
+		t.Errorf("cover = %q, want %q", cover, want)
+	}
+	if args := calls()[0]; argValue(args, "-i") != webp {
+		t.Errorf("ffmpeg args %v, want -i %s", args, webp)
+	}
+	if _, err := os.Stat(webp); !os.IsNotExist(err) {
+		t.Error("webp thumbnail was not removed")
+	}
+}
+
+func TestFindCoverArtJPGNeedsNoConversion(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, logLine)
+	temp := filepath.Join(t.TempDir(), "audio.%(ext)s")
+
+	if cover, err := findCoverArt(context.Background(), temp); cover != "" || err != nil {
+		t.Errorf("without a thumbnail: got %q, %v", cover, err)
+	}
+
+	jpg := strings.Replace(temp, "%(ext)s", "jpg", 1)
+	if err := os.WriteFile(jpg, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cover, err := findCoverArt(context.Background(), temp); cover != jpg || err != nil {
+		t.Errorf("got %q, %v; want %q", cover, err, jpg)
+	}
+	if len(calls()) != 0 {
+		t.Errorf("ffmpeg ran for a jpg thumbnail: %v", calls())
+	}
+}
+
+func TestDownloadAudioEmbedCoverArt(t *testing.T) {
+	ytdlpLog, ytdlpCalls := argsLog(t)
+	useFakeYTDLP(t, ytdlpLog+"\n"+writeOutput("webm")+`
+printf 'image' > "$(printf '%s' "$out" | sed 's/%(ext)s/webp/')"`)
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg))
+
+	_, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:        "mp3",
+		OutputDir:     t.TempDir(),
+		EmbedCoverArt: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := ytdlpCalls()[0]; !hasArgs(args, "--write-thumbnail", "--convert-thumbnails", "jpg") {
+		t.Errorf("yt-dlp args %v lack the thumbnail flags", args)
+	}
+
+	calls := ffmpegCalls()
+	if len(calls) != 2 {
+		t.Fatalf("ffmpeg ran %d times, want thumbnail and audio conversion: %v", len(calls), calls)
+	}
+	if !strings.HasSuffix(argValue(calls[0], "-i"), ".webp") {
+		t.Errorf("first ffmpeg call %v does not convert the webp thumbnail", calls[0])
+	}
+	convert := calls[1]
+	if !hasArgs(convert, "-disposition:v:0", "attached_pic") || !hasArgs(convert, "-id3v2_version", "3") {
+		t.Errorf("audio conversion %v does not embed the cover", convert)
+	}
+	if !strings.HasSuffix(convert[3], ".jpg") {
+		t.Errorf("cover input = %q, want the converted jpg", convert[3])
+	}
+}
+
+func TestDownloadAudioEmbedCoverArtRejectsFormat(t *testing.T) {
+	useFakeYTDLP(t, "exit 1")
+	useFakeFFMPEG(t, ffmpegScript("exit 0"))
+
+	_, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:        "opus",
+		OutputDir:     t.TempDir(),
+		EmbedCoverArt: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "cover art") {
+		t.Errorf("err = %v, want a cover art format error", err)
+	}
+}
@@ -0,0 +1,23 @@
+package downloader
+
+import (
+	"context"
+	"time"
+)
+
+// defaultDownloadTimeout bounds a download and its conversion together when
+// neither the options nor the caller's context set a deadline
+const defaultDownloadTimeout = 50 * time.Minute
+
+// withDownloadDeadline returns the context shared by the download and convert
+// stages, so conversion only gets whatever time the download left over.
+// A positive timeout applies on top of any deadline ctx already has.
+func withDownloadDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		if _, ok := ctx.Deadline(); ok {
+			return context.WithCancel(ctx)
+		}
+		timeout = defaultDownloadTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,59 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithDownloadDeadline(t *testing.T) {
+	ctx, cancel := withDownloadDeadline(context.Background(), 0)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > defaultDownloadTimeout || time.Until(deadline) < defaultDownloadTimeout-time.Minute {
+		t.Errorf("default deadline %v, want about %v from now", deadline, defaultDownloadTimeout)
+	}
+
+	// The caller's deadline is kept when no timeout is given
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelParent()
+	parentDeadline, _ := parent.Deadline()
+	ctx, cancel = withDownloadDeadline(parent, 0)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(parentDeadline) {
+		t.Errorf("deadline %v, want the caller's %v", deadline, parentDeadline)
+	}
+
+	// A timeout can only shorten the caller's deadline
+	ctx, cancel = withDownloadDeadline(parent, time.Hour)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(parentDeadline) {
+		t.Errorf("deadline %v, want the caller's earlier %v", deadline, parentDeadline)
+	}
+	ctx, cancel = withDownloadDeadline(parent, time.Second)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); !deadline.Before(parentDeadline) {
+		t.Errorf("deadline %v not shortened by the timeout", deadline)
+	}
+}
+
+func TestShortDeadlineCancelsConvert(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("webm"))
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\nexec sleep 10"))
+
+	start := time.Now()
+	_, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "mp3",
+		OutputDir: t.TempDir(),
+		Timeout:   500 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("conversion outlived the download deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v, want the conversion cancelled at the 500ms deadline", elapsed)
+	}
+	if len(ffmpegCalls()) == 0 {
+		t.Error("deadline hit before the convert stage started")
+	}
+}
@@ -0,0 +1,27 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// diskFullError maps an ENOSPC anywhere in err's chain to ErrDiskFull,
+// keeping err for details. Other errors are returned unchanged.
+func diskFullError(err error) error {
+	if err != nil && !errors.Is(err, ErrDiskFull) && errors.Is(err, syscall.ENOSPC) {
+		return fmt.Errorf("%w: %w", ErrDiskFull, err)
+	}
+	return err
+}
+
+// removeTemplateFiles removes every file yt-dlp may have written for an
+// output template (the download itself, fragments, thumbnails, ...).
+// Fragments and .ytdl files are named after the full file name, so they
+// share the template's prefix too.
+func removeTemplateFiles(temp string) {
+	for _, match := range templateFiles(temp) {
+		os.Remove(match)
+	}
+}
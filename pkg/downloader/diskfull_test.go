@@ -0,0 +1,92 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestDiskFullError(t *testing.T) {
+	enospc := &os.PathError{Op: "write", Path: "out.mp4", Err: syscall.ENOSPC}
+	if err := diskFullError(fmt.Errorf("copy: %w", enospc)); !errors.Is(err, ErrDiskFull) || !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("diskFullError(ENOSPC) = %v, want ErrDiskFull keeping the cause", err)
+	}
+	other := errors.New("permission denied")
+	if err := diskFullError(other); err != other {
+		t.Errorf("diskFullError(other) = %v, want it unchanged", err)
+	}
+	if diskFullError(nil) != nil {
+		t.Error("diskFullError(nil) != nil")
+	}
+	// Already mapped errors are not wrapped twice
+	mapped := diskFullError(enospc)
+	if err := diskFullError(mapped); err != mapped {
+		t.Errorf("diskFullError wrapped ErrDiskFull again: %v", err)
+	}
+}
+
+func TestCopyFileStreamingDiskFull(t *testing.T) {
+	setForTest(t, &ChunkSize, 4)
+	writes := 0
+	setForTest(t, &writeCopy, func(f *os.File, p []byte) (int, error) {
+		if writes++; writes > 1 {
+			return 0, &os.PathError{Op: "write", Path: f.Name(), Err: syscall.ENOSPC}
+		}
+		return f.Write(p)
+	})
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.mp4")
+	dst := filepath.Join(dir, "out", "dst.mp4")
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := copyFileStreaming(context.Background(), src, dst, nil)
+	if !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("err = %v, want ErrDiskFull", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dst)); len(entries) != 0 {
+		t.Errorf("partial files left behind: %v", entries)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("source removed: %v", err)
+	}
+}
+
+func TestDownloadDiskFullCleansUp(t *testing.T) {
+	// yt-dlp leaves a part file and a fragment before running out of space
+	useFakeYTDLP(t, `out=""; prev=""
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+base="$(printf '%s' "$out" | sed 's/%(ext)s/mp4/')"
+printf 'partial' > "$base.part"
+printf 'fragment' > "$base.part-Frag3"
+echo "ERROR: unable to write data: [Errno 28] No space left on device" >&2
+exit 1`)
+	useFakeFFMPEG(t, "exit 0")
+	// Glob metacharacters in the directory must not stop the cleanup
+	dir := filepath.Join(t.TempDir(), "videos [1] *")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	unrelated := filepath.Join(dir, "keep.mp4")
+	if err := os.WriteFile(unrelated, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: dir})
+	if !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("err = %v, want ErrDiskFull", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "keep.mp4" {
+		t.Errorf("directory holds %v, want only the unrelated file", entries)
+	}
+}
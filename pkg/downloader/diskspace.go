@@ -0,0 +1,80 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInsufficientDiskSpace means the estimated download would not fit in the
+// output directory's free space; nothing was downloaded
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// diskSpaceFactor covers a conversion writing a second copy next to the download
+const diskSpaceFactor = 2
+
+// freeDiskSpace reports the bytes available to this process on the filesystem
+// holding path; a variable so the platform call can be swapped out
+var freeDiskSpace = platformFreeDiskSpace
+
+// DiskSpaceError reports a download that would not fit; it unwraps to
+// ErrInsufficientDiskSpace
+type DiskSpaceError struct {
+	Dir       string
+	Required  int64 // Estimated bytes needed
+	Available int64
+}
+
+func (e *DiskSpaceError) Error() string {
+	return fmt.Sprintf("%s: %s needs about %d MB but only %d MB are free",
+		ErrInsufficientDiskSpace, e.Dir, e.Required/(1024*1024), e.Available/(1024*1024))
+}
+
+func (e *DiskSpaceError) Unwrap() error {
+	return ErrInsufficientDiskSpace
+}
+
+// FreeDiskSpace returns the bytes available to this process on the filesystem
+// holding dir (the current directory if empty)
+func FreeDiskSpace(dir string) (int64, error) {
+	if dir == "" {
+		dir = "."
+	}
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read free disk space of %s: %w", dir, err)
+	}
+	return free, nil
+}
+
+// CheckDiskSpace returns a *DiskSpaceError if required bytes don't fit in
+// dir's free space. Platforms where free space can't be read pass the check.
+func CheckDiskSpace(dir string, required int64) error {
+	free, err := FreeDiskSpace(dir)
+	if err != nil || required <= free {
+		return nil
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return &DiskSpaceError{Dir: dir, Required: required, Available: free}
+}
+
+// checkDownloadSpace estimates the size of url from its metadata and checks
+// dir can hold it with room for a conversion. Videos without a size estimate
+// (e.g. live streams) pass the check.
+func checkDownloadSpace(ctx context.Context, url string, dir string) error {
+	lookupCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	metadata, err := GetVideoMetadataWithContext(lookupCtx, url)
+	if err != nil {
+		return err
+	}
+	size := max(metadata.Filesize, metadata.FilesizeApprox)
+	if size <= 0 {
+		return nil
+	}
+	return CheckDiskSpace(dir, size*diskSpaceFactor)
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package downloader
+
+import "errors"
+
+// platformFreeDiskSpace is not available on this platform
+func platformFreeDiskSpace(path string) (int64, error) {
+	return 0, errors.New("free disk space is not available on this platform")
+}
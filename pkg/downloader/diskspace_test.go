@@ -0,0 +1,100 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// useFreeDiskSpace reports free bytes of free (or err) for every directory
+// for the rest of the test
+func useFreeDiskSpace(t *testing.T, free int64, err error) {
+	t.Helper()
+	setForTest(t, &freeDiskSpace, func(string) (int64, error) { return free, err })
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	useFreeDiskSpace(t, 100<<20, nil)
+
+	if err := CheckDiskSpace("/downloads", 100<<20); err != nil {
+		t.Errorf("exact fit rejected: %v", err)
+	}
+	err := CheckDiskSpace("/downloads", 300<<20)
+	var spaceErr *DiskSpaceError
+	if !errors.Is(err, ErrInsufficientDiskSpace) || !errors.As(err, &spaceErr) {
+		t.Fatalf("err = %v, want a DiskSpaceError", err)
+	}
+	if spaceErr.Dir != "/downloads" || spaceErr.Required != 300<<20 || spaceErr.Available != 100<<20 {
+		t.Errorf("DiskSpaceError %+v", spaceErr)
+	}
+	if err.Error() != "insufficient disk space: /downloads needs about 300 MB but only 100 MB are free" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	// Free space that can't be read doesn't block downloads
+	useFreeDiskSpace(t, 0, errors.New("not supported"))
+	if err := CheckDiskSpace("", 1<<40); err != nil {
+		t.Errorf("unreadable free space: %v", err)
+	}
+	if _, err := FreeDiskSpace(""); err == nil {
+		t.Error("FreeDiskSpace hid the platform error")
+	}
+}
+
+func TestDownloadChecksFreeSpace(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+case "$*" in *--dump-json*) echo '{"id": "aaaaaaaaaaa", "title": "Video", "filesize_approx": 104857600}'; exit 0;; esac
+`+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+	// 100 MB estimate, so 200 MB are needed to leave room for a conversion
+	useFreeDiskSpace(t, 150<<20, nil)
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir:      t.TempDir(),
+		CheckFreeSpace: true,
+	})
+	var spaceErr *DiskSpaceError
+	if !errors.As(err, &spaceErr) || spaceErr.Required != 200<<20 {
+		t.Fatalf("err = %v, want 200 MB required", err)
+	}
+	for _, args := range calls() {
+		if !slices.Contains(args, "--dump-json") {
+			t.Errorf("downloaded despite the space check: %v", args)
+		}
+	}
+
+	useFreeDiskSpace(t, 250<<20, nil)
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir:      t.TempDir(),
+		CheckFreeSpace: true,
+	}); err != nil {
+		t.Errorf("download with enough space: %v", err)
+	}
+}
+
+func TestDownloadFreeSpaceUnknownSize(t *testing.T) {
+	useFakeYTDLP(t, `case "$*" in *--dump-json*) echo '{"id": "aaaaaaaaaaa", "title": "Live", "is_live": true}'; exit 0;; esac
+`+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+	useFreeDiskSpace(t, 0, nil)
+
+	// Without a size estimate there is nothing to check against
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir:      t.TempDir(),
+		CheckFreeSpace: true,
+	}); err != nil {
+		t.Errorf("download without size estimate: %v", err)
+	}
+}
+
+func TestPlatformFreeDiskSpace(t *testing.T) {
+	free, err := platformFreeDiskSpace(t.TempDir())
+	if err != nil {
+		t.Skipf("free space not available here: %v", err)
+	}
+	if free <= 0 {
+		t.Errorf("free space = %d", free)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package downloader
+
+import "syscall"
+
+// platformFreeDiskSpace uses statfs; Bavail excludes blocks reserved for root
+func platformFreeDiskSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(uint64(stat.Bavail) * uint64(stat.Bsize)), nil
+}
@@ -4,34 +4,49 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"youtube-api-server/pkg/internal/installer"
 )
 
 // Auto-installation state
 var (
-	installAttempted bool
-	installMutex     sync.Mutex
-	autoInstallOnce  sync.Once
+	ytdlpInstallAttempted  bool
+	ffmpegInstallAttempted bool
+	installMutex           sync.Mutex
+	autoInstallOnce        sync.Once
 )
 
-// tryGetLocalBinary attempts to find a locally installed binary
-func tryGetLocalBinary(name string) string {
+// localBinDir returns the directory auto-installed binaries live in, or "" if
+// the home directory is unknown
+func localBinDir() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return name // Fall back to system PATH
+		return ""
 	}
+	return filepath.Join(homeDir, ".gostreampuller", "bin")
+}
 
-	binDir := filepath.Join(homeDir, ".gostreampuller", "bin")
+// tryGetLocalBinary attempts to find a locally installed binary.
+// BinarySource decides whether PATH (the bare name) can be used instead.
+func tryGetLocalBinary(name string) string {
+	binDir := localBinDir()
+	if binDir == "" || BinarySource == BinarySourceSystemOnly {
+		return name // Fall back to system PATH
+	}
 
 	// Check for executable with .exe on Windows
 	var candidates []string
@@ -111,23 +126,44 @@ func wasInstalledViaCLI() bool {
 // - GOSTREAMPULLER_NO_AUTO_INSTALL=1 is set
 // - Binaries were already installed via gostreampuller-cli setup
 func ensureBinariesInstalled() error {
-	// Only attempt installation once
+	return ensureBinaries(true)
+}
+
+// ensureYTDLPInstalled is ensureBinariesInstalled for operations that only run
+// yt-dlp (metadata, stream URLs, ...), so they never check for or install ffmpeg
+func ensureYTDLPInstalled() error {
+	return ensureBinaries(false)
+}
+
+// ensureBinaries installs yt-dlp, and ffmpeg if needFFMPEG is set, when missing.
+// Each binary is only checked once.
+func ensureBinaries(needFFMPEG bool) error {
+	// Only attempt installation once per binary
 	installMutex.Lock()
-	if installAttempted {
-		installMutex.Unlock()
-		return nil
+	checkYTDLP := !ytdlpInstallAttempted
+	checkFFMPEG := needFFMPEG && !ffmpegInstallAttempted
+	ytdlpInstallAttempted = true
+	if needFFMPEG {
+		ffmpegInstallAttempted = true
 	}
-	installAttempted = true
 	installMutex.Unlock()
 
+	if !checkYTDLP && !checkFFMPEG {
+		return nil
+	}
+	if BinarySource != BinarySourceAuto {
+		return ensureBinariesFromSource(checkYTDLP, checkFFMPEG)
+	}
+
 	// Check if auto-installation is disabled
 	if os.Getenv("GOSTREAMPULLER_NO_AUTO_INSTALL") == "1" {
 		return nil
 	}
 
 	// Check if binaries already exist and are executable
-	ytdlpExists := checkBinaryExists(YTDLPPath)
-	ffmpegExists := checkBinaryExists(FFMPEGPath)
+	// (a binary that doesn't need checking counts as present)
+	ytdlpExists := !checkYTDLP || checkBinaryExists(YTDLPPath)
+	ffmpegExists := !checkFFMPEG || checkBinaryExists(FFMPEGPath)
 
 	// If both exist, no installation needed
 	if ytdlpExists && ffmpegExists {
@@ -136,7 +172,7 @@ func ensureBinariesInstalled() error {
 
 	// Check if user already ran CLI setup but binaries are in PATH (not local)
 	// If binaries are found in system PATH, don't auto-install
-	if checkBinaryExists("yt-dlp") && checkBinaryExists("ffmpeg") {
+	if (ytdlpExists || checkBinaryExists("yt-dlp")) && (ffmpegExists || checkBinaryExists("ffmpeg")) {
 		// User has system binaries, respect that choice
 		return nil
 	}
@@ -293,8 +329,12 @@ func installFFMPEGAuto() error {
 	return installer.InstallFFMPEG(progressFn)
 }
 
-// updateYTDLPAuto updates yt-dlp automatically (non-blocking, called in background)
+// updateYTDLPAuto updates yt-dlp automatically (non-blocking, called in background).
+// Skipped when GOSTREAMPULLER_NO_AUTO_INSTALL=1 is set, as updating may reinstall it.
 func updateYTDLPAuto() error {
+	if os.Getenv("GOSTREAMPULLER_NO_AUTO_INSTALL") == "1" {
+		return nil
+	}
 	progressFn := func(msg string) {
 		if os.Getenv("GOSTREAMPULLER_VERBOSE") == "1" {
 			fmt.Fprintf(os.Stderr, "[gostreampuller]   %s\n", msg)
@@ -322,8 +362,25 @@ var (
 
 	// MaxConcurrentDownloads limits parallel download operations (default: 3)
 	MaxConcurrentDownloads = 3
+
+	// Retries is the number of times yt-dlp retries a failed download (default: 10)
+	Retries = 10
+
+	// FragmentRetries is the number of times yt-dlp retries a failed fragment (default: 10)
+	FragmentRetries = 10
+
+	// KeepFragments keeps the raw DASH/HLS fragments of each download next to
+	// the output for debugging (default: false). Can be set using SetKeepFragments()
+	KeepFragments = false
+
+	// YTDLPBufferSize is yt-dlp's download --buffer-size (default: 32K).
+	// Unrelated to ChunkSize, which sizes Go-side streaming buffers.
+	YTDLPBufferSize = "32K"
 )
 
+// bufferSizePattern matches yt-dlp buffer sizes: bytes or a K/M suffixed value
+var bufferSizePattern = regexp.MustCompile(`^[1-9]\d*[KkMm]?$`)
+
 // SetYTDLPPath sets a custom path for the yt-dlp binary.
 // This overrides the auto-detected local binary.
 // Use this if you want to use a specific yt-dlp installation.
@@ -369,6 +426,65 @@ func SetMaxConcurrentDownloads(max int) {
 	}
 }
 
+// SetYTDLPBufferSize sets the download buffer size yt-dlp uses, in yt-dlp's
+// format: a number of bytes with an optional K or M suffix (e.g. "64K", "1M").
+// Fast links can benefit from larger buffers.
+func SetYTDLPBufferSize(size string) error {
+	if !bufferSizePattern.MatchString(size) {
+		return fmt.Errorf("invalid yt-dlp buffer size %q (expected e.g. 1024, 32K or 1M)", size)
+	}
+	YTDLPBufferSize = size
+	return nil
+}
+
+// SetRetries sets how many times yt-dlp retries a failed download.
+// Use a higher value for flaky connections, 0 to fail fast.
+func SetRetries(retries int) error {
+	if retries < 0 {
+		return fmt.Errorf("retries must not be negative: %d", retries)
+	}
+	Retries = retries
+	return nil
+}
+
+// SetFragmentRetries sets how many times yt-dlp retries a failed fragment
+// of a DASH/HLS download
+func SetFragmentRetries(retries int) error {
+	if retries < 0 {
+		return fmt.Errorf("fragment retries must not be negative: %d", retries)
+	}
+	FragmentRetries = retries
+	return nil
+}
+
+// SetKeepFragments makes yt-dlp keep the downloaded fragments (--keep-fragments)
+// so DASH/HLS issues can be inspected. Fragments roughly double the disk space
+// a download needs and are never removed by the package, except by
+// CleanupTempFiles once they are older than TempFileMaxAge.
+func SetKeepFragments(keep bool) {
+	KeepFragments = keep
+}
+
+// retryOverrideArgs returns yt-dlp flags overriding the package-level retry
+// settings for one download. yt-dlp uses the last occurrence of a flag, so
+// these take precedence over the defaults in ytdlpDownloadCommand.
+func retryOverrideArgs(retries *int, fragmentRetries *int) ([]string, error) {
+	var args []string
+	if retries != nil {
+		if *retries < 0 {
+			return nil, fmt.Errorf("retries must not be negative: %d", *retries)
+		}
+		args = append(args, "--retries", strconv.Itoa(*retries))
+	}
+	if fragmentRetries != nil {
+		if *fragmentRetries < 0 {
+			return nil, fmt.Errorf("fragment retries must not be negative: %d", *fragmentRetries)
+		}
+		args = append(args, "--fragment-retries", strconv.Itoa(*fragmentRetries))
+	}
+	return args, nil
+}
+
 // DownloadProgress represents download progress information
 type DownloadProgress struct {
 	BytesDownloaded int64
@@ -420,25 +536,55 @@ type VideoMetadata struct {
 	ReleaseDate string `json:"release_date"`
 	Timestamp   int64  `json:"timestamp"`
 
+	// ReleaseTimestamp is the scheduled start of premieres and live streams
+	ReleaseTimestamp int64      `json:"release_timestamp"`
+	ScheduledAt      *time.Time `json:"scheduled_at,omitempty"` // Derived from ReleaseTimestamp
+
 	// Additional Info
-	Categories []string               `json:"categories"`
-	Tags       []string               `json:"tags"`
-	IsLive     bool                   `json:"is_live"`
-	WasLive    bool                   `json:"was_live"`
-	LiveStatus string                 `json:"live_status"`
-	Channel    string                 `json:"channel"`
-	ChannelID  string                 `json:"channel_id"`
-	ChannelURL string                 `json:"channel_url"`
-	Subtitles  map[string]interface{} `json:"subtitles"`
+	Categories   []string               `json:"categories"`
+	Tags         []string               `json:"tags"`
+	IsLive       bool                   `json:"is_live"`
+	WasLive      bool                   `json:"was_live"`
+	LiveStatus   string                 `json:"live_status"`  // not_live, is_live, is_upcoming, was_live, post_live
+	Availability string                 `json:"availability"` // public, unlisted, private, needs_auth, subscriber_only, premium_only
+	Channel      string                 `json:"channel"`
+	ChannelID    string                 `json:"channel_id"`
+	ChannelURL   string                 `json:"channel_url"`
+	Subtitles    map[string]interface{} `json:"subtitles"`
 
 	// Platform Specific
 	Extractor    string `json:"extractor"`
 	ExtractorKey string `json:"extractor_key"`
 
+	// Derived from Raw after fetching (see computeDerivedFields)
+	HasSubtitles            bool     `json:"has_subtitles"`
+	HasChapters             bool     `json:"has_chapters"`
+	ChapterCount            int      `json:"chapter_count"`
+	HasHeatmap              bool     `json:"has_heatmap"`
+	IsShort                 bool     `json:"is_short"`
+	IsUpcoming              bool     `json:"is_upcoming"`     // Premiere or live stream that has not started
+	IsMembersOnly           bool     `json:"is_members_only"` // Requires a channel membership or YouTube Premium
+	AvailableAudioLanguages []string `json:"available_audio_languages"`
+
+	// SecondsUntilStart counts down to an upcoming premiere or live stream,
+	// as of when the metadata was fetched (see TimeUntilStart for a live value)
+	SecondsUntilStart int64 `json:"seconds_until_start,omitempty"`
+
 	// Raw metadata for additional fields
 	Raw map[string]interface{} `json:"-"`
 }
 
+// playerClients are the YouTube player clients tried in order when extracting.
+// Expanded client list to handle more video types
+var playerClients = []string{
+	"android",          // Android app (most reliable)
+	"android_embedded", // Android embedded player
+	"android_music",    // Android Music app
+	"ios",              // iOS app
+	"tv_embedded",      // TV embedded player
+	"web",              // Web browser (fallback)
+}
+
 // GetVideoMetadata fetches comprehensive metadata for a video without downloading it
 // Returns detailed information about the video including title, duration, formats, quality, etc.
 //
@@ -459,8 +605,13 @@ func GetVideoMetadata(url string) (*VideoMetadata, error) {
 
 // GetVideoMetadataWithContext fetches video metadata with a custom context for timeout/cancellation
 func GetVideoMetadataWithContext(ctx context.Context, url string) (*VideoMetadata, error) {
-	// Auto-install binaries if needed (only happens once)
-	if err := ensureBinariesInstalled(); err != nil {
+	return getVideoMetadata(ctx, url, nil)
+}
+
+// getVideoMetadata fetches video metadata, streaming yt-dlp's verbose log to logCb if set
+func getVideoMetadata(ctx context.Context, url string, logCb MetadataLogCallback) (*VideoMetadata, error) {
+	// Auto-install yt-dlp if needed (only happens once); metadata doesn't need ffmpeg
+	if err := ensureYTDLPInstalled(); err != nil {
 		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
 	}
 
@@ -474,18 +625,9 @@ func GetVideoMetadataWithContext(ctx context.Context, url string) (*VideoMetadat
 	}()
 
 	// Try different approaches to get metadata, starting with the most reliable
-	// Expanded client list to handle more video types
-	clients := []string{
-		"android",           // Android app (most reliable)
-		"android_embedded",  // Android embedded player
-		"android_music",     // Android Music app
-		"ios",               // iOS app
-		"tv_embedded",       // TV embedded player
-		"web",               // Web browser (fallback)
-	}
 	var lastErr error
 
-	for _, client := range clients {
+	for _, client := range playerClients {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -494,11 +636,12 @@ func GetVideoMetadataWithContext(ctx context.Context, url string) (*VideoMetadat
 
 		// Use yt-dlp with --dump-json to get metadata without downloading
 		// Add comprehensive headers and options to bypass YouTube bot detection
-		cmd := exec.CommandContext(ctx, YTDLPPath,
+		cmd := ytdlpCommand(ctx,
 			"--dump-json",
 			"--no-playlist",
 			"--no-warnings",
-			"--extractor-args", fmt.Sprintf("youtube:player_client=%s", client),
+			"--ignore-no-formats-error", // Upcoming premieres have no formats yet
+			"--extractor-args", youtubeExtractorArgs(client),
 			"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
 			"--referer", "https://www.youtube.com/",
 			"--add-header", "Accept-Language:en-US,en;q=0.9",
@@ -513,19 +656,18 @@ func GetVideoMetadataWithContext(ctx context.Context, url string) (*VideoMetadat
 			"--add-header", "DNT:1",
 			"--sleep-interval", "1",
 			"--max-sleep-interval", "3",
-			"--no-check-certificate", // Sometimes helps with network issues
 			url,
 		)
 
-		output, err := cmd.Output()
+		output, errMsg, err := runMetadataCommand(cmd, logCb)
 		if err != nil {
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				errMsg := string(exitErr.Stderr)
+			if _, ok := err.(*exec.ExitError); ok {
 				// Check if it's a player response error - might need update
+				downloadErr := newDownloadError(cmd, "metadata", errMsg, err)
 				if strings.Contains(errMsg, "Failed to extract any player response") {
-					lastErr = fmt.Errorf("failed to fetch metadata with client %s: %s (yt-dlp may need update)", client, errMsg)
+					lastErr = fmt.Errorf("failed to fetch metadata with client %s: %w (yt-dlp may need update)", client, downloadErr)
 				} else {
-					lastErr = fmt.Errorf("failed to fetch metadata with client %s: %s", client, errMsg)
+					lastErr = fmt.Errorf("failed to fetch metadata with client %s: %w", client, downloadErr)
 				}
 				// Continue to next client if this one failed
 				continue
@@ -558,6 +700,7 @@ func GetVideoMetadataWithContext(ctx context.Context, url string) (*VideoMetadat
 		}
 
 		// Success! Return the metadata
+		metadata.computeDerivedFields()
 		return metadata, nil
 	}
 
@@ -567,18 +710,20 @@ func GetVideoMetadataWithContext(ctx context.Context, url string) (*VideoMetadat
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		cmd := exec.CommandContext(ctx, YTDLPPath,
+		args := []string{
 			"--dump-json",
 			"--no-playlist",
 			"--no-warnings",
+			"--ignore-no-formats-error", // Upcoming premieres have no formats yet
 			"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
 			"--referer", "https://www.youtube.com/",
 			"--sleep-interval", "1",
 			"--max-sleep-interval", "3",
-			url,
-		)
+		}
+		args = append(args, metadataLanguageArgs()...)
+		cmd := ytdlpCommand(ctx, append(args, url)...)
 
-		output, err := cmd.Output()
+		output, _, err := runMetadataCommand(cmd, logCb)
 		if err == nil && len(output) > 0 {
 			var rawMetadata map[string]interface{}
 			if err := json.Unmarshal(output, &rawMetadata); err == nil {
@@ -586,6 +731,7 @@ func GetVideoMetadataWithContext(ctx context.Context, url string) (*VideoMetadat
 					Raw: rawMetadata,
 				}
 				if err := json.Unmarshal(output, metadata); err == nil {
+					metadata.computeDerivedFields()
 					return metadata, nil
 				}
 			}
@@ -598,26 +744,82 @@ func GetVideoMetadataWithContext(ctx context.Context, url string) (*VideoMetadat
 	return nil, fmt.Errorf("failed to fetch metadata: all extraction methods failed")
 }
 
+// maxStderrLines is how many trailing stderr lines streamCommand keeps for error reporting
+const maxStderrLines = 50
+
+// commandOutput is what streamCommand learned from a command's output
+type commandOutput struct {
+	// SkipReason is set when yt-dlp skipped the video instead of downloading it
+	// (e.g. it did not pass --match-filter)
+	SkipReason string
+
+	// StopReason is set when yt-dlp stopped early on purpose (--max-downloads,
+	// --break-match-filters, --break-on-existing); the command then succeeds
+	StopReason string
+
+	// Retries counts the download and fragment retries yt-dlp reported
+	Retries int
+
+	// Warnings are the distinct non-fatal warnings yt-dlp reported
+	Warnings []string
+
+	// Proxy is the ProxyPool entry (credentials redacted) the download succeeded with
+	Proxy string
+
+	// AverageSpeed is the mean download speed (bytes/s) over SpeedSamples progress lines
+	AverageSpeed float64
+	SpeedSamples int
+}
+
+// maxWarnings caps how many warnings streamCommand collects
+const maxWarnings = 20
+
+// warningNoise are warnings callers can't act on; retries are counted instead
+var warningNoise = []string{
+	"Retrying",
+	"The download speed shown is only of one thread",
+	"Falling back to generic n function search",
+}
+
+// parseWarning returns the message of a yt-dlp "WARNING:" line worth
+// surfacing to callers, or ""
+//
+//	WARNING: [youtube] dQw4w9WgXcQ: Requested format is not available, falling back
+func parseWarning(line string) string {
+	message, ok := strings.CutPrefix(strings.TrimSpace(line), "WARNING:")
+	if !ok {
+		return ""
+	}
+	for _, noise := range warningNoise {
+		if strings.Contains(message, noise) {
+			return ""
+		}
+	}
+	return strings.TrimSpace(message)
+}
+
 // streamCommand executes a command and streams its output to handle large files
-func streamCommand(ctx context.Context, cmd *exec.Cmd, progressCb ProgressCallback, stage string) error {
+func streamCommand(ctx context.Context, cmd *exec.Cmd, progressCb ProgressCallback, stage string) (*commandOutput, error) {
 	var wg sync.WaitGroup
 	var errOut error
 	var mu sync.Mutex
+	result := &commandOutput{}
+	throttle := newProgressThrottle(progressCb, ProgressInterval)
 
 	// Create pipes for stdout and stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+		return result, fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		return result, fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
 	// Start the command
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start command: %w", err)
+		return result, fmt.Errorf("failed to start command: %w", err)
 	}
 
 	// Stream stdout in a goroutine
@@ -628,12 +830,36 @@ func streamCommand(ctx context.Context, cmd *exec.Cmd, progressCb ProgressCallba
 		scanner.Buffer(make([]byte, ChunkSize), ChunkSize)
 
 		for scanner.Scan() {
+			line := scanner.Text()
+			if reason := parseSkipReason(line); reason != "" {
+				result.SkipReason = reason
+			}
+			if reason := parseStopReason(line); reason != "" {
+				mu.Lock()
+				result.StopReason = reason
+				mu.Unlock()
+			}
+			if isRetryLine(line) {
+				mu.Lock()
+				result.Retries++
+				mu.Unlock()
+			}
+			if speed, ok := parseSpeed(line); ok {
+				result.SpeedSamples++
+				result.AverageSpeed += (speed - result.AverageSpeed) / float64(result.SpeedSamples)
+			}
+
 			// Parse progress from output if callback provided
 			if progressCb != nil {
-				line := scanner.Text()
 				// yt-dlp outputs progress information that can be parsed
-				if strings.Contains(line, "%") || strings.Contains(line, "ETA") {
-					progressCb(DownloadProgress{
+				if progress, ok := parseProgressLine(line, stage); ok {
+					throttle.emit(progress)
+				} else if wait, ok := parseWaitLine(line); ok {
+					throttle.emit(DownloadProgress{
+						Stage: fmt.Sprintf("Waiting for the video to start (%s left)", wait),
+					})
+				} else if strings.Contains(line, "%") || strings.Contains(line, "ETA") {
+					throttle.emit(DownloadProgress{
 						Stage: stage,
 					})
 				}
@@ -649,7 +875,8 @@ func streamCommand(ctx context.Context, cmd *exec.Cmd, progressCb ProgressCallba
 		}
 	}()
 
-	// Stream stderr in a goroutine
+	// Stream stderr in a goroutine, keeping the last lines to explain failures
+	var stderrTail []string
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -657,8 +884,28 @@ func streamCommand(ctx context.Context, cmd *exec.Cmd, progressCb ProgressCallba
 		scanner.Buffer(make([]byte, ChunkSize), ChunkSize)
 
 		for scanner.Scan() {
-			// Log errors but don't fail on warnings
-			_ = scanner.Text()
+			line := scanner.Text()
+			// yt-dlp reports retries as warnings on stderr
+			if isRetryLine(line) {
+				mu.Lock()
+				result.Retries++
+				mu.Unlock()
+			}
+			if reason := parseStopReason(line); reason != "" {
+				mu.Lock()
+				result.StopReason = reason
+				mu.Unlock()
+			}
+
+			// Keep warnings for the result but don't fail on them
+			if warning := parseWarning(line); warning != "" && len(result.Warnings) < maxWarnings &&
+				!slices.Contains(result.Warnings, warning) {
+				result.Warnings = append(result.Warnings, warning)
+			}
+			stderrTail = append(stderrTail, line)
+			if len(stderrTail) > maxStderrLines {
+				stderrTail = stderrTail[1:]
+			}
 		}
 
 		if err := scanner.Err(); err != nil && err != io.EOF {
@@ -675,42 +922,223 @@ func streamCommand(ctx context.Context, cmd *exec.Cmd, progressCb ProgressCallba
 
 	// Wait for command to finish
 	if err := cmd.Wait(); err != nil {
-		if errOut != nil {
-			return fmt.Errorf("command failed: %v, %w", err, errOut)
+		// Stopping early on purpose is not a failure; keep what was downloaded
+		if !isIntentionalStop(err, result.StopReason) {
+			return result, newDownloadError(cmd, stage, strings.Join(stderrTail, "\n"), err)
 		}
-		return fmt.Errorf("command failed: %w", err)
 	}
 
-	return errOut
+	return result, errOut
 }
 
-// copyFileStreaming copies a file using streaming to handle large files efficiently
-func copyFileStreaming(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
+// ytdlpStopExitCode is the exit code yt-dlp uses when it stops early on purpose
+const ytdlpStopExitCode = 101
+
+// isIntentionalStop reports whether err is yt-dlp exiting after stopReason.
+// Both are required: other programs may exit with 101 too.
+func isIntentionalStop(err error, stopReason string) bool {
+	var exitErr *exec.ExitError
+	return stopReason != "" && errors.As(err, &exitErr) && exitErr.ExitCode() == ytdlpStopExitCode
+}
+
+// parseStopReason returns the message of a yt-dlp line announcing an
+// intentional early stop, or ""
+//
+//	[info] Maximum number of downloads reached, stopping due to --max-downloads
+//	[download] Encountered a video that did not match filter, stopping due to --break-match-filter
+func parseStopReason(line string) string {
+	if !strings.Contains(line, "stopping due to --") {
+		return ""
 	}
-	defer sourceFile.Close()
+	message := strings.TrimSpace(line)
+	if strings.HasPrefix(message, "[") {
+		if end := strings.Index(message, "]"); end >= 0 {
+			message = strings.TrimSpace(message[end+1:])
+		}
+	}
+	return message
+}
 
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+// retryPattern matches yt-dlp retry reports such as
+//
+//	WARNING: [download] Got error: HTTP Error 503. Retrying (1/10)...
+//	WARNING: [download] Got error: timed out. Retrying fragment 12 (2/10)...
+var retryPattern = regexp.MustCompile(`Retrying (?:fragment \d+ )?\(\d+/\d+\)`)
+
+// isRetryLine reports whether a yt-dlp output line announces a retry
+func isRetryLine(line string) bool {
+	return strings.Contains(line, "Retrying") && retryPattern.MatchString(line)
+}
+
+// parseSkipReason returns the reason from a yt-dlp "skipping" line, or ""
+//
+//	[download] Some title does not pass filter (view_count > 1000), skipping ..
+//	[download] 2020-01-01 upload date is not in range 2024-01-01 - 9999-12-31
+func parseSkipReason(line string) string {
+	if strings.Contains(line, "upload date is not in range") {
+		return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "[download]"))
+	}
+
+	const marker = "does not pass filter"
+	idx := strings.Index(line, marker)
+	if idx < 0 {
+		return ""
 	}
-	defer destFile.Close()
+	reason := line[idx+len(marker):]
+	if end := strings.Index(reason, ", skipping"); end >= 0 {
+		reason = reason[:end]
+	}
+	return strings.Trim(reason, "() ")
+}
 
-	// Use buffered I/O for better performance with large files
-	buf := make([]byte, ChunkSize)
-	written, err := io.CopyBuffer(destFile, sourceFile, buf)
+// outputTemplate returns a unique yt-dlp output template ("<prefix>_<nanos>.%(ext)s")
+// inside outputDir, creating the directory (and cleaning stale temp files when
+// CleanupBeforeDownload is set) if needed.
+// If outputDir is empty, the template is relative to the current working directory.
+func outputTemplate(outputDir string, prefix string) (string, error) {
+	filename := fmt.Sprintf("%s_%d.%%(ext)s", prefix, time.Now().UnixNano())
+	if outputDir != "" {
+		if err := makeOutputDir(outputDir); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	if CleanupBeforeDownload {
+		if err := CleanupTempFiles(outputDir); err != nil {
+			// Not fatal - the download can still proceed
+			fmt.Fprintf(os.Stderr, "[gostreampuller] ⚠ Warning: %v\n", err)
+		}
+	}
+
+	if outputDir == "" {
+		return filename, nil
+	}
+	return filepath.Join(outputDir, filename), nil
+}
+
+// templateFiles returns the files in the template's directory whose names
+// match it, with %(ext)s standing for any text. The directory and name are
+// compared literally rather than as a glob, since output directories and
+// titles may contain '[', '*' or '?'.
+func templateFiles(temp string) []string {
+	dir, base := filepath.Split(temp)
+	prefix, suffix, _ := strings.Cut(base, "%(ext)s")
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
 	if err != nil {
-		return fmt.Errorf("failed to copy file: %w", err)
+		return nil
+	}
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if len(name) < len(prefix)+len(suffix) || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		matches = append(matches, filepath.Join(dir, name))
 	}
+	return matches
+}
+
+// filenameReplacer strips characters that are invalid in filenames, plus '%'
+// which yt-dlp would read as an output template field
+var filenameReplacer = strings.NewReplacer(
+	"/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", "\"", "_",
+	"<", "_", ">", "_", "|", "_", "%", "_", "\n", " ", "\r", " ",
+)
 
-	if err := destFile.Sync(); err != nil {
-		return fmt.Errorf("failed to sync file: %w", err)
+// maxFilenameLength caps ExpectedFilename's base name, in bytes
+const maxFilenameLength = 100
+
+// ExpectedFilename returns the sanitized file name (with extension) a download
+// of the video in format should be saved or served as, based on its title.
+// Falls back to the video ID, or "video", if the title has no usable characters.
+// An empty format defaults to mp4.
+func ExpectedFilename(metadata *VideoMetadata, format string) string {
+	if format == "" {
+		format = "mp4"
 	}
 
-	_ = written
-	return nil
+	var name string
+	if metadata != nil {
+		name = sanitizedName(metadata.Title)
+		if name == "" {
+			name = sanitizedName(metadata.ID)
+		}
+	}
+	if name == "" {
+		name = "video"
+	}
+	return name + "." + format
+}
+
+// sanitizedName replaces invalid filename characters in name and truncates it
+// to maxFilenameLength bytes without splitting a UTF-8 character
+func sanitizedName(name string) string {
+	name = strings.TrimSpace(filenameReplacer.Replace(name))
+	if len(name) > maxFilenameLength {
+		cut := maxFilenameLength
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = strings.TrimSpace(name[:cut])
+	}
+	// Names like "." or ".." are not usable as files
+	if strings.Trim(name, ".") == "" {
+		return ""
+	}
+	return name
+}
+
+// namedTemplate replaces the generated name of an output template with name
+func namedTemplate(temp string, name string) string {
+	name = strings.TrimSpace(filenameReplacer.Replace(name))
+	if name == "" {
+		return temp
+	}
+	return filepath.Join(filepath.Dir(temp), name+".%(ext)s")
+}
+
+// ytdlpDownloadCommand builds the yt-dlp command shared by all media downloads.
+// extra arguments are added before the URL.
+func ytdlpDownloadCommand(ctx context.Context, selector string, temp string, url string, extra ...string) *exec.Cmd {
+	// Use yt-dlp with options optimized for large files
+	// Add headers to bypass YouTube bot detection
+	args := []string{
+		"-f", selector,
+		"-o", temp,
+		"--newline",                                                 // One progress update per line so it can be parsed
+		"--no-part",                                                 // Don't use .part files for large downloads
+		"--concurrent-fragments", strconv.Itoa(ConcurrentFragments), // Download fragments concurrently
+		"--buffer-size", YTDLPBufferSize, // Set buffer size
+		"--retries", strconv.Itoa(Retries), // Retry on failure
+		"--fragment-retries", strconv.Itoa(FragmentRetries), // Retry fragments
+		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"--referer", "https://www.youtube.com/",
+		"--add-header", "Accept-Language:en-US,en;q=0.9",
+		"--add-header", "Accept:text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+	}
+	args = append(args, externalDownloaderArgs()...)
+	args = append(args, rateLimitArgs()...)
+	if KeepFragments {
+		args = append(args, "--keep-fragments")
+	}
+	args = append(args, extra...)
+	args = append(args, url)
+
+	return ytdlpCommand(ctx, args...)
+}
+
+// findDownloadedFile returns the file yt-dlp produced for an output template
+// by trying each extension in order. If none exists (an unusual container),
+// it falls back to the newest file matching the template that ffprobe
+// confirms is media. Returns "" if nothing media-like is found.
+func findDownloadedFile(temp string, extensions []string) string {
+	for _, ext := range extensions {
+		candidate := strings.Replace(temp, "%(ext)s", ext, 1)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return findMediaByTemplate(temp)
 }
 
 // DownloadVideo downloads a video, allowing optional format, resolution, and codec parameters.
@@ -737,118 +1165,316 @@ func DownloadVideoWithProgress(url string, format string, resolution string, cod
 // DownloadVideoToDirWithProgress downloads a video to a specific directory with progress callback support.
 // If outputDir is empty, files are saved to the current working directory.
 func DownloadVideoToDirWithProgress(url string, format string, resolution string, codec string, outputDir string, progressCb ProgressCallback) (string, error) {
+	result, err := DownloadVideoWithOptions(context.Background(), url, VideoOptions{
+		Format:     format,
+		Resolution: resolution,
+		Codec:      codec,
+		OutputDir:  outputDir,
+		Progress:   progressCb,
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Path, nil
+}
+
+// VideoOptions configures DownloadVideoWithOptions.
+// Empty fields use the same defaults as DownloadVideo.
+type VideoOptions struct {
+	Format     string           // Output container (default: mp4)
+	Resolution string           // Maximum height (default: 720)
+	Codec      string           // Preferred video codec (default: avc1)
+	OutputDir  string           // Output directory (default: current working directory)
+	Progress   ProgressCallback // Optional progress callback
+	Quality    QualityPreset    // Quality/bandwidth trade-off (default: QualityBalanced)
+
+	// PreferHDR picks HDR (10-bit) video when available, falling back to SDR.
+	// HDR streams are VP9 or AV1, so Codec only applies to the SDR fallback.
+	PreferHDR bool
+
+	// PreferProgressive picks a single pre-muxed file in Format (e.g. YouTube's
+	// format 18) when one exists, skipping the video+audio merge and ffmpeg
+	// entirely, and only falls back to merging separate streams otherwise.
+	// YouTube rarely offers pre-muxed files above 360p.
+	PreferProgressive bool
+
+	// VideoOnly downloads just the video track (no audio); AudioOnly downloads
+	// just the audio track, still saved in Format. At most one may be set.
+	VideoOnly bool
+	AudioOnly bool
+
+	// MatchFilter is passed to yt-dlp's --match-filter; videos that don't match
+	// are skipped and the download returns ErrSkippedByFilter
+	MatchFilter string
+
+	// DateAfter and DateBefore (YYYYMMDD) skip videos uploaded outside the range;
+	// the download then returns ErrSkippedByFilter
+	DateAfter  string
+	DateBefore string
+
+	// Filename is the output file name without extension; invalid characters
+	// are replaced. Default: a unique generated name
+	Filename string
+
+	// Storage receives the final file; if nil the file stays in OutputDir
+	Storage Storage
+
+	// Retries and FragmentRetries override the package-level Retries and
+	// FragmentRetries for this download when non-nil
+	Retries         *int
+	FragmentRetries *int
+
+	// EmbedSourceMetadata records where the file came from (see SourceMetadataFields)
+	// in the file's metadata, along with the title, uploader and date
+	EmbedSourceMetadata bool
+
+	// EmbedInfoJSON attaches yt-dlp's info json to the file, keeping the full
+	// metadata with it for archival. Requires Format mkv.
+	EmbedInfoJSON bool
+
+	// EmbedSubtitles muxes the subtitles in SubtitleLanguages (default: en)
+	// into the file as selectable soft subtitle tracks, rather than burning
+	// them into the picture. SubtitleAutoGenerated also embeds auto-generated
+	// captions for languages without uploaded subtitles. Languages the video
+	// has no subtitles in are skipped. Requires ffmpeg and Format mp4, mkv
+	// or webm; not available with AudioOnly.
+	EmbedSubtitles        bool
+	SubtitleLanguages     []string
+	SubtitleAutoGenerated bool
+
+	// PosterThumbnail downloads the video thumbnail and attaches it as the
+	// file's poster (cover art), so file managers show the right preview.
+	// Requires ffmpeg and Format mp4, m4v or mov; not available with AudioOnly.
+	PosterThumbnail bool
+
+	// CheckFreeSpace estimates the download's size from its metadata before
+	// downloading and fails with ErrInsufficientDiskSpace if twice that (room
+	// for a conversion) is not free, instead of filling the disk part way.
+	// Costs one extra metadata request.
+	CheckFreeSpace bool
+
+	// RecodeVideo re-encodes the video to codecs the Format container supports
+	// instead of remuxing it with -c copy. Only applies when the downloaded
+	// container differs from Format. Re-encoding is CPU bound and typically takes
+	// as long as (or longer than) the video itself on a single core.
+	// Without it, conversions still re-encode when Format can't hold the
+	// downloaded codecs (e.g. VP9/Opus into mp4).
+	RecodeVideo bool
+
+	// WaitForVideo makes upcoming premieres and live streams wait until they
+	// start instead of failing with ErrNotYetAvailable, re-checking every
+	// WaitForVideo (or at random up to WaitForVideoMax, if set). The wait
+	// counts against Timeout, so raise it for premieres far in the future;
+	// VideoMetadata.TimeUntilStart estimates the wait.
+	WaitForVideo    time.Duration
+	WaitForVideoMax time.Duration
+
+	// Timeout bounds the download and conversion together (default: the
+	// context's deadline, or 50 minutes if it has none)
+	Timeout time.Duration
+}
+
+// DownloadResult describes a finished download
+type DownloadResult struct {
+	Path    string // Absolute path of the final file, or its Storage location
+	Retries int    // Download and fragment retries yt-dlp needed, for diagnostics
+
+	// Warnings are non-fatal yt-dlp warnings, e.g. that the requested format
+	// was not available and another was used
+	Warnings []string
+
+	// Proxy is the ProxyPool entry (credentials redacted) the download used, if any
+	Proxy string
+}
+
+// DownloadVideoWithOptions downloads a video using the given options.
+// The context bounds the whole download; cancel it to abort.
+func DownloadVideoWithOptions(ctx context.Context, url string, opts VideoOptions) (*DownloadResult, error) {
 	// Auto-install binaries if needed (only happens once)
 	if err := ensureBinariesInstalled(); err != nil {
-		return "", fmt.Errorf("failed to ensure binaries are installed: %w", err)
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
 	}
 
+	format := opts.Format
 	if format == "" {
 		format = "mp4"
 	}
-	if resolution == "" {
-		resolution = "720"
+	selector, err := BuildFormatSelector(opts)
+	if err != nil {
+		return nil, err
 	}
-	if codec == "" {
-		codec = "avc1"
+	if opts.EmbedInfoJSON {
+		if err := validateInfoJSONFormat(format); err != nil {
+			return nil, err
+		}
 	}
-
-	// Use custom output directory if provided
-	if outputDir != "" {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create output directory: %w", err)
+	if opts.EmbedSubtitles {
+		if opts.AudioOnly {
+			return nil, fmt.Errorf("EmbedSubtitles needs a video track; use DownloadSubtitles for audio")
+		}
+		if err := validateEmbedSubtitlesFormat(format); err != nil {
+			return nil, err
 		}
 	}
+	if opts.PosterThumbnail {
+		if opts.AudioOnly {
+			return nil, fmt.Errorf("PosterThumbnail needs a video track; use DownloadAudio's EmbedCoverArt for audio")
+		}
+		if err := validatePosterFormat(format); err != nil {
+			return nil, err
+		}
+	}
+	// Without ffmpeg, fall back to a single file that needs no merge or conversion
+	noFFMPEG := !ffmpegAvailable()
+	if noFFMPEG {
+		if selector, err = videoSelectorWithoutFFMPEG(opts, format); err != nil {
+			return nil, err
+		}
+	} else if opts.RecodeVideo {
+		for _, encoder := range recodeCodecs[strings.ToLower(format)] {
+			if err := requireEncoder(encoder); err != nil {
+				return nil, err
+			}
+		}
+	}
+	progressCb, untrack := trackDownload(url, opts.Progress)
+	defer untrack()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	defer cancel()
-
-	filename := fmt.Sprintf("video_%d.%%(ext)s", time.Now().UnixNano())
-	var temp string
-	if outputDir != "" {
-		temp = filepath.Join(outputDir, filename)
-	} else {
-		temp = filename
+	release, err := acquireHostSlot(ctx, url)
+	if err != nil {
+		return nil, err
 	}
-	selector := fmt.Sprintf("bestvideo[height<=%s][vcodec*=%s]+bestaudio/best", resolution, codec)
+	defer release()
 
-	// Use yt-dlp with options optimized for large files
-	// Add headers to bypass YouTube bot detection
-	cmd := exec.CommandContext(ctx, YTDLPPath,
-		"-f", selector,
-		"-o", temp,
-		"--no-part",                   // Don't use .part files for large downloads
-		"--concurrent-fragments", "3", // Download fragments concurrently
-		"--buffer-size", "32K", // Set buffer size
-		"--retries", "10", // Retry on failure
-		"--fragment-retries", "10", // Retry fragments
-		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-		"--referer", "https://www.youtube.com/",
-		"--add-header", "Accept-Language:en-US,en;q=0.9",
-		"--add-header", "Accept:text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
-		url,
-	)
+	parent := ctx
+	ctx, cancel := withDownloadDeadline(ctx, opts.Timeout)
+	defer cancel()
 
+	temp, err := outputTemplate(workDir(opts.OutputDir), "video")
+	if err != nil {
+		return nil, err
+	}
+	if opts.CheckFreeSpace {
+		if err := checkDownloadSpace(ctx, url, filepath.Dir(temp)); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Filename != "" {
+		temp = namedTemplate(temp, opts.Filename)
+	}
+	extra, err := retryOverrideArgs(opts.Retries, opts.FragmentRetries)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MatchFilter != "" {
+		extra = append(extra, "--match-filter", opts.MatchFilter)
+	}
+	dateArgs, err := dateRangeArgs(opts.DateAfter, opts.DateBefore)
+	if err != nil {
+		return nil, err
+	}
+	extra = append(extra, dateArgs...)
+	if opts.EmbedSourceMetadata {
+		extra = append(extra, sourceMetadataArgs()...)
+	}
+	if opts.EmbedInfoJSON {
+		extra = append(extra, infoJSONArgs()...)
+	}
+	if opts.EmbedSubtitles {
+		extra = append(extra, embedSubtitlesArgs(format, opts.SubtitleLanguages, opts.SubtitleAutoGenerated)...)
+	}
+	if opts.PosterThumbnail {
+		extra = append(extra, coverArtArgs()...)
+	}
+	waitArgs, err := waitForVideoArgs(opts.WaitForVideo, opts.WaitForVideoMax)
+	if err != nil {
+		return nil, err
+	}
+	extra = append(extra, waitArgs...)
 	if progressCb != nil {
 		progressCb(DownloadProgress{Stage: "Downloading video"})
 	}
 
-	if err := streamCommand(ctx, cmd, progressCb, "downloading"); err != nil {
-		return "", fmt.Errorf("yt-dlp video download failed: %w", err)
+	existing := templateFiles(temp)
+	output, err := runDownload(ctx, selector, temp, url, progressCb, extra...)
+	if err != nil {
+		// yt-dlp writes straight to the final name (--no-part); don't leave a
+		// truncated file behind that looks complete
+		removeNewTemplateFiles(temp, existing)
+		return nil, fmt.Errorf("yt-dlp video download failed: %w", notYetAvailable(parent, url, err))
 	}
 
 	// Find the actual downloaded file by checking common extensions
-	var downloaded string
-	possibleExtensions := []string{"mkv", "mp4", "webm", "avi", "mov", "flv"}
-
-	for _, ext := range possibleExtensions {
-		candidate := strings.Replace(temp, "%(ext)s", ext, 1)
-		if _, err := os.Stat(candidate); err == nil {
-			downloaded = candidate
-			break
-		}
-	}
-
+	downloaded := findDownloadedFile(temp, downloadExtensions(opts.AudioOnly, format))
 	if downloaded == "" {
-		return "", fmt.Errorf("could not find downloaded video file")
+		if output.SkipReason != "" {
+			return nil, &SkippedError{Reason: output.SkipReason}
+		}
+		if output.StopReason != "" {
+			return nil, &SkippedError{Reason: output.StopReason}
+		}
+		return nil, fmt.Errorf("could not find downloaded video file")
 	}
 
 	// If format is different from downloaded format, convert it
 	finalOutput := strings.Replace(temp, "%(ext)s", format, 1)
+	if downloaded != finalOutput && noFFMPEG {
+		os.Remove(downloaded)
+		return nil, fmt.Errorf("%w: converting %s to %s", ErrFFMPEGRequired, filepath.Ext(downloaded), format)
+	}
 	if downloaded != finalOutput {
 		if progressCb != nil {
 			progressCb(DownloadProgress{Stage: "Converting video format"})
 		}
 
-		convertCtx, convertCancel := context.WithTimeout(context.Background(), 20*time.Minute)
-		defer convertCancel()
-
-		// Use streaming copy for format conversion to handle large files
-		ffmpeg := exec.CommandContext(convertCtx, FFMPEGPath,
-			"-i", downloaded,
-			"-c", "copy",
-			"-movflags", "+faststart", // Optimize for streaming
-			"-max_muxing_queue_size", "1024", // Handle large files
-			"-y",
-			finalOutput,
-		)
-
-		if err := streamCommand(convertCtx, ffmpeg, progressCb, "converting"); err != nil {
-			return "", fmt.Errorf("ffmpeg conversion failed: %w", err)
+		// Use streaming copy for format conversion to handle large files, re-encoding
+		// if requested or if the container can't hold the downloaded codecs
+		recode := opts.RecodeVideo || needsRecode(ctx, downloaded, format)
+		err := convertToFile(ctx, finalOutput, progressCb, func(dst string) []string {
+			return videoConvertArgs(downloaded, dst, format, recode)
+		})
+		if err != nil {
+			if errors.Is(err, ErrDiskFull) {
+				removeTemplateFiles(temp)
+			}
+			return nil, fmt.Errorf("ffmpeg conversion failed: %w", err)
 		}
 		defer os.Remove(downloaded)
 
-		if progressCb != nil {
-			progressCb(DownloadProgress{Stage: "Completed", Percentage: 100.0})
+		downloaded = finalOutput
+	}
+
+	if opts.PosterThumbnail {
+		cover, err := findCoverArt(ctx, temp)
+		if err != nil {
+			return nil, err
+		}
+		if cover == "" {
+			output.Warnings = append(output.Warnings, "no thumbnail available, the video has no poster")
+		} else {
+			defer os.Remove(cover)
+			if progressCb != nil {
+				progressCb(DownloadProgress{Stage: "Attaching poster"})
+			}
+			if err := attachPoster(ctx, downloaded, cover, progressCb); err != nil {
+				if errors.Is(err, ErrDiskFull) {
+					removeTemplateFiles(temp)
+				}
+				return nil, fmt.Errorf("ffmpeg poster attachment failed: %w", err)
+			}
 		}
+	}
 
-		return filepath.Abs(finalOutput)
+	path, err := finishDownload(parent, downloaded, opts.OutputDir, opts.Storage, progressCb)
+	if err != nil {
+		return nil, err
 	}
 
 	if progressCb != nil {
 		progressCb(DownloadProgress{Stage: "Completed", Percentage: 100.0})
 	}
 
-	return filepath.Abs(downloaded)
+	return &DownloadResult{Path: path, Retries: output.Retries, Warnings: output.Warnings, Proxy: output.Proxy}, nil
 }
 
 // DownloadAudio downloads audio, allowing optional output format, codec, and bitrate parameters.
@@ -875,109 +1501,309 @@ func DownloadAudioWithProgress(url string, outputFormat string, codec string, bi
 // DownloadAudioToDirWithProgress downloads audio to a specific directory with progress callback support.
 // If outputDir is empty, files are saved to the current working directory.
 func DownloadAudioToDirWithProgress(url string, outputFormat string, codec string, bitrate string, outputDir string, progressCb ProgressCallback) (string, error) {
+	result, err := DownloadAudioWithOptions(context.Background(), url, AudioOptions{
+		Format:    outputFormat,
+		Codec:     codec,
+		Bitrate:   bitrate,
+		OutputDir: outputDir,
+		Progress:  progressCb,
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Path, nil
+}
+
+// AudioOptions configures DownloadAudioWithOptions.
+// Empty fields use the same defaults as DownloadAudio.
+type AudioOptions struct {
+	Format    string           // Output format (default: mp3)
+	Codec     string           // ffmpeg audio codec (default: based on Format, e.g. mp3→libmp3lame, m4a→aac); "copy" keeps the source stream
+	Bitrate   string           // Audio bitrate (default: 128k)
+	OutputDir string           // Output directory (default: current working directory)
+	Progress  ProgressCallback // Optional progress callback
+	Quality   QualityPreset    // QualityDataSaver picks the smallest audio stream
+
+	// Storage receives the final file; if nil the file stays in OutputDir
+	Storage Storage
+
+	// Retries and FragmentRetries override the package-level Retries and
+	// FragmentRetries for this download when non-nil
+	Retries         *int
+	FragmentRetries *int
+
+	// Filename is the output file name without extension; invalid characters
+	// are replaced. Default: a unique generated name
+	Filename string
+
+	// SampleRate (Hz) and Channels resample and remix the audio, e.g. 16000
+	// and 1 for speech recognition. Zero keeps the source's values.
+	SampleRate int
+	Channels   int
+
+	// TrimSilence removes leading and trailing silence, e.g. for podcasts and
+	// voice recordings. Audio quieter than SilenceThreshold dB (default -50)
+	// for at least SilenceDuration (default 500ms) counts as silence; pauses
+	// in the middle are kept. Requires ffmpeg and re-encoding, so Codec can't
+	// be "copy". The whole track is buffered in memory while trimming the end.
+	TrimSilence      bool
+	SilenceThreshold float64
+	SilenceDuration  time.Duration
+
+	// EmbedCoverArt downloads the video thumbnail and embeds it as album art.
+	// Requires a format that supports cover art (mp3, m4a, flac).
+	EmbedCoverArt bool
+
+	// Tags sets metadata tags on the file, e.g. {"title": ..., "artist": ...,
+	// "album": ...}, using ffmpeg's tag names. They are merged with the
+	// metadata of the source; where both set a tag, Tags wins.
+	Tags map[string]string
+
+	// EmbedSourceMetadata records where the file came from (see SourceMetadataFields)
+	// in the file's metadata, along with the title, uploader and date
+	EmbedSourceMetadata bool
+
+	// WaitForVideo makes upcoming premieres and live streams wait until they
+	// start instead of failing with ErrNotYetAvailable, re-checking every
+	// WaitForVideo (or at random up to WaitForVideoMax, if set). The wait
+	// counts against Timeout, so raise it for premieres far in the future;
+	// VideoMetadata.TimeUntilStart estimates the wait.
+	WaitForVideo    time.Duration
+	WaitForVideoMax time.Duration
+
+	// Timeout bounds the download and conversion together (default: the
+	// context's deadline, or 50 minutes if it has none)
+	Timeout time.Duration
+}
+
+// audioConversion describes the ffmpeg step of an audio download
+type audioConversion struct {
+	Input   string
+	Output  string
+	Format  string
+	Codec   string
+	Bitrate string // Empty for lossless codecs
+	Cover   string // Optional jpg to embed as album art
+
+	Tags map[string]string // Metadata tags overriding the source's
+
+	SampleRate int    // Hz; 0 keeps the source's rate
+	Channels   int    // 0 keeps the source's channels
+	Filter     string // Optional ffmpeg audio filter chain (-af)
+}
+
+// audioConvertArgs builds the ffmpeg arguments for converting downloaded audio
+func audioConvertArgs(c audioConversion) []string {
+	args := []string{"-i", c.Input}
+	if c.Cover != "" {
+		args = append(args,
+			"-i", c.Cover,
+			"-map", "0:a",
+			"-map", "1:v",
+			"-c:v", "mjpeg",
+			"-disposition:v:0", "attached_pic",
+		)
+		if strings.EqualFold(c.Format, "mp3") {
+			// ID3v2.3 is the version most players read cover art from
+			args = append(args, "-id3v2_version", "3")
+		}
+	} else {
+		args = append(args, "-vn")
+	}
+	args = append(args, tagArgs(c.Tags)...)
+
+	args = append(args, "-acodec", c.Codec)
+	if c.Bitrate != "" {
+		args = append(args, "-ab", c.Bitrate)
+	}
+	if c.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(c.SampleRate))
+	}
+	if c.Channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(c.Channels))
+	}
+	if c.Filter != "" {
+		args = append(args, "-af", c.Filter)
+	}
+
+	return append(args,
+		"-max_muxing_queue_size", "1024", // Handle large files
+		"-y",
+		c.Output,
+	)
+}
+
+// DownloadAudioWithOptions downloads audio using the given options.
+// The context bounds the whole download; cancel it to abort.
+func DownloadAudioWithOptions(ctx context.Context, url string, opts AudioOptions) (*DownloadResult, error) {
 	// Auto-install binaries if needed (only happens once)
 	if err := ensureBinariesInstalled(); err != nil {
-		return "", fmt.Errorf("failed to ensure binaries are installed: %w", err)
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
 	}
 
+	outputFormat := opts.Format
 	if outputFormat == "" {
 		outputFormat = "mp3"
 	}
-	if codec == "" {
-		codec = "libmp3lame"
+	codec, err := resolveAudioCodec(outputFormat, opts.Codec)
+	if err != nil {
+		return nil, err
 	}
-	if bitrate == "" {
-		bitrate = "128k"
+	// Without ffmpeg, audio can still be downloaded as-is in a native format
+	noFFMPEG := !ffmpegAvailable()
+	var directSelector string
+	if noFFMPEG {
+		if directSelector, err = audioSelectorWithoutFFMPEG(opts, outputFormat); err != nil {
+			return nil, err
+		}
+	} else if err := requireEncoder(codec); err != nil {
+		return nil, err
 	}
-
-	// Use custom output directory if provided
-	if outputDir != "" {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return "", fmt.Errorf("failed to create output directory: %w", err)
+	bitrate, err := resolveAudioBitrate(codec, opts.Bitrate)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAudioLayout(codec, opts.SampleRate, opts.Channels); err != nil {
+		return nil, err
+	}
+	var filter string
+	if opts.TrimSilence {
+		if codec == "copy" {
+			return nil, fmt.Errorf("TrimSilence requires re-encoding and cannot be used with codec copy")
+		}
+		if filter, err = silenceTrimFilter(opts.SilenceThreshold, opts.SilenceDuration); err != nil {
+			return nil, err
 		}
 	}
+	if opts.EmbedCoverArt {
+		if err := validateCoverArtFormat(outputFormat); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateTags(opts.Tags); err != nil {
+		return nil, err
+	}
+	progressCb, untrack := trackDownload(url, opts.Progress)
+	defer untrack()
+	selector, err := audioSelector(opts.Quality)
+	if err != nil {
+		return nil, err
+	}
+	if noFFMPEG {
+		selector = directSelector
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	defer cancel()
-
-	filename := fmt.Sprintf("audio_%d.%%(ext)s", time.Now().UnixNano())
-	var temp string
-	if outputDir != "" {
-		temp = filepath.Join(outputDir, filename)
-	} else {
-		temp = filename
+	release, err := acquireHostSlot(ctx, url)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	// Use yt-dlp with options optimized for large files
-	// Add headers to bypass YouTube bot detection
-	cmd := exec.CommandContext(ctx, YTDLPPath,
-		"-f", "bestaudio",
-		"-o", temp,
-		"--no-part",                   // Don't use .part files
-		"--concurrent-fragments", "3", // Download fragments concurrently
-		"--buffer-size", "32K", // Set buffer size
-		"--retries", "10", // Retry on failure
-		"--fragment-retries", "10", // Retry fragments
-		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-		"--referer", "https://www.youtube.com/",
-		"--add-header", "Accept-Language:en-US,en;q=0.9",
-		"--add-header", "Accept:text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
-		url,
-	)
+	parent := ctx
+	ctx, cancel := withDownloadDeadline(ctx, opts.Timeout)
+	defer cancel()
 
+	temp, err := outputTemplate(workDir(opts.OutputDir), "audio")
+	if err != nil {
+		return nil, err
+	}
+	if opts.Filename != "" {
+		temp = namedTemplate(temp, opts.Filename)
+	}
+	extra, err := retryOverrideArgs(opts.Retries, opts.FragmentRetries)
+	if err != nil {
+		return nil, err
+	}
+	if opts.EmbedCoverArt {
+		extra = append(extra, coverArtArgs()...)
+	}
+	waitArgs, err := waitForVideoArgs(opts.WaitForVideo, opts.WaitForVideoMax)
+	if err != nil {
+		return nil, err
+	}
+	extra = append(extra, waitArgs...)
+	if opts.EmbedSourceMetadata {
+		extra = append(extra, sourceMetadataArgs()...)
+	}
 	if progressCb != nil {
 		progressCb(DownloadProgress{Stage: "Downloading audio"})
 	}
 
-	if err := streamCommand(ctx, cmd, progressCb, "downloading"); err != nil {
-		return "", fmt.Errorf("yt-dlp audio fetch failed: %w", err)
+	existing := templateFiles(temp)
+	fetched, err := runDownload(ctx, selector, temp, url, progressCb, extra...)
+	if err != nil {
+		// yt-dlp writes straight to the final name (--no-part); don't leave a
+		// truncated file behind that looks complete
+		removeNewTemplateFiles(temp, existing)
+		return nil, fmt.Errorf("yt-dlp audio fetch failed: %w", notYetAvailable(parent, url, err))
 	}
 
 	// Find the downloaded file (could be webm, m4a, opus, etc.)
-	possibleExtensions := []string{"webm", "m4a", "opus", "ogg", "mp3", "aac"}
-	var original string
-
-	for _, ext := range possibleExtensions {
-		candidate := strings.Replace(temp, "%(ext)s", ext, 1)
-		if _, err := os.Stat(candidate); err == nil {
-			original = candidate
-			break
-		}
-	}
-
+	original := findDownloadedFile(temp, downloadExtensions(true, ""))
 	if original == "" {
-		return "", fmt.Errorf("could not find downloaded audio file")
+		return nil, fmt.Errorf("could not find downloaded audio file")
 	}
 
 	output := strings.Replace(temp, "%(ext)s", outputFormat, 1)
+	if noFFMPEG {
+		// The selector only matched files already in outputFormat
+		return finishAudioDownload(parent, original, opts, progressCb, fetched)
+	}
 
 	if progressCb != nil {
 		progressCb(DownloadProgress{Stage: "Converting audio format"})
 	}
 
-	convertCtx, convertCancel := context.WithTimeout(context.Background(), 20*time.Minute)
-	defer convertCancel()
+	conversion := audioConversion{
+		Input:   original,
+		Output:  output,
+		Format:  outputFormat,
+		Codec:   codec,
+		Bitrate: bitrate,
+		Tags:    opts.Tags,
+
+		SampleRate: opts.SampleRate,
+		Channels:   opts.Channels,
+		Filter:     filter,
+	}
+	if opts.EmbedCoverArt {
+		cover, err := findCoverArt(ctx, temp)
+		if err != nil {
+			return nil, err
+		}
+		if cover != "" {
+			defer os.Remove(cover)
+			conversion.Cover = cover
+		}
+	}
 
 	// Use streaming conversion for large audio files
-	ffmpeg := exec.CommandContext(convertCtx, FFMPEGPath,
-		"-i", original,
-		"-vn",
-		"-acodec", codec,
-		"-ab", bitrate,
-		"-max_muxing_queue_size", "1024", // Handle large files
-		"-y",
-		output,
-	)
-
-	if err := streamCommand(convertCtx, ffmpeg, progressCb, "converting"); err != nil {
-		return "", fmt.Errorf("ffmpeg conversion failed: %w", err)
+	err = convertToFile(ctx, output, progressCb, func(dst string) []string {
+		conversion.Output = dst
+		return audioConvertArgs(conversion)
+	})
+	if err != nil {
+		if errors.Is(err, ErrDiskFull) {
+			removeTemplateFiles(temp)
+		}
+		return nil, fmt.Errorf("ffmpeg conversion failed: %w", err)
 	}
 
 	defer os.Remove(original)
 
+	return finishAudioDownload(parent, output, opts, progressCb, fetched)
+}
+
+// finishAudioDownload stores the final audio file and reports completion
+func finishAudioDownload(ctx context.Context, output string, opts AudioOptions, progressCb ProgressCallback, fetched *commandOutput) (*DownloadResult, error) {
+	path, err := finishDownload(ctx, output, opts.OutputDir, opts.Storage, progressCb)
+	if err != nil {
+		return nil, err
+	}
+
 	if progressCb != nil {
 		progressCb(DownloadProgress{Stage: "Completed", Percentage: 100.0})
 	}
 
-	return filepath.Abs(output)
+	return &DownloadResult{Path: path, Retries: fetched.Retries, Warnings: fetched.Warnings, Proxy: fetched.Proxy}, nil
 }
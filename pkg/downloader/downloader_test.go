@@ -0,0 +1,319 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSetRetries(t *testing.T) {
+	setForTest(t, &Retries, 10)
+	setForTest(t, &FragmentRetries, 10)
+
+	if err := SetRetries(-1); err == nil {
+		t.Error("SetRetries(-1) succeeded")
+	}
+	if err := SetFragmentRetries(-1); err == nil {
+		t.Error("SetFragmentRetries(-1) succeeded")
+	}
+	if err := SetRetries(0); err != nil || Retries != 0 {
+		t.Errorf("SetRetries(0): err %v, Retries %d", err, Retries)
+	}
+	if err := SetFragmentRetries(25); err != nil || FragmentRetries != 25 {
+		t.Errorf("SetFragmentRetries(25): err %v, FragmentRetries %d", err, FragmentRetries)
+	}
+}
+
+func TestIsRetryLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"WARNING: [download] Got error: HTTP Error 503. Retrying (1/10)...", true},
+		{"WARNING: [download] Got error: timed out. Retrying fragment 12 (2/10)...", true},
+		{"[download] Retrying is not mentioned here", false},
+		{"[download]  45.3% of 12.34MiB at 1.23MiB/s ETA 00:10", false},
+	}
+	for _, tt := range tests {
+		if got := isRetryLine(tt.line); got != tt.want {
+			t.Errorf("isRetryLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadReportsRetries(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+echo "WARNING: [download] Got error: HTTP Error 503. Retrying (1/10)..." >&2
+echo "[download] Got error: timed out. Retrying fragment 3 (1/10)..."
+echo "WARNING: [download] Got error: timed out. Retrying fragment 3 (2/10)..." >&2
+`+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &Retries, 4)
+	setForTest(t, &FragmentRetries, 7)
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Retries != 3 {
+		t.Errorf("Retries = %d, want 3", result.Retries)
+	}
+	args := calls()[0]
+	if argValue(args, "--retries") != "4" || argValue(args, "--fragment-retries") != "7" {
+		t.Errorf("retry flags not passed: %v", args)
+	}
+	for _, warning := range result.Warnings {
+		t.Errorf("retry reported as warning: %q", warning)
+	}
+}
+
+func TestRetryOverrideArgs(t *testing.T) {
+	three, zero, negative := 3, 0, -1
+
+	if args, err := retryOverrideArgs(nil, nil); err != nil || args != nil {
+		t.Errorf("no overrides: %v, %v", args, err)
+	}
+	args, err := retryOverrideArgs(&three, &zero)
+	if err != nil || !hasArgs(args, "--retries", "3", "--fragment-retries", "0") {
+		t.Errorf("overrides: %v, %v", args, err)
+	}
+	if _, err := retryOverrideArgs(&negative, nil); err == nil {
+		t.Error("negative retries accepted")
+	}
+	if _, err := retryOverrideArgs(nil, &negative); err == nil {
+		t.Error("negative fragment retries accepted")
+	}
+}
+
+// lastArgValue returns the value following the last occurrence of flag, the
+// one yt-dlp uses
+func lastArgValue(args []string, flag string) string {
+	value := ""
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			value = args[i+1]
+		}
+	}
+	return value
+}
+
+func TestDownloadRetryOverrides(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("m4a"))
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg))
+	retries, fragmentRetries := 1, 2
+
+	_, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		OutputDir:       t.TempDir(),
+		Retries:         &retries,
+		FragmentRetries: &fragmentRetries,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := calls()[0]
+	if lastArgValue(args, "--retries") != "1" || lastArgValue(args, "--fragment-retries") != "2" {
+		t.Errorf("overrides not in effect: %v", args)
+	}
+
+	none := -1
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir: t.TempDir(),
+		Retries:   &none,
+	}); err == nil {
+		t.Error("negative retries accepted")
+	}
+}
+
+func TestSetYTDLPBufferSize(t *testing.T) {
+	setForTest(t, &YTDLPBufferSize, "32K")
+	for _, size := range []string{"1024", "64K", "64k", "1M"} {
+		if err := SetYTDLPBufferSize(size); err != nil || YTDLPBufferSize != size {
+			t.Errorf("SetYTDLPBufferSize(%q): err %v, size %q", size, err, YTDLPBufferSize)
+		}
+	}
+	for _, size := range []string{"", "0", "-1K", "1G", "64 K", "1.5M", "32KB"} {
+		if err := SetYTDLPBufferSize(size); err == nil {
+			t.Errorf("SetYTDLPBufferSize(%q) accepted", size)
+		}
+	}
+
+	SetYTDLPBufferSize("2M")
+	setForTest(t, &ChunkSize, 1024)
+	args := ytdlpDownloadCommand(context.Background(), "best", "out.%(ext)s", "https://youtu.be/x").Args
+	if argValue(args, "--buffer-size") != "2M" {
+		t.Errorf("--buffer-size not taken from YTDLPBufferSize: %v", args)
+	}
+}
+
+func TestAudioConvertArgsLossless(t *testing.T) {
+	tests := []struct {
+		format, bitrate string
+		want            []string
+	}{
+		{"flac", "", []string{"-i", "in.webm", "-vn", "-acodec", "flac", "-max_muxing_queue_size", "1024", "-y", "out.flac"}},
+		{"wav", "", []string{"-i", "in.webm", "-vn", "-acodec", "pcm_s16le", "-max_muxing_queue_size", "1024", "-y", "out.wav"}},
+		{"mp3", "", []string{"-i", "in.webm", "-vn", "-acodec", "libmp3lame", "-ab", "128k", "-max_muxing_queue_size", "1024", "-y", "out.mp3"}},
+	}
+	for _, tt := range tests {
+		codec, err := resolveAudioCodec(tt.format, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bitrate, err := resolveAudioBitrate(codec, tt.bitrate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := audioConvertArgs(audioConversion{Input: "in.webm", Output: "out." + tt.format, Format: tt.format, Codec: codec, Bitrate: bitrate})
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: args %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadAudioLossless(t *testing.T) {
+	ytdlpLog, ytdlpCalls := argsLog(t)
+	useFakeYTDLP(t, ytdlpLog+"\n"+writeOutput("webm"))
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg))
+
+	result, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "wav",
+		OutputDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Ext(result.Path) != ".wav" {
+		t.Errorf("path = %s", result.Path)
+	}
+	convert := ffmpegCalls()[0]
+	if !hasArgs(convert, "-acodec", "pcm_s16le") || slices.Contains(convert, "-ab") {
+		t.Errorf("ffmpeg args %v, want pcm_s16le without a bitrate", convert)
+	}
+
+	// A bitrate for a lossless format is rejected before downloading
+	downloads := len(ytdlpCalls())
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "flac",
+		Bitrate:   "320k",
+		OutputDir: t.TempDir(),
+	}); err == nil || !strings.Contains(err.Error(), "lossless") {
+		t.Errorf("err = %v, want a lossless bitrate error", err)
+	}
+	if len(ytdlpCalls()) != downloads {
+		t.Error("downloaded before rejecting the bitrate")
+	}
+}
+
+func TestExpectedFilename(t *testing.T) {
+	long := "a" + strings.Repeat("é", 60)
+	tests := []struct {
+		metadata *VideoMetadata
+		format   string
+		want     string
+	}{
+		{&VideoMetadata{Title: "Plain title"}, "mp4", "Plain title.mp4"},
+		{&VideoMetadata{Title: "AC/DC: Live? <2024> | \"Best\" *ever*"}, "webm", "AC_DC_ Live_ _2024_ _ _Best_ _ever_.webm"},
+		{&VideoMetadata{Title: `C:\path\to`}, "mkv", "C__path_to.mkv"},
+		{&VideoMetadata{Title: "100% %(title)s"}, "mp3", "100_ _(title)s.mp3"},
+		{&VideoMetadata{Title: "Line one\nLine two\r"}, "mp4", "Line one Line two.mp4"},
+		{&VideoMetadata{Title: "  padded  "}, "", "padded.mp4"},
+		{&VideoMetadata{Title: "日本語のタイトル 🎵"}, "m4a", "日本語のタイトル 🎵.m4a"},
+		// Truncated to 100 bytes without splitting a character
+		{&VideoMetadata{Title: long}, "mp4", long[:99] + ".mp4"},
+		// Unusable titles fall back to the ID, then to "video"
+		{&VideoMetadata{Title: "..", ID: "aaaaaaaaaaa"}, "mp4", "aaaaaaaaaaa.mp4"},
+		{&VideoMetadata{Title: "   ", ID: "aaaaaaaaaaa"}, "mp4", "aaaaaaaaaaa.mp4"},
+		{&VideoMetadata{Title: "."}, "mp4", "video.mp4"},
+		{nil, "webm", "video.webm"},
+	}
+	for _, tt := range tests {
+		if got := ExpectedFilename(tt.metadata, tt.format); got != tt.want {
+			t.Errorf("ExpectedFilename(%+v, %q) = %q, want %q", tt.metadata, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestSetKeepFragments(t *testing.T) {
+	setForTest(t, &KeepFragments, false)
+	args := ytdlpDownloadCommand(context.Background(), "best", "out.%(ext)s", "https://youtu.be/x").Args
+	if slices.Contains(args, "--keep-fragments") {
+		t.Errorf("--keep-fragments passed by default: %v", args)
+	}
+
+	SetKeepFragments(true)
+	args = ytdlpDownloadCommand(context.Background(), "best", "out.%(ext)s", "https://youtu.be/x").Args
+	if !slices.Contains(args, "--keep-fragments") {
+		t.Errorf("--keep-fragments not passed when enabled: %v", args)
+	}
+	if args[len(args)-1] != "https://youtu.be/x" {
+		t.Errorf("URL is not the last argument: %v", args)
+	}
+}
+
+func TestParseStopReason(t *testing.T) {
+	tests := []struct{ line, want string }{
+		{"[info] Maximum number of downloads reached, stopping due to --max-downloads", "Maximum number of downloads reached, stopping due to --max-downloads"},
+		{"[download] Encountered a video that did not match filter, stopping due to --break-match-filter", "Encountered a video that did not match filter, stopping due to --break-match-filter"},
+		{"[download] Some title does not pass filter (duration < 600), skipping ..", ""},
+		{"ERROR: [youtube] x: Private video", ""},
+	}
+	for _, tt := range tests {
+		if got := parseStopReason(tt.line); got != tt.want {
+			t.Errorf("parseStopReason(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestStreamCommandIntentionalStop(t *testing.T) {
+	stop := `echo "[info] Maximum number of downloads reached, stopping due to --max-downloads"`
+	tests := []struct {
+		name    string
+		script  string
+		wantErr bool
+	}{
+		{"stop message and exit 101", stop + "\nexit 101", false},
+		{"exit 101 without a stop message", "exit 101", true},
+		{"stop message with another exit code", stop + "\nexit 1", true},
+		{"success", "exit 0", false},
+	}
+	for _, tt := range tests {
+		path := fakeBinary(t, "yt-dlp", tt.script)
+		output, err := streamCommand(context.Background(), newCommand(context.Background(), path), nil, "downloading")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if !tt.wantErr && tt.script != "exit 0" && output.StopReason == "" {
+			t.Errorf("%s: StopReason not set", tt.name)
+		}
+	}
+}
+
+func TestDownloadVideoStoppedByMaxDownloads(t *testing.T) {
+	stop := `echo "[info] Maximum number of downloads reached, stopping due to --max-downloads"
+exit 101`
+	useFakeYTDLP(t, writeOutput("mp4")+"\n"+stop)
+	useFakeFFMPEG(t, "exit 0")
+
+	// What was downloaded before the stop is kept
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("err = %v, want none for an intentional stop", err)
+	}
+	if filepath.Ext(result.Path) != ".mp4" {
+		t.Errorf("path = %s", result.Path)
+	}
+
+	// Stopping before anything was downloaded reports a skip, not a failure
+	useFakeYTDLP(t, stop)
+	_, err = DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	var skipped *SkippedError
+	if !errors.As(err, &skipped) || !strings.Contains(skipped.Reason, "--max-downloads") {
+		t.Errorf("err = %v, want a SkippedError", err)
+	}
+}
@@ -0,0 +1,40 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// embedSubtitleFormats are the containers that can hold soft subtitle tracks
+var embedSubtitleFormats = map[string]bool{
+	"mp4":  true,
+	"mkv":  true,
+	"webm": true, // WebVTT only; yt-dlp converts the subtitles
+}
+
+// validateEmbedSubtitlesFormat returns an error if format can't hold soft subtitles
+func validateEmbedSubtitlesFormat(format string) error {
+	if !embedSubtitleFormats[strings.ToLower(format)] {
+		return fmt.Errorf("subtitles cannot be embedded in %q (use mp4, mkv or webm)", format)
+	}
+	return nil
+}
+
+// embedSubtitlesArgs are the yt-dlp flags embedding the subtitles in languages
+// (default: en) as selectable tracks. The download is remuxed to format first:
+// our ffmpeg conversion would drop the subtitle tracks.
+func embedSubtitlesArgs(format string, languages []string, autoGenerated bool) []string {
+	if len(languages) == 0 {
+		languages = []string{"en"}
+	}
+	args := []string{
+		"--remux-video", strings.ToLower(format),
+		"--write-subs",
+		"--sub-langs", strings.Join(languages, ","),
+		"--embed-subs",
+	}
+	if autoGenerated {
+		args = append(args, "--write-auto-subs")
+	}
+	return args
+}
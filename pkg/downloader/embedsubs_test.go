@@ -0,0 +1,99 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidateEmbedSubtitlesFormat(t *testing.T) {
+	for _, format := range []string{"mp4", "MKV", "webm"} {
+		if err := validateEmbedSubtitlesFormat(format); err != nil {
+			t.Errorf("%s rejected: %v", format, err)
+		}
+	}
+	for _, format := range []string{"avi", "flv", "mov", "mp3", ""} {
+		if err := validateEmbedSubtitlesFormat(format); err == nil {
+			t.Errorf("%q accepted", format)
+		}
+	}
+}
+
+func TestEmbedSubtitlesArgs(t *testing.T) {
+	tests := []struct {
+		format        string
+		languages     []string
+		autoGenerated bool
+		want          []string
+	}{
+		{"mp4", nil, false, []string{"--remux-video", "mp4", "--write-subs", "--sub-langs", "en", "--embed-subs"}},
+		{"MKV", []string{"en", "de"}, false, []string{"--remux-video", "mkv", "--write-subs", "--sub-langs", "en,de", "--embed-subs"}},
+		{"webm", []string{"fr"}, true, []string{"--remux-video", "webm", "--write-subs", "--sub-langs", "fr", "--embed-subs", "--write-auto-subs"}},
+	}
+	for _, tt := range tests {
+		if got := embedSubtitlesArgs(tt.format, tt.languages, tt.autoGenerated); !slices.Equal(got, tt.want) {
+			t.Errorf("embedSubtitlesArgs(%q, %v, %v) = %q, want %q", tt.format, tt.languages, tt.autoGenerated, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadVideoEmbedSubtitles(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("mkv"))
+	useFakeFFMPEG(t, ffmpegScript("exit 0"))
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:            "mkv",
+		OutputDir:         t.TempDir(),
+		EmbedSubtitles:    true,
+		SubtitleLanguages: []string{"en", "es"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := calls()[0]
+	if !slices.Contains(args, "--embed-subs") || argValue(args, "--sub-langs") != "en,es" || argValue(args, "--remux-video") != "mkv" {
+		t.Errorf("yt-dlp args %v lack the embed flags", args)
+	}
+
+	// Without the option nothing is embedded
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:    "mkv",
+		OutputDir: t.TempDir(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if args := calls()[1]; slices.Contains(args, "--embed-subs") || slices.Contains(args, "--write-subs") {
+		t.Errorf("subtitles embedded by default: %v", args)
+	}
+}
+
+func TestDownloadVideoEmbedSubtitlesRejected(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("mp4"))
+	useFakeFFMPEG(t, ffmpegScript("exit 0"))
+
+	for _, opts := range []VideoOptions{
+		{Format: "avi", EmbedSubtitles: true},
+		{Format: "mp4", AudioOnly: true, EmbedSubtitles: true},
+	} {
+		opts.OutputDir = t.TempDir()
+		if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", opts); err == nil {
+			t.Errorf("%+v accepted", opts)
+		}
+	}
+
+	withoutFFMPEG(t)
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:         "mp4",
+		OutputDir:      t.TempDir(),
+		EmbedSubtitles: true,
+	})
+	if !errors.Is(err, ErrFFMPEGRequired) {
+		t.Errorf("err = %v, want ErrFFMPEGRequired", err)
+	}
+	if n := len(calls()); n != 0 {
+		t.Errorf("yt-dlp ran %d times for rejected options", n)
+	}
+}
@@ -0,0 +1,140 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	encodersMutex sync.Mutex
+	encodersCache = make(map[string]*encoderList) // FFMPEGPath -> encoders
+)
+
+// encoderList is a parsed `ffmpeg -encoders` listing
+type encoderList struct {
+	names   map[string]bool     // Encoder names, e.g. "libx264"
+	byCodec map[string][]string // Codec name -> encoders producing it, e.g. "h264" -> ["libx264"]
+}
+
+// codecAliases maps the codec tags yt-dlp reports (vcodec/acodec) to ffmpeg codec names
+var codecAliases = map[string]string{
+	"avc1": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"av01": "av1",
+	"vp09": "vp9",
+	"mp4a": "aac",
+}
+
+// FFMPEGHasEncoder reports whether the configured ffmpeg was built with the
+// named encoder (e.g. "libopus", "libx265"). The encoder list is read once per
+// ffmpeg binary and cached.
+func FFMPEGHasEncoder(name string) (bool, error) {
+	encoders, err := ffmpegEncoders()
+	if err != nil {
+		return false, err
+	}
+	return encoders.names[name], nil
+}
+
+// SupportsEncoder reports whether the configured ffmpeg can encode to name,
+// which is either an encoder (e.g. "libsvtav1") or a codec, including the
+// tags yt-dlp uses (e.g. "av1" or "av01"). Like FFMPEGHasEncoder, the
+// encoder list is cached per ffmpeg binary.
+func SupportsEncoder(name string) (bool, error) {
+	encoders, err := ffmpegEncoders()
+	if err != nil {
+		return false, err
+	}
+	return encoders.names[name] || len(encoders.forCodec(name)) > 0, nil
+}
+
+// forCodec returns the encoders producing codec, in ffmpeg's listing order
+func (l *encoderList) forCodec(codec string) []string {
+	codec = strings.ToLower(codec)
+	if alias, ok := codecAliases[codec]; ok {
+		codec = alias
+	}
+	return l.byCodec[codec]
+}
+
+// requireEncoder returns an error if ffmpeg can't encode to name, an encoder
+// or a codec like "mp3" that ffmpeg resolves to one, so a transcode fails
+// before the download instead of after it
+func requireEncoder(name string) error {
+	if name == "" || name == "copy" {
+		return nil
+	}
+	ok, err := SupportsEncoder(name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("ffmpeg at %s does not support the %s encoder", FFMPEGPath, name)
+	}
+	return nil
+}
+
+// ffmpegEncoders returns the encoders of the configured ffmpeg
+func ffmpegEncoders() (*encoderList, error) {
+	encodersMutex.Lock()
+	defer encodersMutex.Unlock()
+
+	path := FFMPEGPath
+	if encoders, ok := encodersCache[path]; ok {
+		return encoders, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	output, err := newCommand(ctx, path, "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ffmpeg encoders: %w", err)
+	}
+
+	encoders := parseEncoders(string(output))
+	encodersCache[path] = encoders
+	return encoders, nil
+}
+
+// parseEncoders parses `ffmpeg -encoders` output. Encoder lines follow the
+// "------" separator line; the codec is named in parentheses when it differs
+// from the encoder name:
+//
+//	V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC (codec h264)
+//	A....D aac                  AAC (Advanced Audio Coding)
+func parseEncoders(output string) *encoderList {
+	encoders := &encoderList{
+		names:   make(map[string]bool),
+		byCodec: make(map[string][]string),
+	}
+	listing := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if !listing {
+			listing = strings.HasPrefix(fields[0], "---")
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+
+		name := fields[1]
+		codec := name
+		if i := strings.LastIndex(line, "(codec "); i >= 0 {
+			if end := strings.Index(line[i:], ")"); end >= 0 {
+				codec = line[i+len("(codec ") : i+end]
+			}
+		}
+		encoders.names[name] = true
+		encoders.byCodec[codec] = append(encoders.byCodec[codec], name)
+	}
+	return encoders
+}
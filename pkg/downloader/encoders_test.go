@@ -0,0 +1,145 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestParseEncoders(t *testing.T) {
+	encoders := parseEncoders(" V..... = Video\n" + sampleEncoders)
+	for _, name := range []string{"libx264", "libvpx-vp9", "aac", "libmp3lame", "pcm_s16le"} {
+		if !encoders.names[name] {
+			t.Errorf("encoder %s not parsed", name)
+		}
+	}
+	// Legend lines before the separator are not encoders
+	if encoders.names["="] || encoders.names["Video"] {
+		t.Errorf("legend parsed as encoders: %v", encoders.names)
+	}
+	if got := encoders.forCodec("h264"); !slices.Equal(got, []string{"libx264"}) {
+		t.Errorf("forCodec(h264) = %v", got)
+	}
+	if got := encoders.forCodec("avc1"); !slices.Equal(got, []string{"libx264"}) {
+		t.Errorf("forCodec(avc1) = %v", got)
+	}
+	if got := encoders.forCodec("mp3"); !slices.Equal(got, []string{"libmp3lame"}) {
+		t.Errorf("forCodec(mp3) = %v", got)
+	}
+	if got := encoders.forCodec("flac"); !slices.Equal(got, []string{"flac"}) {
+		t.Errorf("forCodec(flac) = %v", got)
+	}
+}
+
+func TestFFMPEGHasEncoder(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, logLine+"\n"+ffmpegScript("exit 1"))
+
+	for name, want := range map[string]bool{"libopus": true, "libx264": true, "libx265": false, "mp3": false} {
+		got, err := FFMPEGHasEncoder(name)
+		if err != nil || got != want {
+			t.Errorf("FFMPEGHasEncoder(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if len(calls()) != 1 {
+		t.Errorf("ffmpeg -encoders ran %d times, want once (cached)", len(calls()))
+	}
+}
+
+func TestRequireEncoder(t *testing.T) {
+	useFakeFFMPEG(t, ffmpegScript("exit 1"))
+	for _, name := range []string{"", "copy", "libmp3lame", "mp3", "h264", "opus"} {
+		if err := requireEncoder(name); err != nil {
+			t.Errorf("requireEncoder(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"libx265", "libfdk_aac", "hevc"} {
+		if err := requireEncoder(name); err == nil {
+			t.Errorf("requireEncoder(%q) accepted a missing encoder", name)
+		}
+	}
+}
+
+func TestDownloadAudioChecksEncoderFirst(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("webm"))
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg))
+
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "m4a",
+		Codec:     "libfdk_aac",
+		OutputDir: t.TempDir(),
+	}); err == nil {
+		t.Fatal("missing encoder accepted")
+	}
+	if len(calls()) != 0 {
+		t.Errorf("downloaded before checking the encoder: %v", calls())
+	}
+
+	// A codec name ffmpeg resolves to an encoder is fine
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "mp3",
+		Codec:     "mp3",
+		OutputDir: t.TempDir(),
+	}); err != nil {
+		t.Errorf("Codec mp3: %v", err)
+	}
+}
+
+// av1Encoders is `ffmpeg -encoders` output of a build with AV1 and HEVC
+// encoders but no H.264 one
+const av1Encoders = `Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ------
+ V....D libsvtav1            SVT-AV1(Scalable Video Technology for AV1) encoder (codec av1)
+ V....D libaom-av1           libaom AV1 (codec av1)
+ V....D libx265              libx265 H.265 / HEVC (codec hevc)
+ A....D libopus              libopus Opus (codec opus)
+ S..... webvtt               WebVTT subtitle
+`
+
+func TestSupportsEncoderParsesListing(t *testing.T) {
+	encoders := parseEncoders(av1Encoders)
+	if got := encoders.forCodec("av01"); !slices.Equal(got, []string{"libsvtav1", "libaom-av1"}) {
+		t.Errorf("forCodec(av01) = %v, want both AV1 encoders in listing order", got)
+	}
+	if got := encoders.forCodec("HEV1"); !slices.Equal(got, []string{"libx265"}) {
+		t.Errorf("forCodec(HEV1) = %v", got)
+	}
+	if !encoders.names["webvtt"] || encoders.names["Frame-level"] {
+		t.Errorf("names = %v", encoders.names)
+	}
+}
+
+func TestSupportsEncoderPerBinary(t *testing.T) {
+	av1Log, av1Calls := argsLog(t)
+	av1 := fakeBinary(t, "ffmpeg", av1Log+"\ncat <<'ENCODERS'\n"+av1Encoders+"ENCODERS")
+	useFakeFFMPEG(t, ffmpegScript("exit 1"))
+
+	for name, want := range map[string]bool{"h264": true, "avc1": true, "libx264": true, "av1": false, "libsvtav1": false} {
+		if got, err := SupportsEncoder(name); err != nil || got != want {
+			t.Errorf("default build: SupportsEncoder(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+
+	// Switching binaries reads the other build's encoders, once
+	setForTest(t, &FFMPEGPath, av1)
+	for name, want := range map[string]bool{"h264": false, "av01": true, "libsvtav1": true, "hevc": true, "libopus": true} {
+		if got, err := SupportsEncoder(name); err != nil || got != want {
+			t.Errorf("AV1 build: SupportsEncoder(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if len(av1Calls()) != 1 {
+		t.Errorf("ffmpeg -encoders ran %d times, want once (cached)", len(av1Calls()))
+	}
+}
+
+func TestSupportsEncoderFailingFFMPEG(t *testing.T) {
+	useFakeFFMPEG(t, "exit 1")
+	if ok, err := SupportsEncoder("libx264"); err == nil || ok {
+		t.Errorf("SupportsEncoder = %v, %v; want an error", ok, err)
+	}
+}
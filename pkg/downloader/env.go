@@ -0,0 +1,71 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// ProcessEnv holds extra environment variables for every yt-dlp, ffmpeg and
+// ffprobe process, merged over the current environment (e.g. HTTPS_PROXY,
+// PYTHONWARNINGS). Can be set using SetProcessEnv()
+var ProcessEnv map[string]string
+
+// SetProcessEnv sets extra environment variables for spawned processes,
+// replacing any set before. A nil or empty map clears them.
+//
+// Example:
+//
+//	downloader.SetProcessEnv(map[string]string{"HTTPS_PROXY": "http://proxy:3128"})
+func SetProcessEnv(env map[string]string) error {
+	copied := make(map[string]string, len(env))
+	for key, value := range env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return fmt.Errorf("invalid environment variable name: %q", key)
+		}
+		copied[key] = value
+	}
+	ProcessEnv = copied
+	return nil
+}
+
+// newCommand is exec.CommandContext with ProcessEnv applied
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = processEnviron()
+	return cmd
+}
+
+// processEnviron returns os.Environ() with ProcessEnv merged over it,
+// or nil (inherit the environment) when ProcessEnv is empty
+func processEnviron() []string {
+	if len(ProcessEnv) == 0 {
+		return nil
+	}
+
+	env := os.Environ()
+	keys := make([]string, 0, len(ProcessEnv))
+	for key := range ProcessEnv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Later entries win in exec, but drop overridden ones to keep the list unambiguous
+	overridden := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		overridden[key] = true
+	}
+	merged := env[:0:0]
+	for _, entry := range env {
+		if key, _, _ := strings.Cut(entry, "="); !overridden[key] {
+			merged = append(merged, entry)
+		}
+	}
+	for _, key := range keys {
+		merged = append(merged, key+"="+ProcessEnv[key])
+	}
+	return merged
+}
@@ -0,0 +1,70 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSetProcessEnv(t *testing.T) {
+	setForTest(t, &ProcessEnv, nil)
+	env := map[string]string{"PYTHONWARNINGS": "ignore"}
+	if err := SetProcessEnv(env); err != nil {
+		t.Fatal(err)
+	}
+	env["PYTHONWARNINGS"] = "changed"
+	if ProcessEnv["PYTHONWARNINGS"] != "ignore" {
+		t.Error("ProcessEnv shares the caller's map")
+	}
+	for _, key := range []string{"", "A=B", "NUL\x00"} {
+		if err := SetProcessEnv(map[string]string{key: "x"}); err == nil {
+			t.Errorf("variable name %q accepted", key)
+		}
+	}
+	if err := SetProcessEnv(nil); err != nil || len(ProcessEnv) != 0 {
+		t.Errorf("clearing: err %v, ProcessEnv %v", err, ProcessEnv)
+	}
+}
+
+func TestNewCommandEnv(t *testing.T) {
+	t.Setenv("GOSTREAMPULLER_TEST_KEEP", "kept")
+	t.Setenv("GOSTREAMPULLER_TEST_OVERRIDE", "old")
+	setForTest(t, &ProcessEnv, nil)
+
+	if cmd := newCommand(context.Background(), "true"); cmd.Env != nil {
+		t.Errorf("Env = %v without ProcessEnv, want the inherited environment", cmd.Env)
+	}
+
+	if err := SetProcessEnv(map[string]string{"GOSTREAMPULLER_TEST_OVERRIDE": "new", "GOSTREAMPULLER_TEST_ADDED": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	env := newCommand(context.Background(), "true").Env
+	for _, want := range []string{"GOSTREAMPULLER_TEST_KEEP=kept", "GOSTREAMPULLER_TEST_OVERRIDE=new", "GOSTREAMPULLER_TEST_ADDED=1"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("Env lacks %s", want)
+		}
+	}
+	if slices.Contains(env, "GOSTREAMPULLER_TEST_OVERRIDE=old") {
+		t.Error("overridden value still in Env")
+	}
+}
+
+func TestProcessEnvReachesYTDLP(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+	useFakeYTDLP(t, `echo "$GOSTREAMPULLER_TEST_VALUE" > '`+envFile+`'
+echo '{"id": "aaaaaaaaaaa"}'`)
+	setForTest(t, &ProcessEnv, nil)
+	if err := SetProcessEnv(map[string]string{"GOSTREAMPULLER_TEST_VALUE": "from ProcessEnv"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GetVideoMetadataWithContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(envFile); strings.TrimSpace(string(data)) != "from ProcessEnv" {
+		t.Errorf("yt-dlp saw %q", data)
+	}
+}
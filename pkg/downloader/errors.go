@@ -0,0 +1,238 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors returned (wrapped) when yt-dlp output identifies the failure.
+// Use errors.Is to check for them.
+var (
+	// ErrRateLimited means the site throttled us (HTTP 429 / "Too Many Requests")
+	ErrRateLimited = errors.New("rate limited by remote site")
+
+	// ErrGeoBlocked means the video is not available from this server's region
+	ErrGeoBlocked = errors.New("video is not available in this region")
+
+	// ErrUnavailable means the video is private, removed, or does not exist
+	ErrUnavailable = errors.New("video is unavailable")
+
+	// ErrAgeRestricted means the video requires signing in to confirm the viewer's age
+	ErrAgeRestricted = errors.New("video is age-restricted")
+
+	// ErrNotYetAvailable means the video is an upcoming premiere or live stream
+	// that has not started yet
+	ErrNotYetAvailable = errors.New("video is not yet available")
+
+	// ErrFFMPEGRequired means the requested operation needs ffmpeg, which is not installed
+	ErrFFMPEGRequired = errors.New("ffmpeg is required but not available")
+
+	// ErrCookiesUnavailable means yt-dlp could not read the browser's cookie store,
+	// usually because the browser is running and has the database locked
+	ErrCookiesUnavailable = errors.New("browser cookies could not be read (close the browser or check the profile)")
+
+	// ErrSkippedByFilter means yt-dlp skipped the video because it did not
+	// match the configured filter (e.g. --match-filter)
+	ErrSkippedByFilter = errors.New("video skipped by filter")
+
+	// ErrDiskFull means the output or work directory ran out of space; the
+	// partial files of the download are removed
+	ErrDiskFull = errors.New("no space left on device")
+
+	// ErrTargetSizeTooSmall means a video cannot be compressed to the requested
+	// size without dropping below the minimum bitrate
+	ErrTargetSizeTooSmall = errors.New("target size is too small for the video's duration")
+)
+
+// SkippedError reports why yt-dlp skipped a video; it unwraps to ErrSkippedByFilter
+type SkippedError struct {
+	Reason string // The filter that rejected the video, or why yt-dlp stopped, as printed by yt-dlp
+}
+
+func (e *SkippedError) Error() string {
+	return ErrSkippedByFilter.Error() + ": " + e.Reason
+}
+
+func (e *SkippedError) Unwrap() error {
+	return ErrSkippedByFilter
+}
+
+// NotYetAvailableError reports when an upcoming video is scheduled to start;
+// it unwraps to the underlying error, which matches ErrNotYetAvailable
+type NotYetAvailableError struct {
+	ScheduledAt *time.Time // Scheduled start, nil if yt-dlp did not report one
+	Err         error
+}
+
+func (e *NotYetAvailableError) Error() string {
+	if e.ScheduledAt == nil {
+		return ErrNotYetAvailable.Error()
+	}
+	return fmt.Sprintf("%s (scheduled for %s)", ErrNotYetAvailable, e.ScheduledAt.UTC().Format(time.RFC3339))
+}
+
+func (e *NotYetAvailableError) Unwrap() error {
+	return e.Err
+}
+
+// DownloadError describes a failed yt-dlp or ffmpeg invocation with enough detail
+// to reproduce it. It unwraps to the ClassifiedError when stderr was recognized,
+// so errors.Is(err, ErrRateLimited) and friends keep working.
+type DownloadError struct {
+	Stage    string   // What was running: "metadata", "downloading", "converting", ...
+	Args     []string // The full command line, binary first (proxy credentials redacted)
+	ExitCode int      // Process exit code, or -1 if it did not exit normally
+	Stderr   string   // Trailing stderr output
+	Err      error    // The *ClassifiedError, or the underlying exec error
+}
+
+func (e *DownloadError) Error() string {
+	detail := e.Err.Error()
+	if _, ok := e.Err.(*ClassifiedError); !ok && strings.TrimSpace(e.Stderr) != "" {
+		detail = lastLine(e.Stderr)
+	}
+	return fmt.Sprintf("%s failed: %s (exit code %d, command: %s)",
+		e.Stage, detail, e.ExitCode, strings.Join(e.Args, " "))
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// newDownloadError builds a DownloadError for a command that failed with err
+func newDownloadError(cmd *exec.Cmd, stage string, stderr string, err error) *DownloadError {
+	downloadErr := &DownloadError{
+		Stage:    stage,
+		Args:     redactArgs(cmd.Args),
+		ExitCode: -1,
+		Stderr:   strings.TrimSpace(stderr),
+		Err:      err,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		downloadErr.ExitCode = exitErr.ExitCode()
+	}
+	if classified := classifyError(stderr); classified != nil {
+		downloadErr.Err = classified
+	}
+	return downloadErr
+}
+
+// DefaultRateLimitRetryAfter is the back-off suggested for rate limited requests
+// when yt-dlp does not report one
+var DefaultRateLimitRetryAfter = 60 * time.Second
+
+// ClassifiedError is a yt-dlp failure matched to one of the sentinel errors
+type ClassifiedError struct {
+	Err        error         // One of the sentinel errors above
+	RetryAfter time.Duration // Suggested back-off, only set for ErrRateLimited
+	Detail     string        // The yt-dlp error line that matched
+}
+
+func (e *ClassifiedError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + e.Detail
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the suggested back-off for a rate limited error, or 0
+// if err is not rate limited
+func RetryAfter(err error) time.Duration {
+	var classified *ClassifiedError
+	if errors.As(err, &classified) && errors.Is(classified.Err, ErrRateLimited) {
+		return classified.RetryAfter
+	}
+	return 0
+}
+
+// errorPatterns maps lowercase yt-dlp stderr fragments to sentinel errors
+var errorPatterns = []struct {
+	fragment string
+	err      error
+}{
+	{"http error 429", ErrRateLimited},
+	{"too many requests", ErrRateLimited},
+	{"rate-limited", ErrRateLimited},
+	{"rate limited", ErrRateLimited},
+	{"not available in your country", ErrGeoBlocked},
+	{"geo restriction", ErrGeoBlocked},
+	{"geo-restricted", ErrGeoBlocked},
+	{"blocked it in your country", ErrGeoBlocked},
+	{"confirm your age", ErrAgeRestricted},
+	{"age-restricted", ErrAgeRestricted},
+	{"inappropriate for some users", ErrAgeRestricted},
+	{"video unavailable", ErrUnavailable},
+	{"this video is unavailable", ErrUnavailable},
+	{"private video", ErrUnavailable},
+	{"has been removed", ErrUnavailable},
+	{"http error 404", ErrUnavailable},
+	// Extractor messages only; local files that don't exist are not the video's fault
+	{"this video does not exist", ErrUnavailable},
+	{"playlist does not exist", ErrUnavailable},
+	{"channel does not exist", ErrUnavailable},
+	{"premieres in", ErrNotYetAvailable},
+	{"live event will begin", ErrNotYetAvailable},
+	{"this live event will start", ErrNotYetAvailable},
+	{"no space left on device", ErrDiskFull},
+	{"not enough space on the disk", ErrDiskFull},
+	{"cookie database", ErrCookiesUnavailable},
+	{"cookies database", ErrCookiesUnavailable},
+	{"database is locked", ErrCookiesUnavailable},
+}
+
+// retryAfterPattern extracts "retry after N seconds" style hints from yt-dlp output
+var retryAfterPattern = regexp.MustCompile(`(?i)retry[- ]after:?\s*(\d+)`)
+
+// classifyError matches yt-dlp stderr against known failure messages.
+// Returns nil if the output is not recognized.
+func classifyError(stderr string) *ClassifiedError {
+	lower := strings.ToLower(stderr)
+	for _, pattern := range errorPatterns {
+		idx := strings.Index(lower, pattern.fragment)
+		if idx < 0 {
+			continue
+		}
+
+		classified := &ClassifiedError{
+			Err:    pattern.err,
+			Detail: lineAt(stderr, idx),
+		}
+		if errors.Is(pattern.err, ErrRateLimited) {
+			classified.RetryAfter = DefaultRateLimitRetryAfter
+			if m := retryAfterPattern.FindStringSubmatch(stderr); m != nil {
+				if seconds, err := strconv.Atoi(m[1]); err == nil && seconds > 0 {
+					classified.RetryAfter = time.Duration(seconds) * time.Second
+				}
+			}
+		}
+		return classified
+	}
+	return nil
+}
+
+// lineAt returns the trimmed line of s containing byte offset idx
+func lineAt(s string, idx int) string {
+	start := strings.LastIndexByte(s[:idx], '\n') + 1
+	end := strings.IndexByte(s[idx:], '\n')
+	if end < 0 {
+		return strings.TrimSpace(s[start:])
+	}
+	return strings.TrimSpace(s[start : idx+end])
+}
+
+// lastLine returns the last non-empty line of command output, which for
+// ffmpeg and yt-dlp is usually the actual error message
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,79 @@
+package downloader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	defaultVideoExtensions = []string{"mkv", "mp4", "webm", "avi", "mov", "flv", "m4v", "ts", "m2ts", "3gp"}
+	defaultAudioExtensions = []string{"webm", "m4a", "opus", "ogg", "mp3", "aac"}
+
+	// VideoExtensions and AudioExtensions are the extensions tried, in order, to
+	// find the file a video or audio download produced (the requested format is
+	// always tried first). Can be set using SetDownloadExtensions()
+	VideoExtensions = slices.Clone(defaultVideoExtensions)
+	AudioExtensions = slices.Clone(defaultAudioExtensions)
+)
+
+// SetDownloadExtensions sets the extensions tried, in order, to find the file
+// a download produced, so uncommon containers (e.g. "ts", "m2ts") are found
+// without falling back to probing every file. A nil or empty list restores
+// that list's default.
+//
+// Example:
+//
+//	err := downloader.SetDownloadExtensions([]string{"mp4", "mkv", "ts"}, nil)
+func SetDownloadExtensions(video []string, audio []string) error {
+	videoExts, err := normalizeExtensions(video, defaultVideoExtensions)
+	if err != nil {
+		return err
+	}
+	audioExts, err := normalizeExtensions(audio, defaultAudioExtensions)
+	if err != nil {
+		return err
+	}
+	VideoExtensions = videoExts
+	AudioExtensions = audioExts
+	return nil
+}
+
+// normalizeExtensions lowercases exts and strips leading dots, returning a copy
+// of fallback if exts is empty
+func normalizeExtensions(exts []string, fallback []string) ([]string, error) {
+	if len(exts) == 0 {
+		return slices.Clone(fallback), nil
+	}
+	normalized := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext == "" || strings.ContainsAny(ext, `/\.*?[`) {
+			return nil, fmt.Errorf("invalid extension %q", ext)
+		}
+		if !slices.Contains(normalized, ext) {
+			normalized = append(normalized, ext)
+		}
+	}
+	return normalized, nil
+}
+
+// downloadExtensions returns the extensions to look for after a download:
+// format first (if set), then AudioExtensions or VideoExtensions
+func downloadExtensions(audio bool, format string) []string {
+	list := VideoExtensions
+	if audio {
+		list = AudioExtensions
+	}
+	format = strings.ToLower(format)
+	if format == "" || (len(list) > 0 && list[0] == format) {
+		return list
+	}
+	exts := []string{format}
+	for _, ext := range list {
+		if ext != format {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
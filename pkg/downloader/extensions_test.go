@@ -0,0 +1,113 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// resetExtensions restores the extension lists when the test ends
+func resetExtensions(t *testing.T) {
+	t.Helper()
+	setForTest(t, &VideoExtensions, slices.Clone(defaultVideoExtensions))
+	setForTest(t, &AudioExtensions, slices.Clone(defaultAudioExtensions))
+}
+
+func TestSetDownloadExtensions(t *testing.T) {
+	resetExtensions(t)
+
+	if err := SetDownloadExtensions([]string{" .MTS", "mp4", "mts"}, []string{"wma"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"mts", "mp4"}; !slices.Equal(VideoExtensions, want) {
+		t.Errorf("VideoExtensions = %v, want %v", VideoExtensions, want)
+	}
+	if want := []string{"wma"}; !slices.Equal(AudioExtensions, want) {
+		t.Errorf("AudioExtensions = %v, want %v", AudioExtensions, want)
+	}
+
+	for _, ext := range []string{"", ".", "tar.gz", "m*", "../mp4", "[a]"} {
+		if err := SetDownloadExtensions([]string{ext}, nil); err == nil {
+			t.Errorf("extension %q accepted", ext)
+		}
+	}
+	if !slices.Equal(AudioExtensions, []string{"wma"}) {
+		t.Error("a rejected call changed the lists")
+	}
+
+	if err := SetDownloadExtensions(nil, []string{}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(VideoExtensions, defaultVideoExtensions) || !slices.Equal(AudioExtensions, defaultAudioExtensions) {
+		t.Errorf("empty lists did not restore the defaults: %v, %v", VideoExtensions, AudioExtensions)
+	}
+}
+
+func TestDownloadExtensions(t *testing.T) {
+	resetExtensions(t)
+	SetDownloadExtensions([]string{"mkv", "mp4", "ts"}, []string{"m4a", "opus"})
+
+	tests := []struct {
+		audio  bool
+		format string
+		want   []string
+	}{
+		{false, "", []string{"mkv", "mp4", "ts"}},
+		{false, "MP4", []string{"mp4", "mkv", "ts"}},
+		{false, "mkv", []string{"mkv", "mp4", "ts"}},
+		{false, "3gp", []string{"3gp", "mkv", "mp4", "ts"}},
+		{true, "", []string{"m4a", "opus"}},
+		{true, "opus", []string{"opus", "m4a"}},
+	}
+	for _, tt := range tests {
+		if got := downloadExtensions(tt.audio, tt.format); !slices.Equal(got, tt.want) {
+			t.Errorf("downloadExtensions(%v, %q) = %v, want %v", tt.audio, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFindDownloadedFileCustomExtension(t *testing.T) {
+	resetExtensions(t)
+	// Without ffprobe nothing is found by probing, only by extension
+	withoutFFMPEG(t)
+	temp := filepath.Join(t.TempDir(), "video_1.%(ext)s")
+	mts := strings.Replace(temp, "%(ext)s", "mts", 1)
+	if err := os.WriteFile(mts, []byte("media"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := findDownloadedFile(temp, downloadExtensions(false, "")); got != "" {
+		t.Fatalf("found %q with the default extensions", got)
+	}
+	if err := SetDownloadExtensions(append(slices.Clone(defaultVideoExtensions), "mts"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := findDownloadedFile(temp, downloadExtensions(false, "")); got != mts {
+		t.Errorf("found %q, want %q", got, mts)
+	}
+}
+
+func TestDownloadFindsCustomExtension(t *testing.T) {
+	resetExtensions(t)
+	useFakeYTDLP(t, writeOutput("mts"))
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(logLine+"\n"+writeLastArg))
+	if err := SetDownloadExtensions([]string{"mts"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The .mts download was found and converted to the requested mp4
+	if len(calls()) == 0 || filepath.Ext(argValue(calls()[0], "-i")) != ".mts" {
+		t.Errorf("ffmpeg calls %v, want the .mts download as input", calls())
+	}
+	if filepath.Ext(result.Path) != ".mp4" {
+		t.Errorf("path = %s", result.Path)
+	}
+}
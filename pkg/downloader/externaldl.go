@@ -0,0 +1,84 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	// ExternalDownloader is the external program yt-dlp delegates downloads to
+	// (e.g. "aria2c"). Empty uses yt-dlp's native downloader.
+	// Can be set using SetExternalDownloader()
+	ExternalDownloader string
+
+	// ExternalDownloaderArgs are extra arguments passed to ExternalDownloader
+	ExternalDownloaderArgs []string
+)
+
+// SetExternalDownloader makes yt-dlp delegate downloads to an external program,
+// passing "--downloader <name> --downloader-args <name>:<args>".
+// aria2c can dramatically speed up fragmented downloads. If the program is not
+// installed, downloads fall back to the native downloader with a warning.
+// Pass an empty name to go back to the native downloader.
+//
+// Example:
+//
+//	downloader.SetExternalDownloader("aria2c", "-x", "16", "-s", "16", "-k", "1M")
+func SetExternalDownloader(name string, args ...string) {
+	ExternalDownloader = name
+	ExternalDownloaderArgs = args
+}
+
+// externalDownloaderArgs returns the yt-dlp flags for the configured external
+// downloader, or nil if none is set or it is not available
+func externalDownloaderArgs() []string {
+	if ExternalDownloader == "" {
+		return nil
+	}
+
+	if _, err := exec.LookPath(ExternalDownloader); err != nil {
+		warnOnce("external-downloader:"+ExternalDownloader,
+			fmt.Sprintf("External downloader %q not found, using yt-dlp's native downloader", ExternalDownloader))
+		return nil
+	}
+
+	args := []string{"--downloader", ExternalDownloader}
+	if len(ExternalDownloaderArgs) > 0 {
+		// --downloader-args is keyed by downloader name, not path
+		name := strings.TrimSuffix(filepath.Base(ExternalDownloader), ".exe")
+		args = append(args, "--downloader-args", name+":"+shellJoin(ExternalDownloaderArgs))
+	}
+	return args
+}
+
+// shellJoin joins args into one string that yt-dlp splits back into the same
+// args, single-quoting any that contain whitespace, quotes or backslashes
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg == "":
+			quoted[i] = "''"
+		case strings.ContainsAny(arg, " \t\n'\"\\"):
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		default:
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// warned tracks which warnOnce keys have already been printed
+var warned sync.Map
+
+// warnOnce prints a warning to stderr the first time it is called with key
+func warnOnce(key string, msg string) {
+	if _, loaded := warned.LoadOrStore(key, true); loaded {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[gostreampuller] ⚠ Warning: %s\n", msg)
+}
@@ -0,0 +1,55 @@
+package downloader
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExternalDownloaderArgs(t *testing.T) {
+	aria2c := fakeBinary(t, "aria2c", "exit 0")
+	setForTest(t, &ExternalDownloader, "")
+	setForTest(t, &ExternalDownloaderArgs, nil)
+
+	if args := externalDownloaderArgs(); args != nil {
+		t.Errorf("no downloader set: got %v", args)
+	}
+
+	SetExternalDownloader(aria2c, "-x", "16", "-k", "1M")
+	got := strings.Join(externalDownloaderArgs(), " ")
+	want := "--downloader " + aria2c + " --downloader-args aria2c:-x 16 -k 1M"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	SetExternalDownloader(aria2c)
+	if got := strings.Join(externalDownloaderArgs(), " "); got != "--downloader "+aria2c {
+		t.Errorf("without args: got %s", got)
+	}
+}
+
+func TestExternalDownloaderArgsWithSpaces(t *testing.T) {
+	aria2c := fakeBinary(t, "aria2c", "exit 0")
+	setForTest(t, &ExternalDownloader, "")
+	setForTest(t, &ExternalDownloaderArgs, nil)
+
+	SetExternalDownloader(aria2c, "--header", "User-Agent: Mozilla/5.0", "--dir", "it's here", "")
+	args := externalDownloaderArgs()
+	want := `aria2c:--header 'User-Agent: Mozilla/5.0' --dir 'it'\''s here' ''`
+	if got := args[len(args)-1]; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestExternalDownloaderFallback(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "aria2c")
+	setForTest(t, &ExternalDownloader, missing)
+	setForTest(t, &ExternalDownloaderArgs, []string{"-x", "16"})
+
+	if args := externalDownloaderArgs(); args != nil {
+		t.Errorf("unavailable downloader: got %v, want the native downloader", args)
+	}
+	if _, warnedAbout := warned.Load("external-downloader:" + missing); !warnedAbout {
+		t.Error("no warning about the missing downloader")
+	}
+}
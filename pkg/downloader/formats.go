@@ -0,0 +1,166 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatInfo describes one format a video is available in, as listed by yt-dlp
+type FormatInfo struct {
+	FormatID       string  `json:"format_id"`
+	FormatNote     string  `json:"format_note"` // e.g. "720p", "medium"
+	Extension      string  `json:"ext"`
+	Protocol       string  `json:"protocol"` // https, m3u8_native, ...
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	FPS            float64 `json:"fps"`
+	VideoCodec     string  `json:"vcodec"` // "none" for audio-only formats
+	AudioCodec     string  `json:"acodec"` // "none" for video-only formats
+	DynamicRange   string  `json:"dynamic_range"`
+	Language       string  `json:"language"`
+	TotalBitrate   float64 `json:"tbr"` // KBit/s
+	AudioBitrate   float64 `json:"abr"` // KBit/s
+	Filesize       int64   `json:"filesize"`
+	FilesizeApprox int64   `json:"filesize_approx"`
+}
+
+// HasVideo reports whether the format contains a video stream
+func (f FormatInfo) HasVideo() bool {
+	return f.VideoCodec != "" && f.VideoCodec != "none"
+}
+
+// HasAudio reports whether the format contains an audio stream
+func (f FormatInfo) HasAudio() bool {
+	return f.AudioCodec != "" && f.AudioCodec != "none"
+}
+
+// GetVideoMetadataWithFormats fetches a video's metadata together with the
+// formats it is available in. Both come from the same yt-dlp --dump-json
+// call, so this is cheaper than GetVideoMetadata followed by ListFormats.
+// Upcoming premieres and live streams have no formats yet.
+//
+// Example:
+//
+//	metadata, formats, err := downloader.GetVideoMetadataWithFormats(url)
+func GetVideoMetadataWithFormats(url string) (*VideoMetadata, []FormatInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	return GetVideoMetadataWithFormatsContext(ctx, url)
+}
+
+// GetVideoMetadataWithFormatsContext is GetVideoMetadataWithFormats with a
+// custom context for timeout/cancellation
+func GetVideoMetadataWithFormatsContext(ctx context.Context, url string) (*VideoMetadata, []FormatInfo, error) {
+	metadata, err := GetVideoMetadataWithContext(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+	formats, err := metadata.Formats()
+	if err != nil {
+		return nil, nil, err
+	}
+	return metadata, formats, nil
+}
+
+// ListFormats returns the formats a video is available in, in yt-dlp's order
+// (worst to best). Use GetVideoMetadataWithFormats if the metadata is needed too.
+func ListFormats(ctx context.Context, url string) ([]FormatInfo, error) {
+	_, formats, err := GetVideoMetadataWithFormatsContext(ctx, url)
+	return formats, err
+}
+
+// Formats decodes the formats array of the raw yt-dlp metadata
+func (m *VideoMetadata) Formats() ([]FormatInfo, error) {
+	formats := []FormatInfo{}
+	list := rawList(m.Raw, "formats")
+	if list == nil {
+		return formats, nil
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode formats: %w", err)
+	}
+	if err := json.Unmarshal(data, &formats); err != nil {
+		return nil, fmt.Errorf("failed to parse formats: %w", err)
+	}
+	return formats, nil
+}
+
+// EstimateSize estimates the size in bytes of a download with opts from the
+// video's formats, following the selection of BuildFormatSelector. Returns 0
+// if a selected format has no known size (e.g. live streams), or if opts are
+// invalid or use a preference it can't follow (PreferHDR, PreferProgressive).
+func EstimateSize(formats []FormatInfo, opts VideoOptions) int64 {
+	if _, err := BuildFormatSelector(opts); err != nil || opts.PreferHDR || opts.PreferProgressive {
+		return 0
+	}
+	resolution, err := strconv.Atoi(opts.Resolution)
+	if err != nil || resolution <= 0 {
+		resolution = 720
+	}
+	codec := opts.Codec
+	if codec == "" {
+		codec = "avc1"
+	}
+
+	// yt-dlp lists formats from worst to best
+	pick := func(best bool, match func(FormatInfo) bool) *FormatInfo {
+		var picked *FormatInfo
+		for i := range formats {
+			if match(formats[i]) {
+				picked = &formats[i]
+				if !best {
+					break
+				}
+			}
+		}
+		return picked
+	}
+	videoOnly := func(f FormatInfo) bool { return f.HasVideo() && !f.HasAudio() }
+	audioOnly := func(f FormatInfo) bool { return f.HasAudio() && !f.HasVideo() }
+	muxed := func(f FormatInfo) bool { return f.HasVideo() && f.HasAudio() }
+
+	best := opts.Quality != QualityDataSaver
+	var video, audio *FormatInfo
+	switch {
+	case opts.AudioOnly:
+		audio = pick(best, audioOnly)
+	case opts.Quality == "" || opts.Quality == QualityBalanced:
+		video = pick(true, func(f FormatInfo) bool {
+			return videoOnly(f) && f.Height <= resolution && strings.Contains(f.VideoCodec, codec)
+		})
+		if video == nil && opts.VideoOnly {
+			video = pick(true, func(f FormatInfo) bool { return videoOnly(f) && f.Height <= resolution })
+		}
+	default:
+		video = pick(best, videoOnly)
+	}
+	if video != nil && !opts.VideoOnly {
+		audio = pick(best, audioOnly)
+		if audio == nil {
+			video = nil
+		}
+	}
+	if video == nil && audio == nil && !opts.AudioOnly && !opts.VideoOnly {
+		// The merge isn't possible, yt-dlp falls back to a pre-muxed file
+		video = pick(best, muxed)
+	}
+
+	var total int64
+	for _, f := range []*FormatInfo{video, audio} {
+		if f == nil {
+			continue
+		}
+		size := max(f.Filesize, f.FilesizeApprox)
+		if size <= 0 {
+			return 0
+		}
+		total += size
+	}
+	return total
+}
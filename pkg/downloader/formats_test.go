@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+)
+
+// formatsFixture is trimmed yt-dlp --dump-json output with one format of each kind
+const formatsFixture = `{
+	"id": "aaaaaaaaaaa",
+	"title": "Fixture video",
+	"duration": 212,
+	"uploader": "Channel",
+	"formats": [
+		{"format_id": "140", "format_note": "medium", "ext": "m4a", "protocol": "https", "vcodec": "none", "acodec": "mp4a.40.2", "abr": 129.5, "tbr": 129.5, "language": "en", "filesize": 3436072},
+		{"format_id": "18", "format_note": "360p", "ext": "mp4", "protocol": "https", "width": 640, "height": 360, "fps": 30, "vcodec": "avc1.42001E", "acodec": "mp4a.40.2", "tbr": 503.2, "filesize_approx": 13343542},
+		{"format_id": "137", "format_note": "1080p", "ext": "mp4", "protocol": "https", "width": 1920, "height": 1080, "fps": 30, "vcodec": "avc1.640028", "acodec": "none", "dynamic_range": "SDR", "tbr": 4424.1, "filesize": 117323648}
+	]
+}`
+
+func TestMetadataFormatsFromOneJSON(t *testing.T) {
+	metadata := parseMetadata(t, formatsFixture)
+	formats, err := metadata.Formats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.ID != "aaaaaaaaaaa" || metadata.Title != "Fixture video" || metadata.Duration != 212 {
+		t.Errorf("metadata %+v", metadata)
+	}
+	if len(formats) != 3 {
+		t.Fatalf("%d formats, want 3", len(formats))
+	}
+	want := []FormatInfo{
+		{FormatID: "140", FormatNote: "medium", Extension: "m4a", Protocol: "https", VideoCodec: "none", AudioCodec: "mp4a.40.2", AudioBitrate: 129.5, TotalBitrate: 129.5, Language: "en", Filesize: 3436072},
+		{FormatID: "18", FormatNote: "360p", Extension: "mp4", Protocol: "https", Width: 640, Height: 360, FPS: 30, VideoCodec: "avc1.42001E", AudioCodec: "mp4a.40.2", TotalBitrate: 503.2, FilesizeApprox: 13343542},
+		{FormatID: "137", FormatNote: "1080p", Extension: "mp4", Protocol: "https", Width: 1920, Height: 1080, FPS: 30, VideoCodec: "avc1.640028", AudioCodec: "none", DynamicRange: "SDR", TotalBitrate: 4424.1, Filesize: 117323648},
+	}
+	for i := range want {
+		if formats[i] != want[i] {
+			t.Errorf("format %d = %+v, want %+v", i, formats[i], want[i])
+		}
+	}
+
+	tracks := [][2]bool{{false, true}, {true, true}, {true, false}}
+	for i, format := range formats {
+		if format.HasVideo() != tracks[i][0] || format.HasAudio() != tracks[i][1] {
+			t.Errorf("format %s: HasVideo %v, HasAudio %v", format.FormatID, format.HasVideo(), format.HasAudio())
+		}
+	}
+}
+
+func TestMetadataFormatsEmpty(t *testing.T) {
+	// Upcoming premieres have no formats yet
+	formats, err := parseMetadata(t, `{"id": "aaaaaaaaaaa", "live_status": "is_upcoming"}`).Formats()
+	if err != nil || formats == nil || len(formats) != 0 {
+		t.Errorf("formats %#v, %v; want an empty list", formats, err)
+	}
+}
+
+func TestGetVideoMetadataWithFormatsOneCall(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\ncat <<'JSON'\n"+formatsFixture+"\nJSON")
+
+	metadata, formats, err := GetVideoMetadataWithFormatsContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Title != "Fixture video" || len(formats) != 3 || formats[2].Height != 1080 {
+		t.Errorf("metadata %q, formats %+v", metadata.Title, formats)
+	}
+	if n := len(calls()); n != 1 {
+		t.Errorf("yt-dlp ran %d times, want once", n)
+	}
+}
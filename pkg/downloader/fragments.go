@@ -0,0 +1,189 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ConcurrentFragments is how many DASH/HLS fragments yt-dlp downloads in
+	// parallel (default: 3). Can be set using SetConcurrentFragmentDownloads()
+	ConcurrentFragments = 3
+
+	// AdaptiveFragments tunes the concurrent fragment count from the speed of
+	// previous downloads (default: false). The tuned value is saved in
+	// ~/.gostreampuller/fragment_tuning.json and used as the starting point
+	// next time. Can be set using SetAdaptiveFragments()
+	AdaptiveFragments = false
+
+	fragmentTuningMutex sync.Mutex
+	fragmentTuningValue int // Current tuned value, 0 until loaded
+)
+
+const (
+	// maxConcurrentFragments caps both the setting and adaptive tuning
+	maxConcurrentFragments = 16
+
+	// minSpeedSamples is how many progress lines a download needs before its
+	// speed is used for tuning; short downloads never reach full speed
+	minSpeedSamples = 5
+
+	// fastFragmentSpeed and slowFragmentSpeed (bytes/s per fragment) bound the
+	// per-connection speed the tuning heuristic aims for
+	fastFragmentSpeed = 1024 * 1024
+	slowFragmentSpeed = 128 * 1024
+)
+
+// speedPattern matches the speed of a yt-dlp progress line such as
+//
+//	[download]  45.3% of ~ 12.34MiB at  1.23MiB/s ETA 00:10
+var speedPattern = regexp.MustCompile(`\sat\s+(\d+(?:\.\d+)?)([KMGT]i?B|B)/s`)
+
+// SetConcurrentFragmentDownloads sets how many fragments yt-dlp downloads in
+// parallel. With AdaptiveFragments this is only the initial value.
+func SetConcurrentFragmentDownloads(n int) error {
+	if n < 1 || n > maxConcurrentFragments {
+		return fmt.Errorf("concurrent fragments must be between 1 and %d: %d", maxConcurrentFragments, n)
+	}
+	ConcurrentFragments = n
+	return nil
+}
+
+// SetAdaptiveFragments enables or disables adaptive tuning of the
+// concurrent fragment count. This is experimental.
+func SetAdaptiveFragments(enabled bool) {
+	AdaptiveFragments = enabled
+}
+
+// parseSpeed returns the download speed (bytes/s) of a yt-dlp progress line
+func parseSpeed(line string) (float64, bool) {
+	m := speedPattern.FindStringSubmatch(line)
+	if m == nil {
+		return 0, false
+	}
+	speed, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return speed * sizeUnits[m[2]], true
+}
+
+// suggestFragments is the tuning heuristic: given the fragment count a
+// download used and its average speed (bytes/s), it returns the count to use
+// next. Fast connections suggest headroom for more; slow ones suggest the
+// fragments are competing for the link.
+func suggestFragments(current int, speed float64) int {
+	if current < 1 {
+		current = 1
+	}
+	perFragment := speed / float64(current)
+	switch {
+	case perFragment >= fastFragmentSpeed:
+		return min(current*2, maxConcurrentFragments)
+	case perFragment < slowFragmentSpeed && current > 1:
+		return max(current/2, 1)
+	}
+	return current
+}
+
+// runDownload runs a yt-dlp download (see runProxiedDownload). With
+// AdaptiveFragments, it uses the tuned fragment count and updates it from the
+// download's speed.
+func runDownload(ctx context.Context, selector string, temp string, url string, progressCb ProgressCallback, extra ...string) (*commandOutput, error) {
+	if !AdaptiveFragments {
+		return runProxiedDownload(ctx, selector, temp, url, progressCb, extra...)
+	}
+
+	fragments := tunedFragments()
+	// yt-dlp uses the last occurrence of a flag, overriding ConcurrentFragments
+	args := append(append([]string{}, extra...), "--concurrent-fragments", strconv.Itoa(fragments))
+	output, err := runProxiedDownload(ctx, selector, temp, url, progressCb, args...)
+	if err == nil && output.SpeedSamples >= minSpeedSamples {
+		updateTunedFragments(fragments, output.AverageSpeed)
+	}
+	return output, err
+}
+
+// tunedFragments returns the fragment count adaptive downloads start with
+func tunedFragments() int {
+	fragmentTuningMutex.Lock()
+	defer fragmentTuningMutex.Unlock()
+
+	if fragmentTuningValue == 0 {
+		fragmentTuningValue = ConcurrentFragments
+		if saved := readFragmentTuning(); saved != nil && saved.ConcurrentFragments >= 1 &&
+			saved.ConcurrentFragments <= maxConcurrentFragments {
+			fragmentTuningValue = saved.ConcurrentFragments
+		}
+	}
+	return fragmentTuningValue
+}
+
+// updateTunedFragments applies the heuristic to a finished download's speed,
+// logging and saving a changed suggestion
+func updateTunedFragments(used int, speed float64) {
+	suggested := suggestFragments(used, speed)
+
+	fragmentTuningMutex.Lock()
+	defer fragmentTuningMutex.Unlock()
+	if suggested == fragmentTuningValue {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[gostreampuller] Adaptive fragments: %.1f MiB/s with %d concurrent fragments, using %d next\n",
+		speed/(1024*1024), used, suggested)
+	fragmentTuningValue = suggested
+	writeFragmentTuning(&fragmentTuning{ConcurrentFragments: suggested, UpdatedAt: time.Now().UTC()})
+}
+
+// fragmentTuning is the saved result of adaptive fragment tuning
+type fragmentTuning struct {
+	ConcurrentFragments int       `json:"concurrent_fragments"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// fragmentTuningPath returns ~/.gostreampuller/fragment_tuning.json
+func fragmentTuningPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".gostreampuller", "fragment_tuning.json"), nil
+}
+
+// readFragmentTuning loads the saved tuning, returning nil if it is missing or invalid
+func readFragmentTuning() *fragmentTuning {
+	path, err := fragmentTuningPath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var tuning fragmentTuning
+	if err := json.Unmarshal(data, &tuning); err != nil {
+		return nil
+	}
+	return &tuning
+}
+
+// writeFragmentTuning saves the tuning; failures only lose the suggestion
+func writeFragmentTuning(tuning *fragmentTuning) {
+	path, err := fragmentTuningPath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(tuning)
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,121 @@
+package downloader
+
+import (
+	"context"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		line string
+		want float64
+		ok   bool
+	}{
+		{"[download]  45.3% of ~ 12.34MiB at  1.23MiB/s ETA 00:10", 1.23 * 1024 * 1024, true},
+		{"[download]   1.0% of 1.00GiB at 512.00KiB/s ETA 30:00", 512 * 1024, true},
+		{"[download]  10.0% of 10.00MB at 2.5MB/s ETA 00:04", 2.5 * 1000 * 1000, true},
+		{"[download]  10.0% of 100B at 37B/s ETA 00:02", 37, true},
+		{"[download]  10.0% of 10.00MiB at Unknown B/s ETA Unknown", 0, false},
+		{"[download] Destination: video.mp4", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSpeed(tt.line)
+		if ok != tt.ok || math.Abs(got-tt.want) > 0.5 {
+			t.Errorf("parseSpeed(%q) = %v, %v; want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSuggestFragments(t *testing.T) {
+	const mib = 1024 * 1024
+	tests := []struct {
+		current int
+		speed   float64
+		want    int
+	}{
+		// 1 MiB/s or more per fragment: double
+		{3, 3 * mib, 6},
+		{4, 40 * mib, 8},
+		{12, 100 * mib, maxConcurrentFragments},
+		{maxConcurrentFragments, 100 * mib, maxConcurrentFragments},
+		// Between the bounds: keep
+		{3, 1.5 * mib, 3},
+		{8, 2 * mib, 8},
+		// Under 128 KiB/s per fragment: halve
+		{8, 512 * 1024, 4},
+		{3, 200 * 1024, 1},
+		{1, 10 * 1024, 1},
+		// A nonsensical current value is treated as 1
+		{0, 2 * mib, 2},
+	}
+	for _, tt := range tests {
+		if got := suggestFragments(tt.current, tt.speed); got != tt.want {
+			t.Errorf("suggestFragments(%d, %.0f) = %d, want %d", tt.current, tt.speed, got, tt.want)
+		}
+	}
+}
+
+// resetFragmentTuning gives the test a fresh tuning state and home directory
+func resetFragmentTuning(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	setForTest(t, &AdaptiveFragments, true)
+	setForTest(t, &ConcurrentFragments, 3)
+	setForTest(t, &fragmentTuningValue, 0)
+}
+
+func TestAdaptiveFragmentsTunesAndPersists(t *testing.T) {
+	resetFragmentTuning(t)
+	logLine, calls := argsLog(t)
+	progress := strings.Repeat("echo \"[download]  50.0% of 100.00MiB at 6.00MiB/s ETA 00:08\"\n", minSpeedSamples)
+	useFakeYTDLP(t, logLine+"\n"+progress+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+
+	for i, want := range []string{"3", "6"} {
+		if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()}); err != nil {
+			t.Fatal(err)
+		}
+		if got := lastArgValue(calls()[i], "--concurrent-fragments"); got != want {
+			t.Errorf("download %d used %s concurrent fragments, want %s", i, got, want)
+		}
+	}
+
+	saved := readFragmentTuning()
+	if saved == nil || saved.ConcurrentFragments != 12 {
+		t.Fatalf("saved tuning = %+v, want 12", saved)
+	}
+	// A new process starts from the saved value
+	fragmentTuningValue = 0
+	if got := tunedFragments(); got != 12 {
+		t.Errorf("tunedFragments after restart = %d, want 12", got)
+	}
+}
+
+func TestAdaptiveFragmentsIgnoresShortDownloads(t *testing.T) {
+	resetFragmentTuning(t)
+	useFakeYTDLP(t, `echo "[download] 100.0% of 1.00MiB at 50.00MiB/s ETA 00:00"
+`+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	if got := tunedFragments(); got != 3 {
+		t.Errorf("tuned to %d from a single sample, want 3", got)
+	}
+	path, _ := fragmentTuningPath()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("tuning saved from a single sample: %v", err)
+	}
+}
+
+func TestTunedFragmentsRejectsInvalidSave(t *testing.T) {
+	resetFragmentTuning(t)
+	writeFragmentTuning(&fragmentTuning{ConcurrentFragments: maxConcurrentFragments + 1})
+	if got := tunedFragments(); got != 3 {
+		t.Errorf("tunedFragments = %d, want ConcurrentFragments for an out of range save", got)
+	}
+}
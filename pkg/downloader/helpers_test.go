@@ -0,0 +1,166 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// setForTest sets *p to v until the test ends
+func setForTest[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// noAutoInstall marks both binaries as already checked, so the test never
+// installs or updates them
+func noAutoInstall(t *testing.T) {
+	t.Helper()
+	t.Setenv("GOSTREAMPULLER_NO_AUTO_INSTALL", "1")
+	installMutex.Lock()
+	defer installMutex.Unlock()
+	setForTest(t, &ytdlpInstallAttempted, true)
+	setForTest(t, &ffmpegInstallAttempted, true)
+}
+
+// fakeBinary writes script as an executable shell script called name in a
+// temporary directory and returns its path. Skips the test on Windows.
+func fakeBinary(t *testing.T, name string, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake binaries are shell scripts")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// useFakeYTDLP runs script as yt-dlp for the rest of the test
+func useFakeYTDLP(t *testing.T, script string) string {
+	t.Helper()
+	noAutoInstall(t)
+	path := fakeBinary(t, "yt-dlp", script)
+	setForTest(t, &YTDLPPath, path)
+	return path
+}
+
+// useFakeFFMPEG runs script as ffmpeg for the rest of the test. ffprobe, if
+// given, is placed next to it so ffprobePath finds it.
+func useFakeFFMPEG(t *testing.T, script string, ffprobe ...string) string {
+	t.Helper()
+	noAutoInstall(t)
+	path := fakeBinary(t, "ffmpeg", script)
+	if len(ffprobe) > 0 {
+		probe := filepath.Join(filepath.Dir(path), "ffprobe")
+		if err := os.WriteFile(probe, []byte("#!/bin/sh\n"+ffprobe[0]+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setForTest(t, &FFMPEGPath, path)
+	return path
+}
+
+// withoutFFMPEG makes ffmpeg unavailable for the rest of the test
+func withoutFFMPEG(t *testing.T) {
+	t.Helper()
+	noAutoInstall(t)
+	setForTest(t, &FFMPEGPath, filepath.Join(t.TempDir(), "missing", "ffmpeg"))
+}
+
+// argsLog returns a script line recording the arguments of every call and a
+// function returning them, one slice per call
+func argsLog(t *testing.T) (string, func() [][]string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "args.log")
+	script := `printf '%s\n' "$@" "--end--" >> '` + path + `'`
+	return script, func() [][]string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var calls [][]string
+		var call []string
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if line == "--end--" {
+				calls = append(calls, call)
+				call = nil
+				continue
+			}
+			call = append(call, line)
+		}
+		return calls
+	}
+}
+
+// writeOutput is a script snippet creating the file named by yt-dlp's -o
+// template, with %(ext)s replaced by ext
+func writeOutput(ext string) string {
+	return `out=""; prev=""
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+[ -n "$out" ] && printf 'media' > "$(printf '%s' "$out" | sed 's/%(ext)s/` + ext + `/')"`
+}
+
+// writeLastArg is a script snippet creating the file named by the last
+// argument, where ffmpeg takes its output
+const writeLastArg = `for a in "$@"; do last="$a"; done
+printf 'converted' > "$last"`
+
+// argValue returns the value following flag in args, or ""
+func argValue(args []string, flag string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// hasArgs reports whether want appears in args as a contiguous sequence
+func hasArgs(args []string, want ...string) bool {
+	for i := 0; i+len(want) <= len(args); i++ {
+		match := true
+		for j := range want {
+			if args[i+j] != want[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleEncoders is an excerpt of `ffmpeg -hide_banner -encoders` output
+const sampleEncoders = `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D libvpx-vp9           libvpx VP9 (codec vp9)
+ V....D mpeg4                MPEG-4 part 2
+ V....D mjpeg                MJPEG (Motion JPEG)
+ A....D aac                  AAC (Advanced Audio Coding)
+ A....D libmp3lame           libmp3lame MP3 (MPEG audio layer 3) (codec mp3)
+ A....D libopus              libopus Opus (codec opus)
+ A....D flac                 FLAC (Free Lossless Audio Codec)
+ A....D pcm_s16le            PCM signed 16-bit little-endian
+`
+
+// ffmpegScript is a fake ffmpeg answering -encoders with sampleEncoders and
+// running then for every other invocation
+func ffmpegScript(then string) string {
+	return `if [ "$2" = "-encoders" ]; then
+cat <<'ENCODERS'
+` + sampleEncoders + `ENCODERS
+exit 0
+fi
+` + then
+}
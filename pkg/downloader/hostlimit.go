@@ -0,0 +1,125 @@
+package downloader
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	// MaxConcurrentPerHost limits simultaneous downloads from the same host
+	// (default: 0, unlimited). Can be set using SetMaxConcurrentPerHost()
+	MaxConcurrentPerHost = 0
+
+	hostSemaphores   = make(map[string]*hostSemaphore)
+	hostSemaphoresMu sync.Mutex
+)
+
+// hostSemaphore counts the downloads running against one host and queues the
+// ones waiting for a slot. Its capacity is MaxConcurrentPerHost at the time a
+// slot is taken or freed, so a new limit also applies to running downloads.
+// Guarded by hostSemaphoresMu.
+type hostSemaphore struct {
+	active  int
+	waiters []chan struct{} // Closed when the waiting download is granted a slot
+}
+
+// grant hands free slots to waiting downloads in arrival order
+func (s *hostSemaphore) grant() {
+	for len(s.waiters) > 0 && (MaxConcurrentPerHost == 0 || s.active < MaxConcurrentPerHost) {
+		s.active++
+		close(s.waiters[0])
+		s.waiters = s.waiters[1:]
+	}
+}
+
+// SetMaxConcurrentPerHost limits how many downloads may run at once against a
+// single host, which is gentler on rate-limited platforms during playlist and
+// batch downloads. Downloads over the limit wait for a slot. 0 removes the limit.
+func SetMaxConcurrentPerHost(max int) {
+	if max < 0 {
+		return
+	}
+
+	hostSemaphoresMu.Lock()
+	defer hostSemaphoresMu.Unlock()
+	MaxConcurrentPerHost = max
+	// Running downloads keep their slots; a higher limit lets waiting ones
+	// start now, a lower one only takes effect as slots are freed
+	for _, sem := range hostSemaphores {
+		sem.grant()
+	}
+}
+
+// hostAliases maps alternate hostnames to the site they belong to
+var hostAliases = map[string]string{
+	"youtu.be":          "youtube.com",
+	"m.youtube.com":     "youtube.com",
+	"music.youtube.com": "youtube.com",
+}
+
+// hostKey returns the normalized host of rawURL used for per-host limits
+func hostKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if alias, ok := hostAliases[host]; ok {
+		return alias
+	}
+	return host
+}
+
+// acquireHostSlot blocks until a download slot for rawURL's host is free.
+// The returned function releases the slot and must always be called.
+func acquireHostSlot(ctx context.Context, rawURL string) (func(), error) {
+	hostSemaphoresMu.Lock()
+	key := hostKey(rawURL)
+	if MaxConcurrentPerHost == 0 || key == "" {
+		hostSemaphoresMu.Unlock()
+		return func() {}, nil
+	}
+
+	sem, ok := hostSemaphores[key]
+	if !ok {
+		sem = &hostSemaphore{}
+		hostSemaphores[key] = sem
+	}
+	release := func() {
+		hostSemaphoresMu.Lock()
+		defer hostSemaphoresMu.Unlock()
+		sem.active--
+		sem.grant()
+		if sem.active == 0 && len(sem.waiters) == 0 && hostSemaphores[key] == sem {
+			delete(hostSemaphores, key)
+		}
+	}
+	if sem.active < MaxConcurrentPerHost {
+		sem.active++
+		hostSemaphoresMu.Unlock()
+		return release, nil
+	}
+	wake := make(chan struct{})
+	sem.waiters = append(sem.waiters, wake)
+	hostSemaphoresMu.Unlock()
+
+	select {
+	case <-wake:
+		return release, nil
+	case <-ctx.Done():
+		hostSemaphoresMu.Lock()
+		if i := slices.Index(sem.waiters, wake); i >= 0 {
+			sem.waiters = slices.Delete(sem.waiters, i, i+1)
+			hostSemaphoresMu.Unlock()
+			return func() {}, ctx.Err()
+		}
+		hostSemaphoresMu.Unlock()
+		// A slot was granted while giving up; pass it on
+		release()
+		return func() {}, ctx.Err()
+	}
+}
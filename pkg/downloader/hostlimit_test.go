@@ -0,0 +1,126 @@
+package downloader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostKey(t *testing.T) {
+	tests := map[string]string{
+		"https://www.youtube.com/watch?v=x": "youtube.com",
+		"https://youtu.be/x":                "youtube.com",
+		"https://music.youtube.com/watch":   "youtube.com",
+		"https://VIMEO.com/123":             "vimeo.com",
+		"not a url":                         "",
+	}
+	for rawURL, want := range tests {
+		if got := hostKey(rawURL); got != want {
+			t.Errorf("hostKey(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestAcquireHostSlotBound(t *testing.T) {
+	setForTest(t, &MaxConcurrentPerHost, 2)
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := acquireHostSlot(context.Background(), "https://youtu.be/x")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if peak.Load() != 2 {
+		t.Errorf("peak concurrency %d, want 2", peak.Load())
+	}
+
+	// Other hosts have their own slots
+	release, err := acquireHostSlot(context.Background(), "https://vimeo.com/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	hostSemaphoresMu.Lock()
+	defer hostSemaphoresMu.Unlock()
+	if len(hostSemaphores) != 0 {
+		t.Errorf("idle semaphores kept: %v", hostSemaphores)
+	}
+}
+
+func TestAcquireHostSlotCancelled(t *testing.T) {
+	setForTest(t, &MaxConcurrentPerHost, 1)
+	release, _ := acquireHostSlot(context.Background(), "https://youtu.be/x")
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := acquireHostSlot(ctx, "https://youtu.be/x"); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestSetMaxConcurrentPerHostResizesInPlace(t *testing.T) {
+	setForTest(t, &MaxConcurrentPerHost, 0)
+	SetMaxConcurrentPerHost(1)
+	held, _ := acquireHostSlot(context.Background(), "https://youtu.be/x")
+
+	acquired := make(chan func())
+	go func() {
+		release, _ := acquireHostSlot(context.Background(), "https://youtu.be/x")
+		acquired <- release
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second download started over the limit of 1")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Raising the limit starts the waiting download while the first still runs
+	SetMaxConcurrentPerHost(2)
+	var second func()
+	select {
+	case second = <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("raising the limit did not start the waiting download")
+	}
+
+	// Lowering it keeps both running, but new downloads wait until one has finished
+	SetMaxConcurrentPerHost(1)
+	go func() {
+		release, _ := acquireHostSlot(context.Background(), "https://youtu.be/x")
+		acquired <- release
+	}()
+	held()
+	select {
+	case <-acquired:
+		t.Fatal("download started with 1 running at a limit of 1")
+	case <-time.After(20 * time.Millisecond):
+	}
+	second()
+	select {
+	case third := <-acquired:
+		third()
+	case <-time.After(time.Second):
+		t.Fatal("download did not start once the slot was free")
+	}
+}
@@ -0,0 +1,15 @@
+package downloader
+
+import (
+	"net/http"
+
+	"youtube-api-server/pkg/internal/installer"
+)
+
+// SetHTTPClient replaces the HTTP client used for binary downloads, yt-dlp
+// updates and thumbnail fetches, to configure proxies, timeouts or TLS in one
+// place. Keys pinned with SetInstallerPins are still enforced unless the
+// client's transport sets its own VerifyConnection. nil restores the default.
+func SetHTTPClient(client *http.Client) {
+	installer.SetHTTPClient(client)
+}
@@ -0,0 +1,38 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc serves requests without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSetHTTPClientUsedForThumbnails(t *testing.T) {
+	var requested []string
+	SetHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("jpeg")), Request: req}, nil
+	})})
+	t.Cleanup(func() { SetHTTPClient(nil) })
+
+	dst := filepath.Join(t.TempDir(), "aaaaaaaaaaa.jpg")
+	if err := fetchThumbnail(context.Background(), "https://i.ytimg.com/vi/aaaaaaaaaaa/hqdefault.jpg", dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 1 {
+		t.Errorf("custom client requests %v", requested)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "jpeg" {
+		t.Errorf("wrote %q", data)
+	}
+}
@@ -0,0 +1,26 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// infoJSONFormats are the containers yt-dlp can embed the info json into
+// (as a Matroska attachment)
+var infoJSONFormats = map[string]bool{
+	"mkv": true,
+}
+
+// validateInfoJSONFormat returns an error if format cannot hold an embedded info json
+func validateInfoJSONFormat(format string) error {
+	if !infoJSONFormats[strings.ToLower(format)] {
+		return fmt.Errorf("info json cannot be embedded in %q (use mkv)", format)
+	}
+	return nil
+}
+
+// infoJSONArgs are the yt-dlp flags embedding the info json. The download is
+// remuxed to mkv first: our ffmpeg conversion would drop the attachment.
+func infoJSONArgs() []string {
+	return []string{"--remux-video", "mkv", "--embed-info-json"}
+}
@@ -0,0 +1,49 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValidateInfoJSONFormat(t *testing.T) {
+	for _, format := range []string{"mkv", "MKV"} {
+		if err := validateInfoJSONFormat(format); err != nil {
+			t.Errorf("validateInfoJSONFormat(%q) = %v", format, err)
+		}
+	}
+	for _, format := range []string{"mp4", "webm", "mov", ""} {
+		if err := validateInfoJSONFormat(format); err == nil {
+			t.Errorf("validateInfoJSONFormat(%q) accepted", format)
+		}
+	}
+}
+
+func TestDownloadEmbedInfoJSON(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("mkv"))
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg))
+
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:        "mkv",
+		OutputDir:     t.TempDir(),
+		EmbedInfoJSON: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if args := calls()[0]; !hasArgs(args, "--remux-video", "mkv", "--embed-info-json") {
+		t.Errorf("info json flags not passed: %v", args)
+	}
+
+	// An unsupported container is rejected before downloading
+	downloads := len(calls())
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:        "mp4",
+		OutputDir:     t.TempDir(),
+		EmbedInfoJSON: true,
+	}); err == nil {
+		t.Error("EmbedInfoJSON accepted for mp4")
+	}
+	if len(calls()) != downloads {
+		t.Error("downloaded before rejecting the container")
+	}
+}
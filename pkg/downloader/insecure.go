@@ -0,0 +1,39 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+
+	"youtube-api-server/pkg/internal/installer"
+)
+
+// InsecureSkipTLSVerify makes yt-dlp skip certificate verification
+// (--no-check-certificates), for self-hosted sites with broken TLS.
+// Default: false. Can be set using SetInsecureSkipTLSVerify()
+var InsecureSkipTLSVerify = false
+
+// SetInsecureSkipTLSVerify turns off certificate verification for yt-dlp, so
+// sites with self-signed or misconfigured certificates can be downloaded from.
+// This exposes every request to interception; only enable it for sites you
+// trust on networks you control. If includeInstaller is set, binary downloads
+// through the default client skip verification too; a client installed with
+// SetHTTPClient keeps its own TLS settings. Keys pinned with SetInstallerPins
+// are still checked, but only against the leaf certificate.
+func SetInsecureSkipTLSVerify(enabled bool, includeInstaller bool) {
+	if enabled {
+		fmt.Fprintln(os.Stderr, "[gostreampuller] ⚠ Warning: TLS certificate verification is DISABLED for yt-dlp; connections can be intercepted")
+		if includeInstaller {
+			fmt.Fprintln(os.Stderr, "[gostreampuller] ⚠ Warning: TLS certificate verification is DISABLED for binary downloads; installed binaries can be tampered with")
+		}
+	}
+	InsecureSkipTLSVerify = enabled
+	installer.SetInsecureSkipVerify(enabled && includeInstaller)
+}
+
+// tlsArgs returns the yt-dlp flags for the configured TLS verification
+func tlsArgs() []string {
+	if InsecureSkipTLSVerify {
+		return []string{"--no-check-certificates"}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSetInsecureSkipTLSVerify(t *testing.T) {
+	SetInsecureSkipTLSVerify(false, false)
+	t.Cleanup(func() { SetInsecureSkipTLSVerify(false, false) })
+
+	if args := tlsArgs(); args != nil {
+		t.Errorf("tlsArgs() = %v by default", args)
+	}
+	SetInsecureSkipTLSVerify(true, false)
+	if args := tlsArgs(); !slices.Equal(args, []string{"--no-check-certificates"}) {
+		t.Errorf("tlsArgs() = %v when enabled", args)
+	}
+	args := ytdlpCommand(context.Background(), "https://youtu.be/x").Args
+	if !slices.Contains(args, "--no-check-certificates") || args[len(args)-1] != "https://youtu.be/x" {
+		t.Errorf("yt-dlp args %v, want --no-check-certificates before the URL", args)
+	}
+}
+
+func TestMetadataChecksCertificatesByDefault(t *testing.T) {
+	SetInsecureSkipTLSVerify(false, false)
+	t.Cleanup(func() { SetInsecureSkipTLSVerify(false, false) })
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+echo '{"id": "aaaaaaaaaaa", "title": "Video"}'`)
+
+	if _, err := GetVideoMetadataWithContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa"); err != nil {
+		t.Fatal(err)
+	}
+	for _, flag := range []string{"--no-check-certificate", "--no-check-certificates"} {
+		if slices.Contains(calls()[0], flag) {
+			t.Errorf("%s passed by default: %v", flag, calls()[0])
+		}
+	}
+
+	SetInsecureSkipTLSVerify(true, false)
+	if _, err := GetVideoMetadataWithContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(calls()[1], "--no-check-certificates") {
+		t.Errorf("--no-check-certificates not passed when enabled: %v", calls()[1])
+	}
+}
@@ -0,0 +1,56 @@
+package downloader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MetadataLanguage is the language YouTube is asked to localize titles and
+// descriptions in (e.g. "de", "pt-BR"). Empty uses YouTube's default.
+// Can be set using SetMetadataLanguage()
+var MetadataLanguage string
+
+// languagePattern matches the language codes YouTube accepts: a language,
+// optionally followed by a region ("en-GB", "es-419") or script ("sr-Latn")
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-([A-Z]{2}|[0-9]{3}|[A-Z][a-z]{3}))?$`)
+
+// SetMetadataLanguage sets the language for localized metadata.
+// An empty lang restores YouTube's default.
+//
+// Example:
+//
+//	downloader.SetMetadataLanguage("de")
+func SetMetadataLanguage(lang string) error {
+	if lang != "" && !languagePattern.MatchString(lang) {
+		return fmt.Errorf("invalid language code %q (expected e.g. \"en\", \"pt-BR\")", lang)
+	}
+	MetadataLanguage = lang
+	return nil
+}
+
+// youtubeExtractorArgs returns the value of --extractor-args for the YouTube
+// extractor, combining the player client (if any) with MetadataLanguage.
+// Returns "" if there is nothing to set.
+func youtubeExtractorArgs(client string) string {
+	var parts []string
+	if client != "" {
+		parts = append(parts, "player_client="+client)
+	}
+	if MetadataLanguage != "" {
+		parts = append(parts, "lang="+MetadataLanguage)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "youtube:" + strings.Join(parts, ";")
+}
+
+// metadataLanguageArgs returns the yt-dlp flags selecting MetadataLanguage
+// for commands that don't pick a player client
+func metadataLanguageArgs() []string {
+	if extractorArgs := youtubeExtractorArgs(""); extractorArgs != "" {
+		return []string{"--extractor-args", extractorArgs}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSetMetadataLanguage(t *testing.T) {
+	setForTest(t, &MetadataLanguage, "")
+	for _, lang := range []string{"de", "pt-BR", "es-419", "sr-Latn", "fil", ""} {
+		if err := SetMetadataLanguage(lang); err != nil || MetadataLanguage != lang {
+			t.Errorf("SetMetadataLanguage(%q): err %v, MetadataLanguage %q", lang, err, MetadataLanguage)
+		}
+	}
+	for _, lang := range []string{"DE", "english", "pt_BR", "de-", "en-gb", "de;lang=fr", "d"} {
+		if err := SetMetadataLanguage(lang); err == nil {
+			t.Errorf("SetMetadataLanguage(%q) accepted", lang)
+		}
+	}
+}
+
+func TestYoutubeExtractorArgs(t *testing.T) {
+	tests := []struct {
+		client, lang string
+		want         string
+	}{
+		{"", "", ""},
+		{"android", "", "youtube:player_client=android"},
+		{"", "de", "youtube:lang=de"},
+		{"web", "pt-BR", "youtube:player_client=web;lang=pt-BR"},
+	}
+	for _, tt := range tests {
+		setForTest(t, &MetadataLanguage, tt.lang)
+		if got := youtubeExtractorArgs(tt.client); got != tt.want {
+			t.Errorf("youtubeExtractorArgs(%q) with lang %q = %q, want %q", tt.client, tt.lang, got, tt.want)
+		}
+	}
+
+	setForTest(t, &MetadataLanguage, "")
+	if args := metadataLanguageArgs(); args != nil {
+		t.Errorf("metadataLanguageArgs() = %v without a language", args)
+	}
+	setForTest(t, &MetadataLanguage, "fr")
+	if args := metadataLanguageArgs(); !slices.Equal(args, []string{"--extractor-args", "youtube:lang=fr"}) {
+		t.Errorf("metadataLanguageArgs() = %v", args)
+	}
+}
+
+func TestMetadataLanguageOnMetadataCommand(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+echo '{"id": "aaaaaaaaaaa", "title": "Titel"}'`)
+	setForTest(t, &MetadataLanguage, "de")
+
+	if _, err := GetVideoMetadataWithContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa"); err != nil {
+		t.Fatal(err)
+	}
+	args := calls()[0]
+	if value := argValue(args, "--extractor-args"); value != "youtube:player_client="+playerClients[0]+";lang=de" {
+		t.Errorf("--extractor-args %q, want the client and lang=de", value)
+	}
+}
@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Manifest statuses of a playlist item
+const (
+	ManifestSucceeded = "succeeded"
+	ManifestFailed    = "failed"
+	ManifestSkipped   = "skipped"
+)
+
+// ManifestEntry is one playlist item in a download manifest
+type ManifestEntry struct {
+	Index  int    `json:"index"` // 1-based position in the playlist
+	URL    string `json:"url"`
+	Status string `json:"status"`           // ManifestSucceeded, ManifestFailed or ManifestSkipped
+	Path   string `json:"path,omitempty"`   // Absolute path of the downloaded file
+	Size   int64  `json:"size,omitempty"`   // File size in bytes
+	Format string `json:"format,omitempty"` // File extension, e.g. "mp4"
+	Error  string `json:"error,omitempty"`  // Failure message, or the filter that skipped the item
+}
+
+// Manifest is the JSON document written to ManifestPath after a playlist download
+type Manifest struct {
+	Title       string          `json:"title"`
+	URL         string          `json:"url"`
+	CompletedAt time.Time       `json:"completed_at"`
+	Items       []ManifestEntry `json:"items"` // In playlist order
+}
+
+// writeManifest writes the manifest of a playlist download to path. It is
+// written to a partial sibling first and renamed into place, so readers never
+// see a truncated manifest.
+func writeManifest(path string, url string, result *PlaylistResult) error {
+	manifest := Manifest{
+		Title:       result.Title,
+		URL:         url,
+		CompletedAt: time.Now().UTC(),
+		Items:       make([]ManifestEntry, len(result.entries)),
+	}
+	for i, entry := range result.entries {
+		if entry.Path != "" {
+			if stat, err := os.Stat(entry.Path); err == nil {
+				entry.Size = stat.Size()
+			}
+			entry.Format = strings.TrimPrefix(filepath.Ext(entry.Path), ".")
+		}
+		manifest.Items[i] = entry
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := makeOutputDir(dir); err != nil {
+			return fmt.Errorf("failed to create manifest directory: %w", err)
+		}
+	}
+
+	partial := partialPath(path)
+	if err := os.WriteFile(partial, data, 0644); err != nil {
+		os.Remove(partial)
+		return diskFullError(fmt.Errorf("failed to write manifest: %w", err))
+	}
+	return commitPartial(partial, path)
+}
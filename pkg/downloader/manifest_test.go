@@ -0,0 +1,122 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readManifest decodes the manifest at path
+func readManifest(t *testing.T, path string) Manifest {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v\n%s", err, data)
+	}
+	return manifest
+}
+
+func TestDownloadPlaylistManifest(t *testing.T) {
+	logLine, _ := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, `case "$url" in
+*bbbbbbbbbbb) echo "[download] Second does not pass filter (duration < 600), skipping .."; exit 0;;
+*ccccccccccc) echo "ERROR: [youtube] ccccccccccc: Private video" >&2; exit 1;;
+esac
+`+writeOutput("mp4")))
+	useFakeFFMPEG(t, "exit 0")
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "reports", "manifest.json")
+
+	result, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: dir},
+		ManifestPath: manifestPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := readManifest(t, manifestPath)
+	if manifest.Title != "Mix" || manifest.URL != "https://www.youtube.com/playlist?list=PL1" || manifest.CompletedAt.IsZero() {
+		t.Errorf("manifest header = %+v", manifest)
+	}
+	if len(manifest.Items) != 3 {
+		t.Fatalf("items = %+v, want 3", manifest.Items)
+	}
+
+	succeeded, skipped, failed := manifest.Items[0], manifest.Items[1], manifest.Items[2]
+	if succeeded.Index != 1 || succeeded.Status != ManifestSucceeded || succeeded.URL != "https://www.youtube.com/watch?v=aaaaaaaaaaa" ||
+		succeeded.Path != result.Succeeded[0] || succeeded.Size != int64(len("media")) || succeeded.Format != "mp4" || succeeded.Error != "" {
+		t.Errorf("succeeded item = %+v", succeeded)
+	}
+	if skipped.Index != 2 || skipped.Status != ManifestSkipped || skipped.Error != "duration < 600" || skipped.Path != "" {
+		t.Errorf("skipped item = %+v", skipped)
+	}
+	if failed.Index != 3 || failed.Status != ManifestFailed || !strings.Contains(failed.Error, "Private video") || failed.Path != "" || failed.Size != 0 {
+		t.Errorf("failed item = %+v", failed)
+	}
+
+	// Written atomically: no partial file is left next to it
+	if entries, _ := os.ReadDir(filepath.Dir(manifestPath)); len(entries) != 1 {
+		t.Errorf("manifest directory holds %v", entries)
+	}
+}
+
+func TestDownloadBatchManifest(t *testing.T) {
+	useFakeYTDLP(t, `for a in "$@"; do url="$a"; done
+case "$url" in
+*bbbbbbbbbbb) echo "ERROR: [youtube] bbbbbbbbbbb: Video unavailable" >&2; exit 1;;
+esac
+`+writeOutput("webm"))
+	useFakeFFMPEG(t, "exit 0")
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest.json")
+	urls := []string{"https://www.youtube.com/watch?v=aaaaaaaaaaa", "https://www.youtube.com/watch?v=bbbbbbbbbbb"}
+
+	if _, err := DownloadBatch(context.Background(), urls, BatchOptions{
+		VideoOptions: VideoOptions{Format: "webm", OutputDir: dir},
+		ManifestPath: manifestPath,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := readManifest(t, manifestPath)
+	if len(manifest.Items) != 2 {
+		t.Fatalf("items = %+v, want 2", manifest.Items)
+	}
+	if item := manifest.Items[0]; item.URL != urls[0] || item.Status != ManifestSucceeded || item.Format != "webm" || item.Size == 0 {
+		t.Errorf("first item = %+v", item)
+	}
+	if item := manifest.Items[1]; item.URL != urls[1] || item.Status != ManifestFailed || item.Error == "" {
+		t.Errorf("second item = %+v", item)
+	}
+}
+
+func TestDownloadPlaylistManifestWhenAllFail(t *testing.T) {
+	logLine, _ := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, `echo "ERROR: [youtube] x: Private video" >&2; exit 1`))
+	useFakeFFMPEG(t, "exit 0")
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+
+	if _, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: t.TempDir()},
+		ManifestPath: manifestPath,
+	}); err == nil {
+		t.Fatal("playlist of failures succeeded")
+	}
+	manifest := readManifest(t, manifestPath)
+	for _, item := range manifest.Items {
+		if item.Status != ManifestFailed {
+			t.Errorf("item = %+v, want failed", item)
+		}
+	}
+	if len(manifest.Items) != 3 {
+		t.Errorf("items = %+v, want 3", manifest.Items)
+	}
+}
@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxShortDuration is the longest a YouTube Short can be, in seconds
+const maxShortDuration = 180
+
+// computeDerivedFields fills the convenience fields of VideoMetadata from Raw
+// so callers don't have to dig into the raw yt-dlp JSON
+func (m *VideoMetadata) computeDerivedFields() {
+	m.HasSubtitles = len(m.Subtitles) > 0
+	m.ChapterCount = len(rawList(m.Raw, "chapters"))
+	m.HasChapters = m.ChapterCount > 0
+	m.HasHeatmap = len(rawList(m.Raw, "heatmap")) > 0
+	m.IsUpcoming = m.LiveStatus == "is_upcoming"
+	m.IsMembersOnly = m.Availability == "subscriber_only" || m.Availability == "premium_only"
+	if m.ReleaseTimestamp > 0 {
+		scheduled := time.Unix(m.ReleaseTimestamp, 0)
+		m.ScheduledAt = &scheduled
+	}
+	m.SecondsUntilStart = int64(m.TimeUntilStart().Round(time.Second) / time.Second)
+
+	// Shorts are short portrait videos, or served from a /shorts/ URL
+	m.IsShort = strings.Contains(m.WebpageURL, "/shorts/") ||
+		(m.Duration > 0 && m.Duration <= maxShortDuration && m.Height > m.Width && m.Width > 0)
+
+	languages := make(map[string]bool)
+	for _, item := range rawList(m.Raw, "formats") {
+		format, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if acodec, _ := format["acodec"].(string); acodec == "" || acodec == "none" {
+			continue
+		}
+		if language, _ := format["language"].(string); language != "" {
+			languages[language] = true
+		}
+	}
+
+	m.AvailableAudioLanguages = make([]string, 0, len(languages))
+	for language := range languages {
+		m.AvailableAudioLanguages = append(m.AvailableAudioLanguages, language)
+	}
+	sort.Strings(m.AvailableAudioLanguages)
+}
+
+// notYetAvailable decorates a download error that matched ErrNotYetAvailable
+// with the scheduled start time, looked up from the video's metadata
+func notYetAvailable(ctx context.Context, url string, err error) error {
+	if !errors.Is(err, ErrNotYetAvailable) {
+		return err
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	notYet := &NotYetAvailableError{Err: err}
+	if metadata, metaErr := GetVideoMetadataWithContext(lookupCtx, url); metaErr == nil {
+		notYet.ScheduledAt = metadata.ScheduledAt
+	}
+	return notYet
+}
+
+// rawList returns raw[key] as a list, or nil if it is missing or not a list
+func rawList(raw map[string]interface{}, key string) []interface{} {
+	list, _ := raw[key].([]interface{})
+	return list
+}
@@ -0,0 +1,211 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseMetadata decodes yt-dlp --dump-json output the way getVideoMetadata does
+func parseMetadata(t *testing.T, data string) *VideoMetadata {
+	t.Helper()
+	metadata := &VideoMetadata{}
+	if err := json.Unmarshal([]byte(data), &metadata.Raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(data), metadata); err != nil {
+		t.Fatal(err)
+	}
+	metadata.computeDerivedFields()
+	return metadata
+}
+
+func TestComputeDerivedFields(t *testing.T) {
+	metadata := parseMetadata(t, `{
+		"id": "aaaaaaaaaaa",
+		"duration": 45, "width": 1080, "height": 1920,
+		"webpage_url": "https://www.youtube.com/watch?v=aaaaaaaaaaa",
+		"subtitles": {"en": [{"ext": "vtt"}]},
+		"chapters": [{"title": "Intro"}, {"title": "Main"}, {"title": "Outro"}],
+		"heatmap": [{"start_time": 0, "value": 1}],
+		"formats": [
+			{"format_id": "140", "acodec": "mp4a.40.2", "vcodec": "none", "language": "en"},
+			{"format_id": "251-1", "acodec": "opus", "vcodec": "none", "language": "de"},
+			{"format_id": "137", "acodec": "none", "vcodec": "avc1", "language": "fr"},
+			{"format_id": "18", "acodec": "mp4a.40.2", "vcodec": "avc1", "language": "en"}
+		]
+	}`)
+
+	if !metadata.HasSubtitles || !metadata.HasChapters || metadata.ChapterCount != 3 || !metadata.HasHeatmap {
+		t.Errorf("subtitles/chapters/heatmap flags wrong: %+v", metadata)
+	}
+	if !metadata.IsShort {
+		t.Error("45s portrait video not detected as a Short")
+	}
+	// Video-only formats don't count as audio languages
+	if want := []string{"de", "en"}; !slices.Equal(metadata.AvailableAudioLanguages, want) {
+		t.Errorf("AvailableAudioLanguages = %v, want %v", metadata.AvailableAudioLanguages, want)
+	}
+}
+
+func TestComputeDerivedFieldsEmpty(t *testing.T) {
+	metadata := parseMetadata(t, `{"id": "aaaaaaaaaaa", "duration": 600, "width": 1920, "height": 1080}`)
+	if metadata.HasSubtitles || metadata.HasChapters || metadata.ChapterCount != 0 || metadata.HasHeatmap || metadata.IsShort {
+		t.Errorf("flags set without data: %+v", metadata)
+	}
+	if metadata.AvailableAudioLanguages == nil || len(metadata.AvailableAudioLanguages) != 0 {
+		t.Errorf("AvailableAudioLanguages = %#v, want an empty list", metadata.AvailableAudioLanguages)
+	}
+
+	shorts := parseMetadata(t, `{"id": "x", "webpage_url": "https://www.youtube.com/shorts/x"}`)
+	if !shorts.IsShort {
+		t.Error("/shorts/ URL not detected as a Short")
+	}
+}
+
+func TestComputeDerivedFieldsAvailability(t *testing.T) {
+	tests := []struct {
+		json        string
+		upcoming    bool
+		membersOnly bool
+		scheduled   int64
+	}{
+		{`{"live_status": "not_live", "availability": "public"}`, false, false, 0},
+		{`{"live_status": "is_upcoming", "availability": "public", "release_timestamp": 1893456000}`, true, false, 1893456000},
+		{`{"live_status": "is_upcoming"}`, true, false, 0},
+		{`{"live_status": "is_live", "availability": "unlisted"}`, false, false, 0},
+		{`{"live_status": "was_live", "availability": "subscriber_only", "release_timestamp": 1700000000}`, false, true, 1700000000},
+		{`{"availability": "premium_only"}`, false, true, 0},
+		{`{"availability": "needs_auth"}`, false, false, 0},
+	}
+	for _, tt := range tests {
+		metadata := parseMetadata(t, tt.json)
+		if metadata.IsUpcoming != tt.upcoming || metadata.IsMembersOnly != tt.membersOnly {
+			t.Errorf("%s: IsUpcoming %v, IsMembersOnly %v", tt.json, metadata.IsUpcoming, metadata.IsMembersOnly)
+		}
+		switch {
+		case tt.scheduled == 0 && metadata.ScheduledAt != nil:
+			t.Errorf("%s: ScheduledAt = %v, want nil", tt.json, metadata.ScheduledAt)
+		case tt.scheduled != 0 && (metadata.ScheduledAt == nil || metadata.ScheduledAt.Unix() != tt.scheduled):
+			t.Errorf("%s: ScheduledAt = %v, want %d", tt.json, metadata.ScheduledAt, tt.scheduled)
+		}
+	}
+}
+
+func TestDownloadUpcomingFailsFast(t *testing.T) {
+	useFakeYTDLP(t, `for a in "$@"; do [ "$a" = "--dump-json" ] && dump=1; done
+if [ -n "$dump" ]; then
+	echo '{"id": "aaaaaaaaaaa", "live_status": "is_upcoming", "release_timestamp": 1893456000}'
+	exit 0
+fi
+echo "ERROR: [youtube] aaaaaaaaaaa: Premieres in 3 hours" >&2
+exit 1`)
+	useFakeFFMPEG(t, ffmpegScript("exit 0"))
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if !errors.Is(err, ErrNotYetAvailable) {
+		t.Fatalf("err = %v, want ErrNotYetAvailable", err)
+	}
+	var notYet *NotYetAvailableError
+	if !errors.As(err, &notYet) || notYet.ScheduledAt == nil || notYet.ScheduledAt.Unix() != 1893456000 {
+		t.Fatalf("err = %v, want the scheduled start", err)
+	}
+	if !strings.Contains(err.Error(), "2030-01-01T00:00:00Z") {
+		t.Errorf("Error() = %q, want the scheduled time", err.Error())
+	}
+}
+
+func TestMetadataDoesNotRequireFFMPEG(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	useFakeYTDLP(t, `echo '{"id": "aaaaaaaaaaa", "title": "Video"}'`)
+	withoutFFMPEG(t)
+	// Neither binary has been checked yet
+	setForTest(t, &ytdlpInstallAttempted, false)
+	setForTest(t, &ffmpegInstallAttempted, false)
+
+	if _, err := GetVideoMetadataWithContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa"); err != nil {
+		t.Fatalf("metadata without ffmpeg: %v", err)
+	}
+	if !ytdlpInstallAttempted || ffmpegInstallAttempted {
+		t.Errorf("checked yt-dlp %v, ffmpeg %v; want only yt-dlp", ytdlpInstallAttempted, ffmpegInstallAttempted)
+	}
+}
+
+func TestEnsureYTDLPInstalledIgnoresMissingFFMPEG(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	useFakeYTDLP(t, "exit 0")
+	withoutFFMPEG(t)
+	// Auto-install enabled: a missing ffmpeg would be installed if it were checked
+	t.Setenv("GOSTREAMPULLER_NO_AUTO_INSTALL", "")
+	setForTest(t, &BinarySource, BinarySourceAuto)
+	setForTest(t, &ytdlpInstallAttempted, false)
+	setForTest(t, &ffmpegInstallAttempted, false)
+
+	if err := ensureYTDLPInstalled(); err != nil {
+		t.Fatal(err)
+	}
+	if ffmpegInstallAttempted {
+		t.Error("ffmpeg checked for a yt-dlp only operation")
+	}
+	if _, err := os.Stat(filepath.Join(home, ".gostreampuller", "bin")); !os.IsNotExist(err) {
+		t.Errorf("binaries installed: %v", err)
+	}
+}
+
+func TestComputeDerivedFieldsPremiere(t *testing.T) {
+	start := time.Now().Add(90 * time.Minute).Unix()
+	metadata := parseMetadata(t, `{
+		"id": "aaaaaaaaaaa",
+		"title": "Premiere",
+		"live_status": "is_upcoming",
+		"release_timestamp": `+strconv.FormatInt(start, 10)+`,
+		"availability": "public",
+		"formats": []
+	}`)
+
+	if !metadata.IsUpcoming || metadata.ReleaseTimestamp != start {
+		t.Errorf("IsUpcoming %v, ReleaseTimestamp %d", metadata.IsUpcoming, metadata.ReleaseTimestamp)
+	}
+	if metadata.ScheduledAt == nil || metadata.ScheduledAt.Unix() != start {
+		t.Errorf("ScheduledAt = %v, want %d", metadata.ScheduledAt, start)
+	}
+	if metadata.SecondsUntilStart < 85*60 || metadata.SecondsUntilStart > 90*60 {
+		t.Errorf("SecondsUntilStart = %d, want about 90 minutes", metadata.SecondsUntilStart)
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	if fields["scheduled_at"] == nil || fields["seconds_until_start"] == nil || fields["is_upcoming"] != true {
+		t.Errorf("countdown fields missing from JSON: %s", data)
+	}
+}
+
+func TestComputeDerivedFieldsPremiereStarted(t *testing.T) {
+	// A premiere already running or over has no countdown
+	for _, data := range []string{
+		`{"live_status": "is_live", "release_timestamp": 1700000000}`,
+		`{"live_status": "is_upcoming", "release_timestamp": 1700000000}`,
+		`{"live_status": "not_live"}`,
+	} {
+		metadata := parseMetadata(t, data)
+		if metadata.SecondsUntilStart != 0 {
+			t.Errorf("%s: SecondsUntilStart = %d, want 0", data, metadata.SecondsUntilStart)
+		}
+		out, _ := json.Marshal(metadata)
+		if strings.Contains(string(out), "seconds_until_start") {
+			t.Errorf("%s: seconds_until_start in JSON: %s", data, out)
+		}
+	}
+}
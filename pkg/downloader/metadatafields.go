@@ -0,0 +1,178 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// derivedMetadataFields are VideoMetadata fields computed locally rather than
+// reported by yt-dlp, so they can't be requested from GetVideoMetadataFields
+var derivedMetadataFields = map[string]bool{
+	"has_subtitles":             true,
+	"has_chapters":              true,
+	"chapter_count":             true,
+	"has_heatmap":               true,
+	"is_short":                  true,
+	"is_upcoming":               true,
+	"is_members_only":           true,
+	"available_audio_languages": true,
+	"scheduled_at":              true,
+	"seconds_until_start":       true,
+}
+
+// printableMetadataFields returns the yt-dlp field names VideoMetadata maps
+func printableMetadataFields() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(VideoMetadata{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && !derivedMetadataFields[name] {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// metadataPrintTemplate returns the yt-dlp --print template that outputs only
+// the given fields as a JSON object, e.g. "%(.{id,title})#j"
+func metadataPrintTemplate(fields []string) (string, error) {
+	if len(fields) == 0 {
+		return "", fmt.Errorf("at least one metadata field is required")
+	}
+	allowed := printableMetadataFields()
+	for _, field := range fields {
+		if !allowed[field] {
+			return "", fmt.Errorf("unknown metadata field: %q", field)
+		}
+	}
+	return "%(.{" + strings.Join(fields, ",") + "})#j", nil
+}
+
+// GetVideoMetadataFields fetches only the given metadata fields (yt-dlp JSON
+// names such as "id", "title", "duration", "thumbnail") using --print instead
+// of the full --dump-json object. Only the requested VideoMetadata fields are
+// populated; Raw holds just those fields and derived fields are left unset.
+//
+// Example:
+//
+//	metadata, err := downloader.GetVideoMetadataFields(ctx, url, "id", "title", "duration")
+func GetVideoMetadataFields(ctx context.Context, url string, fields ...string) (*VideoMetadata, error) {
+	template, err := metadataPrintTemplate(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Auto-install yt-dlp if needed (only happens once)
+	if err := ensureYTDLPInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	args := []string{
+		"--print", template,
+		"--skip-download",
+		"--no-playlist",
+		"--no-warnings",
+		"--ignore-no-formats-error", // Upcoming premieres have no formats yet
+		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"--referer", "https://www.youtube.com/",
+	}
+	args = append(args, metadataLanguageArgs()...)
+	cmd := ytdlpCommand(ctx, append(args, url)...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to fetch metadata: %w", newDownloadError(cmd, "metadata", string(exitErr.Stderr), err))
+		}
+		return nil, fmt.Errorf("failed to execute yt-dlp: %w", err)
+	}
+
+	metadata := &VideoMetadata{}
+	if err := json.Unmarshal(output, &metadata.Raw); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
+	}
+	if err := json.Unmarshal(output, metadata); err != nil {
+		return nil, fmt.Errorf("failed to map metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// BasicMetadata is the handful of fields list views need, see GetBasicMetadata
+type BasicMetadata struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Duration  int    `json:"duration"` // Seconds; 0 if unknown (e.g. live streams)
+	Thumbnail string `json:"thumbnail"`
+}
+
+// basicMetadataTemplate prints the BasicMetadata fields tab-separated on one line
+const basicMetadataTemplate = "%(id)s\t%(title)s\t%(duration)s\t%(thumbnail)s"
+
+// GetBasicMetadata fetches just the ID, title, duration and thumbnail of a
+// video. It prints the fields with yt-dlp's --print rather than dumping and
+// decoding the full metadata JSON, which makes it noticeably faster for
+// list views.
+func GetBasicMetadata(ctx context.Context, url string) (*BasicMetadata, error) {
+	// Auto-install yt-dlp if needed (only happens once)
+	if err := ensureYTDLPInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	args := []string{
+		"--print", basicMetadataTemplate,
+		"--skip-download",
+		"--no-playlist",
+		"--no-warnings",
+		"--ignore-no-formats-error", // Upcoming premieres have no formats yet
+		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"--referer", "https://www.youtube.com/",
+	}
+	args = append(args, metadataLanguageArgs()...)
+	cmd := ytdlpCommand(ctx, append(args, url)...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to fetch metadata: %w", newDownloadError(cmd, "metadata", string(exitErr.Stderr), err))
+		}
+		return nil, fmt.Errorf("failed to execute yt-dlp: %w", err)
+	}
+	return parseBasicMetadata(string(output))
+}
+
+// parseBasicMetadata parses the line printed for basicMetadataTemplate. yt-dlp
+// prints "NA" for missing fields. Titles may themselves contain tabs, so the
+// title is everything between the first field and the last two.
+func parseBasicMetadata(output string) (*BasicMetadata, error) {
+	line := strings.TrimRight(output, "\r\n")
+	fields := strings.Split(line, "\t")
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("unexpected metadata output: %q", line)
+	}
+
+	notNA := func(value string) string {
+		if value == "NA" {
+			return ""
+		}
+		return value
+	}
+	metadata := &BasicMetadata{
+		ID:        notNA(fields[0]),
+		Title:     notNA(strings.Join(fields[1:len(fields)-2], "\t")),
+		Thumbnail: notNA(fields[len(fields)-1]),
+	}
+	if duration := notNA(fields[len(fields)-2]); duration != "" {
+		seconds, err := strconv.ParseFloat(duration, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q: %w", duration, err)
+		}
+		metadata.Duration = int(math.Round(seconds))
+	}
+	return metadata, nil
+}
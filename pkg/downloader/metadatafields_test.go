@@ -0,0 +1,114 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMetadataPrintTemplate(t *testing.T) {
+	tests := []struct {
+		fields  []string
+		want    string
+		wantErr bool
+	}{
+		{[]string{"id"}, "%(.{id})#j", false},
+		{[]string{"id", "title", "duration", "thumbnail"}, "%(.{id,title,duration,thumbnail})#j", false},
+		{[]string{"live_status", "release_timestamp"}, "%(.{live_status,release_timestamp})#j", false},
+		{nil, "", true},
+		{[]string{"id", "nonexistent"}, "", true},
+		{[]string{"is_short"}, "", true}, // Derived locally, yt-dlp doesn't know it
+		{[]string{"id})s%(title"}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := metadataPrintTemplate(tt.fields)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("metadataPrintTemplate(%q) = %q, %v; want %q (error %v)", tt.fields, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGetVideoMetadataFields(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+echo '{"id": "aaaaaaaaaaa", "title": "Video", "duration": 212}'`)
+
+	metadata, err := GetVideoMetadataFields(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", "id", "title", "duration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.ID != "aaaaaaaaaaa" || metadata.Title != "Video" || metadata.Duration != 212 {
+		t.Errorf("metadata = %+v", metadata)
+	}
+	// Fields that weren't requested stay empty
+	if metadata.Uploader != "" || metadata.ViewCount != 0 || len(metadata.Raw) != 3 {
+		t.Errorf("unrequested fields populated: %+v", metadata)
+	}
+
+	args := calls()[0]
+	if argValue(args, "--print") != "%(.{id,title,duration})#j" || !hasArgs(args, "--skip-download") {
+		t.Errorf("yt-dlp args %v", args)
+	}
+	if hasArgs(args, "--dump-json") {
+		t.Errorf("full JSON requested: %v", args)
+	}
+
+	if _, err := GetVideoMetadataFields(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", "bogus"); err == nil {
+		t.Error("unknown field accepted")
+	}
+	if len(calls()) != 1 {
+		t.Error("yt-dlp ran for an invalid field list")
+	}
+}
+
+func TestParseBasicMetadata(t *testing.T) {
+	tests := []struct {
+		output string
+		want   BasicMetadata
+	}{
+		{
+			"aaaaaaaaaaa\tNever Gonna Give You Up\t212\thttps://i.ytimg.com/vi/aaaaaaaaaaa/maxresdefault.jpg\n",
+			BasicMetadata{ID: "aaaaaaaaaaa", Title: "Never Gonna Give You Up", Duration: 212, Thumbnail: "https://i.ytimg.com/vi/aaaaaaaaaaa/maxresdefault.jpg"},
+		},
+		// Fractional durations are rounded
+		{"aaaaaaaaaaa\tClip\t59.6\thttps://i.ytimg.com/x.jpg\r\n", BasicMetadata{ID: "aaaaaaaaaaa", Title: "Clip", Duration: 60, Thumbnail: "https://i.ytimg.com/x.jpg"}},
+		// Live streams have no duration; missing fields print as NA
+		{"aaaaaaaaaaa\tLive now\tNA\tNA\n", BasicMetadata{ID: "aaaaaaaaaaa", Title: "Live now"}},
+		// Tabs inside the title stay in the title
+		{"aaaaaaaaaaa\tPart 1\tPart 2\t30\thttps://i.ytimg.com/x.jpg", BasicMetadata{ID: "aaaaaaaaaaa", Title: "Part 1\tPart 2", Duration: 30, Thumbnail: "https://i.ytimg.com/x.jpg"}},
+		{"aaaaaaaaaaa\t\t30\tNA\n", BasicMetadata{ID: "aaaaaaaaaaa", Duration: 30}},
+	}
+	for _, tt := range tests {
+		got, err := parseBasicMetadata(tt.output)
+		if err != nil {
+			t.Errorf("parseBasicMetadata(%q): %v", tt.output, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("parseBasicMetadata(%q) = %+v, want %+v", tt.output, *got, tt.want)
+		}
+	}
+
+	for _, output := range []string{"", "aaaaaaaaaaa\tTitle\n", "aaaaaaaaaaa\tTitle\tlong\tNA\n"} {
+		if _, err := parseBasicMetadata(output); err == nil {
+			t.Errorf("parseBasicMetadata(%q) accepted", output)
+		}
+	}
+}
+
+func TestGetBasicMetadata(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+printf 'aaaaaaaaaaa\tVideo\t212\thttps://i.ytimg.com/vi/aaaaaaaaaaa/hqdefault.jpg\n'`)
+
+	metadata, err := GetBasicMetadata(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.ID != "aaaaaaaaaaa" || metadata.Title != "Video" || metadata.Duration != 212 || metadata.Thumbnail == "" {
+		t.Errorf("metadata = %+v", metadata)
+	}
+	args := calls()[0]
+	if argValue(args, "--print") != basicMetadataTemplate || !hasArgs(args, "--skip-download") || hasArgs(args, "--dump-json") {
+		t.Errorf("yt-dlp args %v", args)
+	}
+}
@@ -0,0 +1,73 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// MetadataLogCallback receives yt-dlp's verbose log, one line at a time
+type MetadataLogCallback func(line string)
+
+// GetVideoMetadataWithLog fetches video metadata like GetVideoMetadataWithContext,
+// but runs yt-dlp with -v and passes every log line to logCb. Useful when
+// debugging extraction failures; normal calls should use GetVideoMetadataWithContext.
+//
+// Example:
+//
+//	metadata, err := downloader.GetVideoMetadataWithLog(ctx, url, func(line string) {
+//	    log.Println("yt-dlp:", line)
+//	})
+func GetVideoMetadataWithLog(ctx context.Context, url string, logCb MetadataLogCallback) (*VideoMetadata, error) {
+	return getVideoMetadata(ctx, url, logCb)
+}
+
+// runMetadataCommand runs cmd and returns its stdout and stderr. If logCb is
+// set, yt-dlp runs verbosely and stderr is streamed to logCb as it arrives.
+func runMetadataCommand(cmd *exec.Cmd, logCb MetadataLogCallback) ([]byte, string, error) {
+	if logCb == nil {
+		output, err := cmd.Output()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return output, string(exitErr.Stderr), err
+		}
+		return output, "", err
+	}
+
+	// -v goes right after the binary so it applies before any other option
+	cmd.Args = append([]string{cmd.Args[0], "-v"}, cmd.Args[1:]...)
+	stderr := &logWriter{callback: logCb}
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	stderr.flush()
+	return output, stderr.all.String(), err
+}
+
+// logWriter passes complete lines to callback and keeps everything written
+type logWriter struct {
+	callback MetadataLogCallback
+	all      bytes.Buffer
+	pending  []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.all.Write(p)
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.callback(strings.TrimRight(string(w.pending[:i]), "\r"))
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush passes a trailing line without a newline to callback
+func (w *logWriter) flush() {
+	if len(w.pending) > 0 {
+		w.callback(strings.TrimRight(string(w.pending), "\r"))
+		w.pending = nil
+	}
+}
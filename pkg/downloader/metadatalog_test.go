@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// verboseMetadataScript prints a debug log to stderr when run with -v
+const verboseMetadataScript = `if [ "$1" = "-v" ]; then
+	printf '[debug] Command-line config: [...]\n[debug] yt-dlp version 2024.08.06\r\n[youtube] aaaaaaaaaaa: Downloading webpage' >&2
+fi
+echo '{"id": "aaaaaaaaaaa", "title": "Video"}'`
+
+func TestGetVideoMetadataWithLog(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+verboseMetadataScript)
+
+	var lines []string
+	metadata, err := GetVideoMetadataWithLog(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Title != "Video" {
+		t.Errorf("Title = %q", metadata.Title)
+	}
+	want := []string{
+		"[debug] Command-line config: [...]",
+		"[debug] yt-dlp version 2024.08.06",
+		"[youtube] aaaaaaaaaaa: Downloading webpage", // No trailing newline
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("log lines %q, want %q", lines, want)
+	}
+	if args := calls()[0]; args[0] != "-v" {
+		t.Errorf("yt-dlp args %v, want -v first", args)
+	}
+}
+
+func TestGetVideoMetadataQuietByDefault(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+verboseMetadataScript)
+
+	if _, err := GetVideoMetadataWithContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa"); err != nil {
+		t.Fatal(err)
+	}
+	if args := calls()[0]; slices.Contains(args, "-v") {
+		t.Errorf("yt-dlp ran verbosely without a log callback: %v", args)
+	}
+}
+
+func TestGetVideoMetadataWithLogOnFailure(t *testing.T) {
+	useFakeYTDLP(t, `echo "[debug] trying" >&2; echo "ERROR: [youtube] aaaaaaaaaaa: Private video" >&2; exit 1`)
+
+	var lines []string
+	_, err := GetVideoMetadataWithLog(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", func(line string) {
+		lines = append(lines, line)
+	})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want ErrUnavailable classified from the captured log", err)
+	}
+	if len(lines) < 2 || lines[0] != "[debug] trying" {
+		t.Errorf("log lines %q, want the log of every attempt", lines)
+	}
+}
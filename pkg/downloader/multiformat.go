@@ -0,0 +1,229 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// audioOnlyFormats are output formats that can only hold an audio stream
+var audioOnlyFormats = map[string]bool{
+	"mp3":  true,
+	"m4a":  true,
+	"aac":  true,
+	"opus": true,
+	"ogg":  true,
+	"flac": true,
+	"wav":  true,
+}
+
+// OutputSpec describes one output produced by DownloadMultiFormat
+type OutputSpec struct {
+	Format    string // Output container/extension: mp4, mkv, mp3, m4a, etc.
+	AudioOnly bool   // Drop the video stream (implied for audio formats like mp3)
+	Codec     string // Optional ffmpeg codec; empty remuxes video and uses DefaultAudioCodec for audio
+	Bitrate   string // Optional audio bitrate for audio-only outputs, e.g. "192k"
+}
+
+// isAudioOnly reports whether the spec produces an audio-only file
+func (s OutputSpec) isAudioOnly() bool {
+	return s.AudioOnly || audioOnlyFormats[strings.ToLower(s.Format)]
+}
+
+// MultiFormatOptions configures the source download for DownloadMultiFormat
+type MultiFormatOptions struct {
+	Resolution string           // Maximum source height (default: 720)
+	Codec      string           // Preferred source video codec (default: avc1)
+	OutputDir  string           // Output directory (default: current working directory)
+	Progress   ProgressCallback // Optional progress callback
+
+	// Timeout bounds the download and every conversion together (default: 50 minutes)
+	Timeout time.Duration
+}
+
+// DownloadMultiFormat downloads the source once and produces every requested output
+// from the cached source with ffmpeg, so an mp4 and an mp3 of the same video
+// do not require two downloads.
+// Returns one path per spec, in the same order as formats.
+//
+// Example:
+//
+//	paths, err := downloader.DownloadMultiFormat(url, []downloader.OutputSpec{
+//	    {Format: "mp4"},
+//	    {Format: "mp3", Bitrate: "192k"},
+//	}, downloader.MultiFormatOptions{OutputDir: "./downloads"})
+func DownloadMultiFormat(url string, formats []OutputSpec, opts MultiFormatOptions) ([]string, error) {
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("at least one output format is required")
+	}
+
+	seen := make(map[string]bool)
+	needsVideo := false
+	for _, spec := range formats {
+		if spec.Format == "" {
+			return nil, fmt.Errorf("output format is required")
+		}
+		key := strings.ToLower(spec.Format)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate output format: %s", spec.Format)
+		}
+		seen[key] = true
+		if !spec.isAudioOnly() {
+			needsVideo = true
+		} else {
+			if spec.Codec != "" {
+				if err := validateAudioCodec(spec.Format, spec.Codec); err != nil {
+					return nil, err
+				}
+			}
+			codec := spec.Codec
+			if codec == "" {
+				codec = DefaultAudioCodec(spec.Format)
+			}
+			if spec.Bitrate != "" && isLosslessAudioCodec(codec) {
+				return nil, fmt.Errorf("bitrate %q cannot be used with lossless %s output", spec.Bitrate, spec.Format)
+			}
+		}
+	}
+
+	// Auto-install binaries if needed (only happens once)
+	if err := ensureBinariesInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+	if !ffmpegAvailable() {
+		return nil, fmt.Errorf("%w: producing outputs from the cached source", ErrFFMPEGRequired)
+	}
+	for _, spec := range formats {
+		if err := requireEncoder(spec.Codec); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Resolution == "" {
+		opts.Resolution = "720"
+	}
+	if opts.Codec == "" {
+		opts.Codec = "avc1"
+	}
+	progressCb, untrack := trackDownload(url, opts.Progress)
+	defer untrack()
+
+	ctx, cancel := withDownloadDeadline(context.Background(), opts.Timeout)
+	defer cancel()
+
+	release, err := acquireHostSlot(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	temp, err := outputTemplate(opts.OutputDir, "source")
+	if err != nil {
+		return nil, err
+	}
+
+	// Only fetch a video stream if at least one output needs it
+	selector := "bestaudio"
+	if needsVideo {
+		selector = fmt.Sprintf("bestvideo[height<=%s][vcodec*=%s]+bestaudio/best", opts.Resolution, opts.Codec)
+	}
+
+	if progressCb != nil {
+		progressCb(DownloadProgress{Stage: "Downloading source"})
+	}
+
+	existing := templateFiles(temp)
+	if _, err := runDownload(ctx, selector, temp, url, progressCb); err != nil {
+		removeNewTemplateFiles(temp, existing)
+		return nil, fmt.Errorf("yt-dlp source download failed: %w", err)
+	}
+
+	source := findDownloadedFile(temp, downloadExtensions(!needsVideo, ""))
+	if source == "" {
+		return nil, fmt.Errorf("could not find downloaded source file")
+	}
+	defer os.Remove(source)
+
+	// Outputs get their own name so a same-container output never overwrites the source
+	outputTemp, err := outputTemplate(opts.OutputDir, "video")
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(outputTemp, ".%(ext)s")
+	var outputs []string
+	for i, spec := range formats {
+		output := base + "." + spec.Format
+
+		if progressCb != nil {
+			progressCb(DownloadProgress{
+				Stage:      fmt.Sprintf("Converting to %s", spec.Format),
+				Percentage: float64(i) / float64(len(formats)) * 100,
+			})
+		}
+
+		// Each container decides separately whether the source can be copied
+		recode := !spec.isAudioOnly() && needsRecode(ctx, source, spec.Format)
+		err := convertToFile(ctx, output, progressCb, func(dst string) []string {
+			return multiFormatArgs(source, dst, spec, recode)
+		})
+		if err != nil {
+			// Don't leave a partial set of outputs behind
+			for _, done := range outputs {
+				os.Remove(done)
+			}
+			return nil, fmt.Errorf("ffmpeg conversion to %s failed: %w", spec.Format, err)
+		}
+
+		abs, err := filepath.Abs(output)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, abs)
+	}
+
+	if progressCb != nil {
+		progressCb(DownloadProgress{Stage: "Completed", Percentage: 100.0})
+	}
+
+	return outputs, nil
+}
+
+// multiFormatArgs builds the ffmpeg arguments producing one output from the
+// source. Video outputs without a Codec are remuxed, or re-encoded like
+// RecodeVideo does if recode is set (see needsRecode).
+func multiFormatArgs(source string, output string, spec OutputSpec, recode bool) []string {
+	if !spec.isAudioOnly() && spec.Codec == "" {
+		return videoConvertArgs(source, output, spec.Format, recode)
+	}
+
+	args := []string{"-i", source}
+	if spec.isAudioOnly() {
+		args = append(args, "-vn")
+		codec := spec.Codec
+		if codec == "" {
+			codec = DefaultAudioCodec(spec.Format)
+		}
+		if codec != "" {
+			args = append(args, "-acodec", codec)
+		}
+		if spec.Bitrate != "" {
+			args = append(args, "-ab", spec.Bitrate)
+		}
+	} else {
+		audioCodec := "copy"
+		if codecs, ok := recodeCodecs[strings.ToLower(spec.Format)]; ok && recode {
+			audioCodec = codecs[1]
+		}
+		args = append(args, "-c:v", spec.Codec, "-c:a", audioCodec)
+		args = append(args, faststartArgs(spec.Format)...)
+	}
+
+	return append(args,
+		"-max_muxing_queue_size", "1024", // Handle large files
+		"-y",
+		output,
+	)
+}
@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiFormatArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   OutputSpec
+		recode bool
+		want   string
+	}{
+		{"mp4 remux", OutputSpec{Format: "mp4"}, false,
+			"-i src -c copy -movflags +faststart -max_muxing_queue_size 1024 -y out"},
+		{"mp4 recode", OutputSpec{Format: "mp4"}, true,
+			"-i src -c:v libx264 -c:a aac -preset veryfast -crf 23 -movflags +faststart -max_muxing_queue_size 1024 -y out"},
+		{"mkv remux has no faststart", OutputSpec{Format: "mkv"}, false,
+			"-i src -c copy -max_muxing_queue_size 1024 -y out"},
+		{"webm recode", OutputSpec{Format: "webm"}, true,
+			"-i src -c:v libvpx-vp9 -c:a libopus -max_muxing_queue_size 1024 -y out"},
+		{"custom video codec", OutputSpec{Format: "mov", Codec: "libx264"}, false,
+			"-i src -c:v libx264 -c:a copy -movflags +faststart -max_muxing_queue_size 1024 -y out"},
+		{"custom video codec recoding audio", OutputSpec{Format: "mp4", Codec: "mpeg4"}, true,
+			"-i src -c:v mpeg4 -c:a aac -movflags +faststart -max_muxing_queue_size 1024 -y out"},
+		{"mp3", OutputSpec{Format: "mp3", Bitrate: "192k"}, false,
+			"-i src -vn -acodec libmp3lame -ab 192k -max_muxing_queue_size 1024 -y out"},
+		{"audio only mkv", OutputSpec{Format: "mka", AudioOnly: true, Codec: "flac"}, false,
+			"-i src -vn -acodec flac -max_muxing_queue_size 1024 -y out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(multiFormatArgs("src", "out", tt.spec, tt.recode), " ")
+			if got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadMultiFormatDownloadsOnce(t *testing.T) {
+	ytdlpLog, ytdlpCalls := argsLog(t)
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeYTDLP(t, ytdlpLog+"\n"+writeOutput("webm"))
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg),
+		`echo '{"format":{"format_name":"matroska,webm"},"streams":[{"codec_type":"video","codec_name":"vp9"},{"codec_type":"audio","codec_name":"opus"}]}'`)
+	dir := t.TempDir()
+
+	paths, err := DownloadMultiFormat("https://example.com/watch?v=1", []OutputSpec{
+		{Format: "mp4"},
+		{Format: "mkv"},
+		{Format: "mp3"},
+	}, MultiFormatOptions{OutputDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := ytdlpCalls(); len(calls) != 1 {
+		t.Fatalf("yt-dlp ran %d times, want 1", len(calls))
+	}
+	conversions := ffmpegCalls()
+	if len(conversions) != 3 {
+		t.Fatalf("ffmpeg ran %d times, want 3", len(conversions))
+	}
+	// The VP9/Opus source is re-encoded for mp4 only
+	if !hasArgs(conversions[0], "-c:v", "libx264", "-c:a", "aac") {
+		t.Errorf("mp4 output not re-encoded: %v", conversions[0])
+	}
+	if !hasArgs(conversions[1], "-c", "copy") || hasArgs(conversions[1], "-movflags", "+faststart") {
+		t.Errorf("mkv output not remuxed without faststart: %v", conversions[1])
+	}
+
+	if len(paths) != 3 {
+		t.Fatalf("got %d paths, want 3", len(paths))
+	}
+	for i, ext := range []string{".mp4", ".mkv", ".mp3"} {
+		if filepath.Ext(paths[i]) != ext {
+			t.Errorf("paths[%d] = %s, want extension %s", i, paths[i], ext)
+		}
+		if _, err := os.Stat(paths[i]); err != nil {
+			t.Errorf("output %s missing: %v", paths[i], err)
+		}
+	}
+	if sources, _ := filepath.Glob(filepath.Join(dir, "source_*")); len(sources) != 0 {
+		t.Errorf("source left behind: %v", sources)
+	}
+}
@@ -0,0 +1,106 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// nativeAudioFormats are audio formats YouTube serves directly, so they can be
+// downloaded as-is without ffmpeg
+var nativeAudioFormats = map[string]bool{
+	"m4a":  true,
+	"webm": true,
+}
+
+// ffmpegAvailable reports whether the configured ffmpeg binary can be run
+func ffmpegAvailable() bool {
+	return checkBinaryExists(FFMPEGPath)
+}
+
+// videoSelectorWithoutFFMPEG returns a selector for a single pre-merged file
+// already in the requested container, so neither merging nor converting is needed.
+// Returns an error if the options can only be met with ffmpeg.
+func videoSelectorWithoutFFMPEG(opts VideoOptions, format string) (string, error) {
+	if opts.RecodeVideo {
+		return "", fmt.Errorf("%w: RecodeVideo re-encodes the video", ErrFFMPEGRequired)
+	}
+	if opts.EmbedSourceMetadata {
+		return "", fmt.Errorf("%w: embedding source metadata", ErrFFMPEGRequired)
+	}
+	if opts.EmbedInfoJSON {
+		return "", fmt.Errorf("%w: embedding the info json", ErrFFMPEGRequired)
+	}
+	if opts.EmbedSubtitles {
+		return "", fmt.Errorf("%w: embedding subtitles", ErrFFMPEGRequired)
+	}
+	if opts.PosterThumbnail {
+		return "", fmt.Errorf("%w: attaching a poster", ErrFFMPEGRequired)
+	}
+
+	resolution := opts.Resolution
+	if resolution == "" {
+		resolution = "720"
+	}
+	codec := opts.Codec
+	if codec == "" {
+		codec = "avc1"
+	}
+	ext := strings.ToLower(format)
+
+	// Single-track downloads still need no merge; just pick the track in ext
+	if opts.VideoOnly || opts.AudioOnly {
+		track := "video"
+		if opts.AudioOnly {
+			track = "audio"
+		}
+		if opts.Quality == QualityDataSaver {
+			return fmt.Sprintf("worst%s[ext=%s]", track, ext), nil
+		}
+		if opts.VideoOnly && (opts.Quality == "" || opts.Quality == QualityBalanced) {
+			return fmt.Sprintf("bestvideo[height<=%s][ext=%s]/bestvideo[ext=%s]", resolution, ext, ext), nil
+		}
+		return fmt.Sprintf("best%s[ext=%s]", track, ext), nil
+	}
+
+	switch opts.Quality {
+	case "", QualityBalanced:
+		return fmt.Sprintf("best[height<=%s][vcodec*=%s][ext=%s]/best[height<=%s][ext=%s]/best[ext=%s]",
+			resolution, codec, ext, resolution, ext, ext), nil
+	case QualityBest:
+		return fmt.Sprintf("best[ext=%s]", ext), nil
+	case QualityDataSaver:
+		return fmt.Sprintf("worst[ext=%s]", ext), nil
+	default:
+		return "", fmt.Errorf("unknown quality preset: %q", opts.Quality)
+	}
+}
+
+// audioSelectorWithoutFFMPEG returns a selector downloading audio directly in
+// format. Returns an error if the options can only be met with ffmpeg.
+func audioSelectorWithoutFFMPEG(opts AudioOptions, format string) (string, error) {
+	ext := strings.ToLower(format)
+	switch {
+	case !nativeAudioFormats[ext]:
+		return "", fmt.Errorf("%w: converting audio to %s", ErrFFMPEGRequired, format)
+	case opts.Codec != "" || opts.Bitrate != "":
+		return "", fmt.Errorf("%w: re-encoding audio with a codec or bitrate", ErrFFMPEGRequired)
+	case opts.SampleRate != 0 || opts.Channels != 0:
+		return "", fmt.Errorf("%w: changing the sample rate or channels", ErrFFMPEGRequired)
+	case opts.TrimSilence:
+		return "", fmt.Errorf("%w: trimming silence", ErrFFMPEGRequired)
+	case opts.EmbedCoverArt:
+		return "", fmt.Errorf("%w: embedding cover art", ErrFFMPEGRequired)
+	case opts.EmbedSourceMetadata:
+		return "", fmt.Errorf("%w: embedding source metadata", ErrFFMPEGRequired)
+	case len(opts.Tags) > 0:
+		return "", fmt.Errorf("%w: setting metadata tags", ErrFFMPEGRequired)
+	}
+
+	if opts.Quality == QualityDataSaver {
+		return fmt.Sprintf("worstaudio[ext=%s]", ext), nil
+	}
+	if _, err := audioSelector(opts.Quality); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("bestaudio[ext=%s]", ext), nil
+}
@@ -0,0 +1,106 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestVideoSelectorWithoutFFMPEG(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     VideoOptions
+		format   string
+		want     string
+		required bool
+	}{
+		{"default", VideoOptions{}, "mp4", "best[height<=720][vcodec*=avc1][ext=mp4]/best[height<=720][ext=mp4]/best[ext=mp4]", false},
+		{"best", VideoOptions{Quality: QualityBest}, "WEBM", "best[ext=webm]", false},
+		{"data saver", VideoOptions{Quality: QualityDataSaver}, "mp4", "worst[ext=mp4]", false},
+		{"video only", VideoOptions{VideoOnly: true, Resolution: "1080"}, "mp4", "bestvideo[height<=1080][ext=mp4]/bestvideo[ext=mp4]", false},
+		{"audio only", VideoOptions{AudioOnly: true}, "m4a", "bestaudio[ext=m4a]", false},
+		{"recode", VideoOptions{RecodeVideo: true}, "mp4", "", true},
+		{"subtitles", VideoOptions{EmbedSubtitles: true}, "mp4", "", true},
+		{"poster", VideoOptions{PosterThumbnail: true}, "mp4", "", true},
+		{"source metadata", VideoOptions{EmbedSourceMetadata: true}, "mp4", "", true},
+	}
+	for _, tt := range tests {
+		got, err := videoSelectorWithoutFFMPEG(tt.opts, tt.format)
+		if got != tt.want || errors.Is(err, ErrFFMPEGRequired) != tt.required {
+			t.Errorf("%s: got %q, %v; want %q (ffmpeg required %v)", tt.name, got, err, tt.want, tt.required)
+		}
+	}
+}
+
+func TestAudioSelectorWithoutFFMPEG(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     AudioOptions
+		format   string
+		want     string
+		required bool
+	}{
+		{"m4a", AudioOptions{}, "m4a", "bestaudio[ext=m4a]", false},
+		{"webm", AudioOptions{}, "webm", "bestaudio[ext=webm]", false},
+		{"data saver", AudioOptions{Quality: QualityDataSaver}, "m4a", "worstaudio[ext=m4a]", false},
+		{"mp3", AudioOptions{}, "mp3", "", true},
+		{"bitrate", AudioOptions{Bitrate: "192k"}, "m4a", "", true},
+		{"sample rate", AudioOptions{SampleRate: 44100}, "m4a", "", true},
+		{"cover art", AudioOptions{EmbedCoverArt: true}, "m4a", "", true},
+		{"tags", AudioOptions{Tags: map[string]string{"title": "x"}}, "m4a", "", true},
+	}
+	for _, tt := range tests {
+		got, err := audioSelectorWithoutFFMPEG(tt.opts, tt.format)
+		if got != tt.want || errors.Is(err, ErrFFMPEGRequired) != tt.required {
+			t.Errorf("%s: got %q, %v; want %q (ffmpeg required %v)", tt.name, got, err, tt.want, tt.required)
+		}
+	}
+}
+
+func TestDownloadWithoutFFMPEG(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("mp4"))
+	withoutFFMPEG(t)
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:    "mp4",
+		OutputDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Ext(result.Path) != ".mp4" {
+		t.Errorf("path = %s", result.Path)
+	}
+	if selector := argValue(calls()[0], "-f"); selector != "best[height<=720][vcodec*=avc1][ext=mp4]/best[height<=720][ext=mp4]/best[ext=mp4]" {
+		t.Errorf("selector %q needs a merge", selector)
+	}
+
+	// The download came back in another container and can't be converted
+	useFakeYTDLP(t, writeOutput("webm"))
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:    "mp4",
+		OutputDir: t.TempDir(),
+	}); !errors.Is(err, ErrFFMPEGRequired) {
+		t.Errorf("err = %v, want ErrFFMPEGRequired", err)
+	}
+}
+
+func TestDownloadAudioWithoutFFMPEG(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("m4a"))
+	withoutFFMPEG(t)
+
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "m4a",
+		OutputDir: t.TempDir(),
+	}); err != nil {
+		t.Errorf("native m4a without ffmpeg: %v", err)
+	}
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "mp3",
+		OutputDir: t.TempDir(),
+	}); !errors.Is(err, ErrFFMPEGRequired) {
+		t.Errorf("mp3 without ffmpeg: err = %v, want ErrFFMPEGRequired", err)
+	}
+}
@@ -0,0 +1,99 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+var (
+	// OutputFileMode is applied to finished downloads (default: 0, keep the
+	// umask-based mode the file was created with).
+	// Can be set using SetOutputFileMode()
+	OutputFileMode os.FileMode
+
+	// OutputFileGroup is the group ID finished downloads are given on Unix
+	// (default: -1, keep the creating process's group).
+	// Can be set using SetOutputFileGroup()
+	OutputFileGroup = -1
+
+	// OutputDirMode is the mode of output directories the package creates
+	// (default: 0755, reduced by the umask unless set explicitly).
+	// Can be set using SetOutputDirMode()
+	OutputDirMode os.FileMode = 0755
+
+	// outputDirModeSet reports whether OutputDirMode was set explicitly, in
+	// which case it is applied regardless of the umask
+	outputDirModeSet bool
+)
+
+// SetOutputFileMode sets the permissions of finished downloads, e.g. 0664 for
+// a directory shared by a group. Pass 0 to keep the default. Only permission
+// bits are allowed, and the owner must keep read access.
+func SetOutputFileMode(mode os.FileMode) error {
+	if mode&^os.ModePerm != 0 {
+		return fmt.Errorf("invalid output file mode %v: only permission bits are allowed", mode)
+	}
+	if mode != 0 && mode&0400 == 0 {
+		return fmt.Errorf("invalid output file mode %#o: the owner must be able to read the file", uint32(mode))
+	}
+	OutputFileMode = mode
+	return nil
+}
+
+// SetOutputFileGroup sets the group ID finished downloads are given, e.g. a
+// group shared by the server and the users reading its downloads. The process
+// must be a member of the group (or root). Pass -1 to keep the default.
+// Not supported on Windows.
+func SetOutputFileGroup(gid int) error {
+	if gid < -1 {
+		return fmt.Errorf("invalid group ID: %d", gid)
+	}
+	if gid != -1 && runtime.GOOS == "windows" {
+		return fmt.Errorf("setting the output file group is not supported on windows")
+	}
+	OutputFileGroup = gid
+	return nil
+}
+
+// SetOutputDirMode sets the permissions of output directories the package
+// creates, e.g. 0775. Existing directories are left untouched.
+func SetOutputDirMode(mode os.FileMode) {
+	if mode.Perm() == 0 {
+		OutputDirMode, outputDirModeSet = 0755, false
+		return
+	}
+	OutputDirMode, outputDirModeSet = mode.Perm(), true
+}
+
+// makeOutputDir creates dir (and its parents) with OutputDirMode if it does not exist
+func makeOutputDir(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, OutputDirMode); err != nil {
+		return err
+	}
+	if outputDirModeSet {
+		// MkdirAll's mode is reduced by the umask
+		return os.Chmod(dir, OutputDirMode)
+	}
+	return nil
+}
+
+// applyOutputFileMode sets OutputFileMode and OutputFileGroup on a finished
+// download, if configured
+func applyOutputFileMode(path string) error {
+	if OutputFileGroup != -1 {
+		if err := os.Chown(path, -1, OutputFileGroup); err != nil {
+			return fmt.Errorf("failed to set file group: %w", err)
+		}
+	}
+	if OutputFileMode == 0 {
+		return nil
+	}
+	if err := os.Chmod(path, OutputFileMode); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+//go:build unix
+
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// withUmask sets the process umask for the rest of the test
+func withUmask(t *testing.T, mask int) {
+	t.Helper()
+	old := syscall.Umask(mask)
+	t.Cleanup(func() { syscall.Umask(old) })
+}
+
+func TestDownloadOutputFileMode(t *testing.T) {
+	withUmask(t, 0077)
+	useFakeYTDLP(t, writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &OutputFileMode, 0)
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(result.Path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("default mode %v, %v; want the umask-based 0600", info.Mode().Perm(), err)
+	}
+
+	if err := SetOutputFileMode(0664); err != nil {
+		t.Fatal(err)
+	}
+	result, err = DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(result.Path); err != nil || info.Mode().Perm() != 0664 {
+		t.Errorf("mode %v, %v; want 0664", info.Mode().Perm(), err)
+	}
+}
+
+func TestMakeOutputDirMode(t *testing.T) {
+	withUmask(t, 0022)
+	setForTest(t, &OutputDirMode, OutputDirMode)
+	setForTest(t, &outputDirModeSet, outputDirModeSet)
+	root := t.TempDir()
+
+	SetOutputDirMode(0)
+	dir := filepath.Join(root, "default")
+	if err := makeOutputDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(dir); info.Mode().Perm() != 0755 {
+		t.Errorf("default dir mode %v, want 0755", info.Mode().Perm())
+	}
+
+	// An explicit mode is applied regardless of the umask, to every new directory
+	SetOutputDirMode(0775)
+	dir = filepath.Join(root, "shared", "nested")
+	if err := makeOutputDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(dir); info.Mode().Perm() != 0775 {
+		t.Errorf("dir mode %v, want 0775", info.Mode().Perm())
+	}
+
+	// Existing directories are left alone
+	existing := filepath.Join(root, "existing")
+	os.Mkdir(existing, 0700)
+	if err := makeOutputDir(existing); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(existing); info.Mode().Perm() != 0700 {
+		t.Errorf("existing dir mode changed to %v", info.Mode().Perm())
+	}
+}
+
+func TestSetOutputFileModeValidation(t *testing.T) {
+	setForTest(t, &OutputFileMode, 0)
+	for _, mode := range []os.FileMode{os.ModeSetuid | 0644, os.ModeDir | 0755, 0200, 0044} {
+		if err := SetOutputFileMode(mode); err == nil {
+			t.Errorf("SetOutputFileMode(%v) accepted", mode)
+		}
+	}
+	for _, mode := range []os.FileMode{0, 0400, 0640, 0664} {
+		if err := SetOutputFileMode(mode); err != nil || OutputFileMode != mode {
+			t.Errorf("SetOutputFileMode(%#o): err %v, OutputFileMode %#o", uint32(mode), err, uint32(OutputFileMode))
+		}
+	}
+}
+
+func TestSetOutputFileGroup(t *testing.T) {
+	setForTest(t, &OutputFileGroup, -1)
+	if err := SetOutputFileGroup(-2); err == nil {
+		t.Error("negative group ID accepted")
+	}
+	if err := SetOutputFileGroup(1000); err != nil || OutputFileGroup != 1000 {
+		t.Errorf("SetOutputFileGroup(1000): err %v, OutputFileGroup %d", err, OutputFileGroup)
+	}
+}
+
+func TestDownloadOutputFileGroup(t *testing.T) {
+	withUmask(t, 0022)
+	useFakeYTDLP(t, writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &OutputFileMode, 0640)
+	// The process can always give files its own group
+	gid := os.Getgid()
+	setForTest(t, &OutputFileGroup, gid)
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(result.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode %v, want 0640", info.Mode().Perm())
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Gid) != gid {
+		t.Errorf("group %d, want %d", stat.Gid, gid)
+	}
+}
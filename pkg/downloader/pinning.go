@@ -0,0 +1,17 @@
+package downloader
+
+import "youtube-api-server/pkg/internal/installer"
+
+// SetInstallerPins pins the TLS public keys the auto-installer accepts from a
+// binary download host (github.com, objects.githubusercontent.com,
+// johnvansickle.com, evermeet.cx), hardening installs against interception.
+// Each pin is the base64 SHA-256 of a certificate's SubjectPublicKeyInfo; one
+// of them must appear in the host's verified chain, or be the key of its leaf
+// certificate when verification is disabled. No pins removes pinning.
+//
+// Example:
+//
+//	err := downloader.SetInstallerPins("github.com", "YLh1dUR9y6Kja30RrAn7JKnbQG/uEtLMkBgFF2Fuihg=")
+func SetInstallerPins(host string, pins ...string) error {
+	return installer.SetPinnedKeys(host, pins)
+}
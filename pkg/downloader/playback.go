@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Playback URLs are resolved with one yt-dlp -g call per stream, so the
+// video URL of an adaptive video never silently falls back to a combined format
+const (
+	playbackVideoSelector    = "bestvideo"
+	playbackAudioSelector    = "bestaudio"
+	playbackCombinedSelector = "best"
+)
+
+// PlaybackInfo is everything a web player needs to play a video directly from
+// the source: the metadata plus the direct media URLs.
+//
+// When Adaptive is true the video URL carries no audio and the player must
+// combine VideoURL and AudioURL itself (e.g. with MSE or a DASH manifest).
+// Otherwise VideoURL is a single combined stream that can be played as-is.
+type PlaybackInfo struct {
+	Metadata *VideoMetadata `json:"metadata"`
+	StreamURLs
+	Adaptive bool `json:"adaptive"`
+}
+
+// GetPlaybackInfo fetches metadata and direct playback URLs for a video
+func GetPlaybackInfo(url string) (*PlaybackInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	return GetPlaybackInfoWithContext(ctx, url)
+}
+
+// GetPlaybackInfoWithContext fetches playback info with a custom context for timeout/cancellation.
+// The URLs are temporary; refresh them before PlaybackInfo.ExpiresAt.
+func GetPlaybackInfoWithContext(ctx context.Context, url string) (*PlaybackInfo, error) {
+	metadata, err := GetVideoMetadataWithContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.IsUpcoming {
+		return nil, &NotYetAvailableError{ScheduledAt: metadata.ScheduledAt, Err: ErrNotYetAvailable}
+	}
+
+	formats, err := metadata.Formats()
+	if err != nil {
+		return nil, err
+	}
+	if !hasAdaptiveFormats(formats) {
+		urls, err := GetStreamURL(ctx, url, playbackCombinedSelector)
+		if err != nil {
+			return nil, err
+		}
+		return &PlaybackInfo{Metadata: metadata, StreamURLs: StreamURLs{VideoURL: urls.VideoURL, ExpiresAt: urls.ExpiresAt}}, nil
+	}
+
+	video, err := GetStreamURL(ctx, url, playbackVideoSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video stream URL: %w", err)
+	}
+	audio, err := GetStreamURL(ctx, url, playbackAudioSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio stream URL: %w", err)
+	}
+
+	info := &PlaybackInfo{
+		Metadata:   metadata,
+		StreamURLs: StreamURLs{VideoURL: video.VideoURL, AudioURL: audio.VideoURL, ExpiresAt: video.ExpiresAt},
+		Adaptive:   true,
+	}
+	// The player needs both streams, so the earlier expiry applies
+	if audio.ExpiresAt != nil && (info.ExpiresAt == nil || audio.ExpiresAt.Before(*info.ExpiresAt)) {
+		info.ExpiresAt = audio.ExpiresAt
+	}
+	return info, nil
+}
+
+// hasAdaptiveFormats reports whether formats include separate video-only and
+// audio-only streams, as YouTube serves above 360p
+func hasAdaptiveFormats(formats []FormatInfo) bool {
+	var videoOnly, audioOnly bool
+	for _, format := range formats {
+		videoOnly = videoOnly || (format.HasVideo() && !format.HasAudio())
+		audioOnly = audioOnly || (format.HasAudio() && !format.HasVideo())
+	}
+	return videoOnly && audioOnly
+}
@@ -0,0 +1,95 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// playbackScript is a fake yt-dlp answering --dump-json with metadata listing
+// formats, and -g with a URL per selector
+func playbackScript(logLine string, formats string) string {
+	return logLine + `
+prev=""
+for a in "$@"; do
+	[ "$a" = "--dump-json" ] && dump=1
+	[ "$prev" = "-f" ] && selector="$a"
+	prev="$a"
+done
+if [ -n "$dump" ]; then
+	echo '{"id": "aaaaaaaaaaa", "title": "Video", "formats": ` + formats + `}'
+	exit 0
+fi
+case "$selector" in
+bestvideo) echo "https://rr1.googlevideo.com/videoplayback?itag=137&expire=1700003600" ;;
+bestaudio) echo "https://rr1.googlevideo.com/videoplayback?itag=140&expire=1700000000" ;;
+best) echo "https://rr1.googlevideo.com/videoplayback?itag=18&expire=1700007200" ;;
+*) echo "ERROR: Requested format is not available" >&2; exit 1 ;;
+esac`
+}
+
+func TestGetPlaybackInfoAdaptive(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, playbackScript(logLine, `[
+		{"format_id": "140", "vcodec": "none", "acodec": "mp4a.40.2"},
+		{"format_id": "18", "vcodec": "avc1", "acodec": "mp4a.40.2"},
+		{"format_id": "137", "vcodec": "avc1", "acodec": "none"}]`))
+
+	info, err := GetPlaybackInfoWithContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Adaptive || info.Metadata.Title != "Video" {
+		t.Errorf("info = %+v", info)
+	}
+	if info.VideoURL != "https://rr1.googlevideo.com/videoplayback?itag=137&expire=1700003600" ||
+		info.AudioURL != "https://rr1.googlevideo.com/videoplayback?itag=140&expire=1700000000" {
+		t.Errorf("video %q, audio %q", info.VideoURL, info.AudioURL)
+	}
+	if info.ExpiresAt == nil || info.ExpiresAt.Unix() != 1700000000 {
+		t.Errorf("ExpiresAt = %v, want the audio URL's earlier expiry", info.ExpiresAt)
+	}
+
+	// Metadata, then one -g call per stream
+	var selectors []string
+	for _, call := range calls()[1:] {
+		if !hasArgs(call, "-g") {
+			t.Errorf("not a -g call: %v", call)
+		}
+		selectors = append(selectors, argValue(call, "-f"))
+	}
+	if len(selectors) != 2 || selectors[0] != "bestvideo" || selectors[1] != "bestaudio" {
+		t.Errorf("-g selectors %v, want [bestvideo bestaudio]", selectors)
+	}
+}
+
+func TestGetPlaybackInfoCombined(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, playbackScript(logLine, `[{"format_id": "18", "vcodec": "avc1", "acodec": "mp4a.40.2"}]`))
+
+	info, err := GetPlaybackInfoWithContext(context.Background(), "https://example.com/video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Adaptive || info.AudioURL != "" || info.VideoURL != "https://rr1.googlevideo.com/videoplayback?itag=18&expire=1700007200" {
+		t.Errorf("info = %+v", info)
+	}
+	if got := calls(); len(got) != 2 || argValue(got[1], "-f") != "best" {
+		t.Errorf("calls %v, want metadata and one -g -f best", got)
+	}
+}
+
+func TestGetPlaybackInfoUpcoming(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+echo '{"id": "aaaaaaaaaaa", "live_status": "is_upcoming", "release_timestamp": 1893456000}'`)
+
+	_, err := GetPlaybackInfoWithContext(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa")
+	var notYet *NotYetAvailableError
+	if !errors.As(err, &notYet) || notYet.ScheduledAt == nil {
+		t.Errorf("err = %v, want NotYetAvailableError with the scheduled time", err)
+	}
+	if len(calls()) != 1 {
+		t.Errorf("stream URLs requested for an upcoming video: %v", calls())
+	}
+}
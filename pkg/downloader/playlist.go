@@ -0,0 +1,496 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MatchFilter is the yt-dlp --match-filter expression applied to every item of
+// playlist downloads (e.g. "duration < 600 & view_count > 1000").
+// Empty means no filtering. Can be set using SetMatchFilter()
+var MatchFilter string
+
+// SetMatchFilter sets the yt-dlp --match-filter expression used by playlist
+// downloads. Items that don't match are skipped and reported in PlaylistResult.Skipped.
+//
+// Example:
+//
+//	downloader.SetMatchFilter("duration < 600 & !is_live")
+func SetMatchFilter(expr string) error {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return fmt.Errorf("match filter expression must not be empty (use ClearMatchFilter to remove it)")
+	}
+	MatchFilter = expr
+	return nil
+}
+
+// ClearMatchFilter removes the match filter set by SetMatchFilter()
+func ClearMatchFilter() {
+	MatchFilter = ""
+}
+
+// DateAfter and DateBefore limit playlist downloads to videos uploaded in a
+// date range (YYYYMMDD, inclusive), mapping to yt-dlp's --dateafter/--datebefore.
+// Empty means unbounded. Can be set using SetDateAfter() and SetDateBefore()
+var (
+	DateAfter  string
+	DateBefore string
+)
+
+// SetDateAfter only downloads playlist items uploaded on or after date (YYYYMMDD).
+// An empty date removes the bound.
+//
+// Example (incremental channel mirroring):
+//
+//	downloader.SetDateAfter("20240101")
+func SetDateAfter(date string) error {
+	if err := validateDate(date); err != nil {
+		return err
+	}
+	DateAfter = date
+	return nil
+}
+
+// SetDateBefore only downloads playlist items uploaded on or before date (YYYYMMDD).
+// An empty date removes the bound.
+func SetDateBefore(date string) error {
+	if err := validateDate(date); err != nil {
+		return err
+	}
+	DateBefore = date
+	return nil
+}
+
+// validateDate checks that date is empty or a valid YYYYMMDD date
+func validateDate(date string) error {
+	if date == "" {
+		return nil
+	}
+	if _, err := time.Parse("20060102", date); err != nil || len(date) != 8 {
+		return fmt.Errorf("invalid date %q: expected YYYYMMDD", date)
+	}
+	return nil
+}
+
+// dateRangeArgs returns the yt-dlp flags restricting downloads to an upload date range
+func dateRangeArgs(after string, before string) ([]string, error) {
+	var args []string
+	if after != "" {
+		if err := validateDate(after); err != nil {
+			return nil, err
+		}
+		args = append(args, "--dateafter", after)
+	}
+	if before != "" {
+		if err := validateDate(before); err != nil {
+			return nil, err
+		}
+		args = append(args, "--datebefore", before)
+	}
+	if after != "" && before != "" && after > before {
+		return nil, fmt.Errorf("date range is empty: %s is after %s", after, before)
+	}
+	return args, nil
+}
+
+// PlaylistEntry is one item of a playlist as listed by yt-dlp --flat-playlist
+type PlaylistEntry struct {
+	ID         string      `json:"id"`
+	URL        string      `json:"url"`
+	Title      string      `json:"title"`
+	Thumbnails []Thumbnail `json:"thumbnails"`
+}
+
+// Thumbnail is one of the thumbnail images yt-dlp lists for a video
+type Thumbnail struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// PlaylistInfo is the flat listing of a playlist
+type PlaylistInfo struct {
+	ID       string          `json:"id"`
+	Title    string          `json:"title"`
+	Uploader string          `json:"uploader"`
+	Entries  []PlaylistEntry `json:"entries"`
+}
+
+// SkippedItem is a playlist item yt-dlp skipped because of a filter
+type SkippedItem struct {
+	Index  int // 1-based position in the playlist
+	URL    string
+	Reason string
+}
+
+// ItemError is a playlist item that failed to download
+type ItemError struct {
+	Index int // 1-based position in the playlist
+	URL   string
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("playlist item %d (%s) failed: %v", e.Index, e.URL, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// PlaylistResult describes the outcome of DownloadPlaylist
+type PlaylistResult struct {
+	Title     string
+	Succeeded []string      // Absolute paths of downloaded files, in playlist order
+	Skipped   []SkippedItem // Items that did not pass the filter
+	Failed    []ItemError   // Items that failed; retry them by URL
+
+	entries []ManifestEntry // Every item in playlist order, for the manifest
+}
+
+// PlaylistOptions configures DownloadPlaylist.
+// The embedded VideoOptions apply to every item; if MatchFilter, DateAfter or
+// DateBefore are empty the package-level values are used. Progress receives
+// the overall progress of the playlist rather than per-item progress.
+type PlaylistOptions struct {
+	VideoOptions
+
+	// IndexPrefix names each file "<index> - <title>", with the playlist index
+	// zero-padded to the playlist length (e.g. "07 - Title" in a 42 item
+	// playlist) so files sort in playlist order on disk
+	IndexPrefix bool
+
+	// ManifestPath, if set, is where a JSON manifest of every item (URL, path,
+	// size, format and error) is written once the playlist is done
+	ManifestPath string
+
+	PlaylistSleep
+}
+
+// PlaylistSleep configures pauses between playlist items
+type PlaylistSleep struct {
+	// SleepInterval pauses between items to avoid rate limits on large playlists.
+	// If MaxSleepInterval is set, each pause is a random duration between the two.
+	SleepInterval    time.Duration
+	MaxSleepInterval time.Duration
+}
+
+// validateSleep checks the playlist sleep settings
+func (o PlaylistSleep) validateSleep() error {
+	if o.SleepInterval < 0 || o.MaxSleepInterval < 0 {
+		return fmt.Errorf("sleep intervals must not be negative")
+	}
+	if o.MaxSleepInterval != 0 && o.MaxSleepInterval < o.SleepInterval {
+		return fmt.Errorf("max sleep interval (%s) is less than sleep interval (%s)", o.MaxSleepInterval, o.SleepInterval)
+	}
+	return nil
+}
+
+// sleepDuration returns how long to pause before the next item
+func (o PlaylistSleep) sleepDuration() time.Duration {
+	if o.MaxSleepInterval <= o.SleepInterval {
+		return o.SleepInterval
+	}
+	return o.SleepInterval + time.Duration(rand.Int63n(int64(o.MaxSleepInterval-o.SleepInterval)+1))
+}
+
+// pauseBetweenItems pauses runPlaylist between items; a variable so the wait
+// can be swapped out
+var pauseBetweenItems = sleepContext
+
+// sleepContext pauses for d, returning early with the context's error if it is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// playlistFilename returns the IndexPrefix file name of the item at 1-based index
+func playlistFilename(index int, total int, title string) string {
+	return fmt.Sprintf("%0*d - %s", indexWidth(total), index, title)
+}
+
+// indexWidth returns the number of digits needed for indexes up to total (at least 2)
+func indexWidth(total int) int {
+	width := len(strconv.Itoa(total))
+	if width < 2 {
+		width = 2
+	}
+	return width
+}
+
+// GetPlaylistInfo lists the items of a playlist without downloading them
+func GetPlaylistInfo(ctx context.Context, url string) (*PlaylistInfo, error) {
+	// Auto-install yt-dlp if needed (only happens once)
+	if err := ensureYTDLPInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	args := []string{
+		"--flat-playlist",
+		"--dump-single-json",
+		"--no-warnings",
+		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"--referer", "https://www.youtube.com/",
+	}
+	args = append(args, metadataLanguageArgs()...)
+	cmd := ytdlpCommand(ctx, append(args, url)...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to list playlist: %w", newDownloadError(cmd, "playlist", string(exitErr.Stderr), err))
+		}
+		return nil, fmt.Errorf("failed to execute yt-dlp: %w", err)
+	}
+
+	var info PlaylistInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist JSON: %w", err)
+	}
+
+	// Flat entries from some extractors only carry an ID
+	for i := range info.Entries {
+		if info.Entries[i].URL == "" && info.Entries[i].ID != "" {
+			info.Entries[i].URL = "https://www.youtube.com/watch?v=" + info.Entries[i].ID
+		}
+	}
+
+	return &info, nil
+}
+
+// DownloadPlaylist downloads every item of a playlist, one at a time.
+// Items rejected by the match filter or date range are reported in PlaylistResult.Skipped,
+// and items that failed in PlaylistResult.Failed. An error is only returned if
+// every attempted item failed or ctx was cancelled.
+//
+// Example:
+//
+//	downloader.SetMatchFilter("duration < 600")
+//	result, err := downloader.DownloadPlaylist(ctx, playlistURL, downloader.PlaylistOptions{
+//	    VideoOptions: downloader.VideoOptions{OutputDir: "./playlist"},
+//	})
+func DownloadPlaylist(ctx context.Context, url string, opts PlaylistOptions) (*PlaylistResult, error) {
+	if err := opts.validateSleep(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 6*time.Hour)
+	defer cancel()
+
+	info, err := GetPlaylistInfo(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	itemOpts := opts.VideoOptions
+	if itemOpts.MatchFilter == "" {
+		itemOpts.MatchFilter = MatchFilter
+	}
+	if itemOpts.DateAfter == "" {
+		itemOpts.DateAfter = DateAfter
+	}
+	if itemOpts.DateBefore == "" {
+		itemOpts.DateBefore = DateBefore
+	}
+
+	result, err := runPlaylist(ctx, info, opts.PlaylistSleep, opts.Progress, func(index int, entry PlaylistEntry, progress ProgressCallback) (string, error) {
+		entryOpts := itemOpts
+		entryOpts.Progress = progress
+		if opts.IndexPrefix {
+			entryOpts.Filename = playlistFilename(index, len(info.Entries), entryTitle(entry))
+		}
+
+		download, err := DownloadVideoWithOptions(ctx, entry.URL, entryOpts)
+		if err != nil {
+			return "", err
+		}
+		return download.Path, nil
+	})
+	return result, finishPlaylist(opts.ManifestPath, url, result, err)
+}
+
+// AudioPlaylistOptions configures DownloadAudioPlaylist.
+// The embedded AudioOptions apply to every track; OutputDir is the library root
+// and Progress receives the overall progress of the playlist.
+type AudioPlaylistOptions struct {
+	AudioOptions
+
+	// ManifestPath, if set, is where a JSON manifest of every track is
+	// written once the playlist is done (see PlaylistOptions.ManifestPath)
+	ManifestPath string
+
+	PlaylistSleep
+}
+
+// DownloadAudioPlaylist downloads a music playlist as an album, one track at a time,
+// organized as "<OutputDir>/<Artist>/<Album>/<NN> - <Title>.<ext>". The artist is
+// the playlist uploader and the album the playlist title; track numbers come from
+// the playlist index, zero-padded to the playlist length.
+//
+// Example:
+//
+//	result, err := downloader.DownloadAudioPlaylist(ctx, albumURL, downloader.AudioPlaylistOptions{
+//	    AudioOptions: downloader.AudioOptions{Format: "m4a", OutputDir: "./music"},
+//	})
+func DownloadAudioPlaylist(ctx context.Context, url string, opts AudioPlaylistOptions) (*PlaylistResult, error) {
+	if err := opts.validateSleep(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 6*time.Hour)
+	defer cancel()
+
+	info, err := GetPlaylistInfo(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	trackOpts := opts.AudioOptions
+	trackOpts.OutputDir = albumDir(opts.OutputDir, info)
+
+	result, err := runPlaylist(ctx, info, opts.PlaylistSleep, opts.Progress, func(index int, entry PlaylistEntry, progress ProgressCallback) (string, error) {
+		entryOpts := trackOpts
+		entryOpts.Progress = progress
+		entryOpts.Filename = playlistFilename(index, len(info.Entries), entryTitle(entry))
+
+		download, err := DownloadAudioWithOptions(ctx, entry.URL, entryOpts)
+		if err != nil {
+			return "", err
+		}
+		return download.Path, nil
+	})
+	return result, finishPlaylist(opts.ManifestPath, url, result, err)
+}
+
+// albumDir returns "<root>/<Artist>/<Album>" for a playlist, with both
+// components sanitized so playlist metadata can never escape root
+func albumDir(root string, info *PlaylistInfo) string {
+	artist := sanitizePathComponent(info.Uploader, "Unknown Artist")
+	album := sanitizePathComponent(info.Title, "Unknown Album")
+	return filepath.Join(root, artist, album)
+}
+
+// sanitizePathComponent makes name safe to use as a single path component,
+// returning fallback if nothing usable is left
+func sanitizePathComponent(name string, fallback string) string {
+	name = strings.TrimSpace(filenameReplacer.Replace(name))
+	// Leading/trailing dots would allow "." and "..", and Windows drops trailing dots
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// entryTitle returns the title of a playlist entry, or its ID if it has none
+func entryTitle(entry PlaylistEntry) string {
+	if entry.Title != "" {
+		return entry.Title
+	}
+	return entry.ID
+}
+
+// finishPlaylist writes the manifest of a finished (or interrupted) playlist
+// download if manifestPath is set, adding any write failure to err
+func finishPlaylist(manifestPath string, url string, result *PlaylistResult, err error) error {
+	if manifestPath == "" || result == nil {
+		return err
+	}
+	if manifestErr := writeManifest(manifestPath, url, result); manifestErr != nil {
+		return errors.Join(err, manifestErr)
+	}
+	return err
+}
+
+// runPlaylist calls download for every entry in order (index is 1-based),
+// pausing between items as configured (but not after skipped ones). If
+// progressCb is set, each item gets a callback feeding one overall progress
+// stream (see AggregateProgress).
+// Items rejected by a filter are recorded as skipped and failing items as
+// failed; the error is non-nil only if nothing succeeded and something failed,
+// or if ctx is done.
+func runPlaylist(ctx context.Context, info *PlaylistInfo, sleep PlaylistSleep, progressCb ProgressCallback,
+	download func(index int, entry PlaylistEntry, progress ProgressCallback) (string, error)) (*PlaylistResult, error) {
+	var aggregate *AggregateProgress
+	if progressCb != nil {
+		aggregate = NewAggregateProgress(len(info.Entries), progressCb)
+	}
+
+	result := &PlaylistResult{Title: info.Title}
+	skippedLast := false
+	for i, entry := range info.Entries {
+		// Skipped items download nothing, so there is nothing to pause after
+		if i > 0 && !skippedLast {
+			if err := pauseBetweenItems(ctx, sleep.sleepDuration()); err != nil {
+				return result, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		var itemProgress ProgressCallback
+		if aggregate != nil {
+			itemProgress = aggregate.Item(i)
+		}
+		path, err := download(i+1, entry, itemProgress)
+		if aggregate != nil {
+			aggregate.Complete(i)
+		}
+		var skipped *SkippedError
+		skippedLast = errors.As(err, &skipped)
+		if skippedLast {
+			result.Skipped = append(result.Skipped, SkippedItem{
+				Index:  i + 1,
+				URL:    entry.URL,
+				Reason: skipped.Reason,
+			})
+			result.entries = append(result.entries, ManifestEntry{
+				Index: i + 1, URL: entry.URL, Status: ManifestSkipped, Error: skipped.Reason,
+			})
+			continue
+		}
+		if err != nil {
+			// The whole playlist is bounded by ctx; stop instead of failing every remaining item
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return result, ctxErr
+			}
+			result.Failed = append(result.Failed, ItemError{Index: i + 1, URL: entry.URL, Err: err})
+			result.entries = append(result.entries, ManifestEntry{
+				Index: i + 1, URL: entry.URL, Status: ManifestFailed, Error: err.Error(),
+			})
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, path)
+		result.entries = append(result.entries, ManifestEntry{
+			Index: i + 1, URL: entry.URL, Status: ManifestSucceeded, Path: path,
+		})
+	}
+
+	if len(result.Succeeded) == 0 && len(result.Failed) > 0 {
+		errs := make([]error, len(result.Failed))
+		for i := range result.Failed {
+			errs[i] = &result.Failed[i]
+		}
+		return result, fmt.Errorf("all %d playlist items failed: %w", len(result.Failed), errors.Join(errs...))
+	}
+	return result, nil
+}
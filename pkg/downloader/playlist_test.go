@@ -0,0 +1,394 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testPlaylist is the --flat-playlist listing served by playlistScript
+const testPlaylist = `{"id":"PL1","title":"Mix","uploader":"Band","entries":[` +
+	`{"id":"aaaaaaaaaaa","title":"First"},` +
+	`{"id":"bbbbbbbbbbb","title":"Second"},` +
+	`{"id":"ccccccccccc","title":"Third"}]}`
+
+// playlistScript is a fake yt-dlp listing testPlaylist and then running
+// perItem for item downloads, with $url set to the item's URL
+func playlistScript(logLine string, perItem string) string {
+	return logLine + `
+for a in "$@"; do url="$a"; done
+case "$*" in *--flat-playlist*) echo '` + testPlaylist + `'; exit 0;; esac
+` + perItem
+}
+
+func TestParseSkipReason(t *testing.T) {
+	tests := []struct{ line, want string }{
+		{"[download] Some title does not pass filter (duration < 600), skipping ..", "duration < 600"},
+		{"[download] Title does not pass filter !is_live, skipping ..", "!is_live"},
+		{"[download] 2020-01-01 upload date is not in range 2024-01-01 - 9999-12-31", "2020-01-01 upload date is not in range 2024-01-01 - 9999-12-31"},
+		{"[download]  45.3% of 12.34MiB at 1.23MiB/s ETA 00:10", ""},
+	}
+	for _, tt := range tests {
+		if got := parseSkipReason(tt.line); got != tt.want {
+			t.Errorf("parseSkipReason(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSetMatchFilter(t *testing.T) {
+	setForTest(t, &MatchFilter, "")
+	if err := SetMatchFilter("   "); err == nil {
+		t.Error("empty expression accepted")
+	}
+	if err := SetMatchFilter(" duration < 600 "); err != nil || MatchFilter != "duration < 600" {
+		t.Errorf("SetMatchFilter: err %v, MatchFilter %q", err, MatchFilter)
+	}
+	ClearMatchFilter()
+	if MatchFilter != "" {
+		t.Errorf("ClearMatchFilter left %q", MatchFilter)
+	}
+}
+
+func TestDownloadPlaylistMatchFilter(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, `case "$url" in
+*bbbbbbbbbbb) echo "[download] Second does not pass filter (duration < 600), skipping .."; exit 0;;
+esac
+`+writeOutput("mp4")))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &MatchFilter, "duration < 600")
+
+	result, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Succeeded) != 2 {
+		t.Errorf("succeeded %v, want 2 items", result.Succeeded)
+	}
+	want := SkippedItem{Index: 2, URL: "https://www.youtube.com/watch?v=bbbbbbbbbbb", Reason: "duration < 600"}
+	if len(result.Skipped) != 1 || result.Skipped[0] != want {
+		t.Errorf("skipped %+v, want [%+v]", result.Skipped, want)
+	}
+	for _, call := range calls()[1:] {
+		if argValue(call, "--match-filter") != "duration < 600" {
+			t.Errorf("item download without the match filter: %v", call)
+		}
+	}
+}
+
+func TestDownloadVideoSkippedByFilter(t *testing.T) {
+	useFakeYTDLP(t, `echo "[download] Title does not pass filter (view_count > 1000), skipping .."`)
+	useFakeFFMPEG(t, "exit 0")
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir:   t.TempDir(),
+		MatchFilter: "view_count > 1000",
+	})
+	var skipped *SkippedError
+	if !errors.Is(err, ErrSkippedByFilter) || !errors.As(err, &skipped) || skipped.Reason != "view_count > 1000" {
+		t.Errorf("err = %v, want a SkippedError for view_count > 1000", err)
+	}
+}
+
+func TestDateRangeArgs(t *testing.T) {
+	tests := []struct {
+		after, before string
+		want          []string
+		wantErr       bool
+	}{
+		{"", "", nil, false},
+		{"20240101", "", []string{"--dateafter", "20240101"}, false},
+		{"", "20241231", []string{"--datebefore", "20241231"}, false},
+		{"20240101", "20241231", []string{"--dateafter", "20240101", "--datebefore", "20241231"}, false},
+		{"20240101", "20240101", []string{"--dateafter", "20240101", "--datebefore", "20240101"}, false},
+		{"20241231", "20240101", nil, true}, // Empty range
+		{"2024-01-01", "", nil, true},
+		{"20241301", "", nil, true}, // No month 13
+		{"", "2024011", nil, true},
+		{"now-7days", "", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := dateRangeArgs(tt.after, tt.before)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("dateRangeArgs(%q, %q) = %v, %v; want %v (error %v)", tt.after, tt.before, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetDateAfter(t *testing.T) {
+	setForTest(t, &DateAfter, "")
+	setForTest(t, &DateBefore, "")
+	if err := SetDateAfter("20240230"); err == nil {
+		t.Error("invalid date accepted")
+	}
+	if err := SetDateAfter("20240101"); err != nil || DateAfter != "20240101" {
+		t.Errorf("SetDateAfter: err %v, DateAfter %q", err, DateAfter)
+	}
+	if err := SetDateBefore("20241231"); err != nil || DateBefore != "20241231" {
+		t.Errorf("SetDateBefore: err %v, DateBefore %q", err, DateBefore)
+	}
+	if err := SetDateAfter(""); err != nil || DateAfter != "" {
+		t.Errorf("clearing DateAfter: err %v, DateAfter %q", err, DateAfter)
+	}
+}
+
+func TestDownloadPlaylistDateRange(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, writeOutput("mp4")))
+	useFakeFFMPEG(t, "exit 0")
+	setForTest(t, &DateAfter, "20240101")
+	setForTest(t, &DateBefore, "")
+
+	_, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: t.TempDir(), DateBefore: "20241231"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range calls()[1:] {
+		if argValue(call, "--dateafter") != "20240101" || argValue(call, "--datebefore") != "20241231" {
+			t.Errorf("item download without the date range: %v", call)
+		}
+	}
+
+	if _, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: t.TempDir(), DateBefore: "20231231"},
+	}); err == nil {
+		t.Error("empty date range accepted")
+	}
+}
+
+func TestPlaylistFilename(t *testing.T) {
+	tests := []struct {
+		index, total int
+		want         string
+	}{
+		{1, 3, "01 - Title"},
+		{9, 9, "09 - Title"},
+		{10, 42, "10 - Title"},
+		{7, 100, "007 - Title"},
+		{100, 100, "100 - Title"},
+		{42, 1000, "0042 - Title"},
+	}
+	for _, tt := range tests {
+		if got := playlistFilename(tt.index, tt.total, "Title"); got != tt.want {
+			t.Errorf("playlistFilename(%d, %d) = %q, want %q", tt.index, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadPlaylistIndexPrefix(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, writeOutput("mp4")))
+	useFakeFFMPEG(t, "exit 0")
+	dir := t.TempDir()
+
+	result, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: dir},
+		IndexPrefix:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, title := range []string{"01 - First", "02 - Second", "03 - Third"} {
+		if got := filepath.Base(argValue(calls()[i+1], "-o")); got != title+".%(ext)s" {
+			t.Errorf("item %d template %q, want %q", i+1, got, title+".%(ext)s")
+		}
+	}
+	if len(result.Succeeded) != 3 || filepath.Base(result.Succeeded[0]) != "01 - First.mp4" {
+		t.Errorf("succeeded %+v", result.Succeeded)
+	}
+}
+
+func TestPlaylistSleepValidation(t *testing.T) {
+	tests := []struct {
+		sleep PlaylistSleep
+		ok    bool
+	}{
+		{PlaylistSleep{}, true},
+		{PlaylistSleep{SleepInterval: time.Second}, true},
+		{PlaylistSleep{SleepInterval: time.Second, MaxSleepInterval: 5 * time.Second}, true},
+		{PlaylistSleep{MaxSleepInterval: time.Second}, true},
+		{PlaylistSleep{SleepInterval: -time.Second}, false},
+		{PlaylistSleep{SleepInterval: 5 * time.Second, MaxSleepInterval: time.Second}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.sleep.validateSleep(); (err == nil) != tt.ok {
+			t.Errorf("validateSleep(%+v) = %v", tt.sleep, err)
+		}
+	}
+}
+
+func TestPlaylistSleepDuration(t *testing.T) {
+	fixed := PlaylistSleep{SleepInterval: 2 * time.Second}
+	if got := fixed.sleepDuration(); got != 2*time.Second {
+		t.Errorf("sleepDuration() = %v, want 2s", got)
+	}
+	random := PlaylistSleep{SleepInterval: time.Second, MaxSleepInterval: 3 * time.Second}
+	for range 100 {
+		if got := random.sleepDuration(); got < time.Second || got > 3*time.Second {
+			t.Fatalf("sleepDuration() = %v, want between 1s and 3s", got)
+		}
+	}
+}
+
+func TestSleepContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := sleepContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("cancelled sleep did not return promptly")
+	}
+}
+
+func TestDownloadPlaylistSleepsBetweenItems(t *testing.T) {
+	logLine, _ := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, `case "$url" in
+*bbbbbbbbbbb) echo "[download] Second does not pass filter (duration < 600), skipping .."; exit 0;;
+esac
+`+writeOutput("mp4")))
+	useFakeFFMPEG(t, "exit 0")
+	var pauses []time.Duration
+	setForTest(t, &pauseBetweenItems, func(ctx context.Context, d time.Duration) error {
+		pauses = append(pauses, d)
+		return nil
+	})
+
+	_, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions:  VideoOptions{OutputDir: t.TempDir()},
+		PlaylistSleep: PlaylistSleep{SleepInterval: 3 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One pause after the first item; the skipped second item needs none
+	if len(pauses) != 1 || pauses[0] != 3*time.Second {
+		t.Errorf("paused %v, want [3s]", pauses)
+	}
+
+	if _, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions:  VideoOptions{OutputDir: t.TempDir()},
+		PlaylistSleep: PlaylistSleep{SleepInterval: -time.Second},
+	}); err == nil {
+		t.Error("negative sleep interval accepted")
+	}
+}
+
+func TestSanitizePathComponent(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Band", "Band"},
+		{"AC/DC", "AC_DC"},
+		{"..", "Unknown"},
+		{"../../etc", "_.._etc"},
+		{`C:\Windows`, "C__Windows"},
+		{"  Trailing dots... ", "Trailing dots"},
+		{".hidden", "hidden"},
+		{"100% Hits", "100_ Hits"},
+		{"", "Unknown"},
+		{" . ", "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := sanitizePathComponent(tt.name, "Unknown"); got != tt.want {
+			t.Errorf("sanitizePathComponent(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAlbumDir(t *testing.T) {
+	root := filepath.Join("music", "library")
+	tests := []struct {
+		info PlaylistInfo
+		want string
+	}{
+		{PlaylistInfo{Uploader: "Band", Title: "Mix"}, filepath.Join(root, "Band", "Mix")},
+		{PlaylistInfo{Uploader: "..", Title: "../.."}, filepath.Join(root, "Unknown Artist", "_")},
+		{PlaylistInfo{}, filepath.Join(root, "Unknown Artist", "Unknown Album")},
+	}
+	for _, tt := range tests {
+		got := albumDir(root, &tt.info)
+		if got != tt.want {
+			t.Errorf("albumDir(%+v) = %q, want %q", tt.info, got, tt.want)
+		}
+		if rel, err := filepath.Rel(root, got); err != nil || !filepath.IsLocal(rel) {
+			t.Errorf("albumDir(%+v) = %q escapes %q", tt.info, got, root)
+		}
+	}
+}
+
+func TestDownloadAudioPlaylistTemplate(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, writeOutput("m4a")))
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg))
+	root := t.TempDir()
+
+	result, err := DownloadAudioPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", AudioPlaylistOptions{
+		AudioOptions: AudioOptions{Format: "m4a", OutputDir: root},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	album := filepath.Join(root, "Band", "Mix")
+	for i, title := range []string{"01 - First", "02 - Second", "03 - Third"} {
+		if got, want := argValue(calls()[i+1], "-o"), filepath.Join(album, title+".%(ext)s"); got != want {
+			t.Errorf("track %d template %q, want %q", i+1, got, want)
+		}
+	}
+	if len(result.Succeeded) != 3 || result.Succeeded[0] != filepath.Join(album, "01 - First.m4a") {
+		t.Errorf("succeeded %v", result.Succeeded)
+	}
+}
+
+func TestDownloadPlaylistPartialFailure(t *testing.T) {
+	logLine, _ := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, `case "$url" in
+*bbbbbbbbbbb) echo "ERROR: [youtube] bbbbbbbbbbb: Private video" >&2; exit 1;;
+esac
+`+writeOutput("mp4")))
+	useFakeFFMPEG(t, "exit 0")
+
+	result, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("err = %v, want nil with some items downloaded", err)
+	}
+	if len(result.Succeeded) != 2 {
+		t.Errorf("succeeded %v, want items 1 and 3", result.Succeeded)
+	}
+	if len(result.Failed) != 1 {
+		t.Fatalf("failed %+v, want item 2", result.Failed)
+	}
+	failed := result.Failed[0]
+	if failed.Index != 2 || failed.URL != "https://www.youtube.com/watch?v=bbbbbbbbbbb" || !errors.Is(failed.Err, ErrUnavailable) {
+		t.Errorf("failed item %+v, want item 2 unavailable", failed)
+	}
+}
+
+func TestDownloadPlaylistAllItemsFail(t *testing.T) {
+	logLine, _ := argsLog(t)
+	useFakeYTDLP(t, playlistScript(logLine, `echo "ERROR: [youtube] x: Private video" >&2
+exit 1`))
+	useFakeFFMPEG(t, "exit 0")
+
+	result, err := DownloadPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1", PlaylistOptions{
+		VideoOptions: VideoOptions{OutputDir: t.TempDir()},
+	})
+	if err == nil {
+		t.Fatal("no error when every item failed")
+	}
+	if result == nil || len(result.Failed) != 3 || len(result.Succeeded) != 0 {
+		t.Fatalf("result %+v, want 3 failures", result)
+	}
+	var itemErr *ItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 1 || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want the joined item errors", err)
+	}
+}
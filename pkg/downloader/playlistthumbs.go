@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"youtube-api-server/pkg/internal/installer"
+)
+
+// thumbnailWorkers is how many thumbnails DownloadPlaylistThumbnails fetches at once
+const thumbnailWorkers = 8
+
+// DownloadPlaylistThumbnails saves the thumbnail of every playlist item to
+// outputDir as "<video ID>.<ext>" without downloading any media, using the
+// thumbnail URLs of the flat playlist listing. Useful for grid previews.
+// Returns a map of video ID to local path; items without a thumbnail, or
+// whose thumbnail could not be fetched, are left out. The error is non-nil
+// only if listing the playlist failed, or if no thumbnail could be fetched
+// and at least one fetch failed.
+//
+// Example:
+//
+//	thumbs, err := downloader.DownloadPlaylistThumbnails(ctx, playlistURL, "./thumbs")
+func DownloadPlaylistThumbnails(ctx context.Context, url string, outputDir string) (map[string]string, error) {
+	info, err := GetPlaylistInfo(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := makeOutputDir(outputDir); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return fetchThumbnails(ctx, info.Entries, outputDir)
+}
+
+// fetchThumbnails downloads the best thumbnail of each entry concurrently
+func fetchThumbnails(ctx context.Context, entries []PlaylistEntry, outputDir string) (map[string]string, error) {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		paths = make(map[string]string)
+		errs  []error
+		slots = make(chan struct{}, thumbnailWorkers)
+	)
+	for _, entry := range entries {
+		thumbURL := bestThumbnail(entry.Thumbnails)
+		if thumbURL == "" || entry.ID == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(id string, thumbURL string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			dst := filepath.Join(outputDir, sanitizePathComponent(id, "thumbnail")+thumbnailExt(thumbURL))
+			err := fetchThumbnail(ctx, thumbURL, dst)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("thumbnail of %s: %w", id, err))
+				return
+			}
+			paths[id] = dst
+		}(entry.ID, thumbURL)
+	}
+	wg.Wait()
+
+	if len(paths) == 0 && len(errs) > 0 {
+		return paths, fmt.Errorf("all %d thumbnail downloads failed: %w", len(errs), errors.Join(errs...))
+	}
+	return paths, nil
+}
+
+// bestThumbnail returns the URL of the largest thumbnail, or "" if there is none.
+// yt-dlp lists thumbnails from worst to best, so without dimensions the last wins.
+func bestThumbnail(thumbnails []Thumbnail) string {
+	best := -1
+	for i, thumb := range thumbnails {
+		if thumb.URL == "" {
+			continue
+		}
+		if best < 0 || thumb.Width*thumb.Height >= thumbnails[best].Width*thumbnails[best].Height {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return thumbnails[best].URL
+}
+
+// thumbnailExt returns the image extension of a thumbnail URL, defaulting to .jpg
+func thumbnailExt(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ".jpg"
+	}
+	switch ext := strings.ToLower(path.Ext(parsed.Path)); ext {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		return ext
+	}
+	return ".jpg"
+}
+
+// fetchThumbnail downloads thumbURL to dst through a partial file
+func fetchThumbnail(ctx context.Context, thumbURL string, dst string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thumbURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := installer.HTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	partial := partialPath(dst)
+	file, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, NewRateLimitedReaderWithContext(ctx, resp.Body, 0))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partial)
+		return diskFullError(err)
+	}
+	if err := commitPartial(partial, dst); err != nil {
+		return err
+	}
+	return applyOutputFileMode(dst)
+}
@@ -0,0 +1,124 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// thumbnailServer serves "image:<path>" for every path except /missing/*
+func thumbnailServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/missing/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("image:" + r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadPlaylistThumbnails(t *testing.T) {
+	images := thumbnailServer(t)
+	// A second host, as thumbnails are often spread over CDN hosts
+	other := thumbnailServer(t)
+	listing := `{"id":"PL1","title":"Mix","entries":[` +
+		`{"id":"aaaaaaaaaaa","thumbnails":[{"url":"` + images.URL + `/a/small.jpg","width":120,"height":90},{"url":"` + images.URL + `/a/large.webp","width":1280,"height":720}]},` +
+		`{"id":"bbbbbbbbbbb","thumbnails":[{"url":"` + other.URL + `/b/default"}]},` +
+		`{"id":"ccccccccccc","thumbnails":[]},` +
+		`{"id":"ddddddddddd","thumbnails":[{"url":"` + images.URL + `/missing/d.jpg"}]}]}`
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\necho '"+listing+"'")
+	dir := filepath.Join(t.TempDir(), "thumbs")
+
+	thumbs, err := DownloadPlaylistThumbnails(context.Background(), "https://www.youtube.com/playlist?list=PL1", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"aaaaaaaaaaa": filepath.Join(dir, "aaaaaaaaaaa.webp"),
+		"bbbbbbbbbbb": filepath.Join(dir, "bbbbbbbbbbb.jpg"),
+	}
+	if len(thumbs) != len(want) {
+		t.Errorf("thumbnails = %v, want %v", thumbs, want)
+	}
+	contents := map[string]string{"aaaaaaaaaaa": "image:/a/large.webp", "bbbbbbbbbbb": "image:/b/default"}
+	for id, path := range want {
+		if thumbs[id] != path {
+			t.Errorf("thumbnail of %s at %q, want %q", id, thumbs[id], path)
+		}
+		if data, _ := os.ReadFile(path); string(data) != contents[id] {
+			t.Errorf("%s holds %q, want %q", path, data, contents[id])
+		}
+	}
+
+	// Only the listing ran through yt-dlp; no media was downloaded
+	if len(calls()) != 1 || !strings.Contains(strings.Join(calls()[0], " "), "--flat-playlist") {
+		t.Errorf("yt-dlp calls %v, want a single flat listing", calls())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("output directory holds %v", entries)
+	}
+}
+
+func TestDownloadPlaylistThumbnailsAllFail(t *testing.T) {
+	images := thumbnailServer(t)
+	useFakeYTDLP(t, `echo '{"id":"PL1","entries":[{"id":"aaaaaaaaaaa","thumbnails":[{"url":"`+images.URL+`/missing/a.jpg"}]}]}'`)
+
+	thumbs, err := DownloadPlaylistThumbnails(context.Background(), "https://www.youtube.com/playlist?list=PL1", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("err = %v, want the failed fetch", err)
+	}
+	if len(thumbs) != 0 {
+		t.Errorf("thumbnails = %v", thumbs)
+	}
+}
+
+func TestDownloadPlaylistThumbnailsNoneListed(t *testing.T) {
+	useFakeYTDLP(t, `echo '{"id":"PL1","entries":[{"id":"aaaaaaaaaaa"},{"id":"bbbbbbbbbbb","thumbnails":[{"url":""}]}]}'`)
+
+	thumbs, err := DownloadPlaylistThumbnails(context.Background(), "https://www.youtube.com/playlist?list=PL1", t.TempDir())
+	if err != nil || len(thumbs) != 0 {
+		t.Errorf("thumbnails %v, err %v; want none without an error", thumbs, err)
+	}
+}
+
+func TestBestThumbnail(t *testing.T) {
+	tests := []struct {
+		thumbnails []Thumbnail
+		want       string
+	}{
+		{nil, ""},
+		{[]Thumbnail{{URL: ""}}, ""},
+		{[]Thumbnail{{URL: "a"}, {URL: "b"}}, "b"},
+		{[]Thumbnail{{URL: "big", Width: 1280, Height: 720}, {URL: "small", Width: 120, Height: 90}}, "big"},
+		{[]Thumbnail{{URL: "a", Width: 120, Height: 90}, {URL: "", Width: 1920, Height: 1080}}, "a"},
+	}
+	for _, tt := range tests {
+		if got := bestThumbnail(tt.thumbnails); got != tt.want {
+			t.Errorf("bestThumbnail(%+v) = %q, want %q", tt.thumbnails, got, tt.want)
+		}
+	}
+}
+
+func TestThumbnailExt(t *testing.T) {
+	tests := map[string]string{
+		"https://i.ytimg.com/vi/x/hqdefault.jpg":        ".jpg",
+		"https://i.ytimg.com/vi_webp/x/maxres.WEBP?s=1": ".webp",
+		"https://example.com/x.png":                     ".png",
+		"https://example.com/x":                         ".jpg",
+		"https://example.com/x.php?img=a.png":           ".jpg",
+		"%%":                                            ".jpg",
+	}
+	for thumbURL, want := range tests {
+		if got := thumbnailExt(thumbURL); got != want {
+			t.Errorf("thumbnailExt(%q) = %q, want %q", thumbURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// posterFormats are the containers where ffmpeg can attach the thumbnail as
+// an attached_pic video stream (cover art for video files)
+var posterFormats = map[string]bool{
+	"mp4": true,
+	"m4v": true,
+	"mov": true,
+}
+
+// validatePosterFormat returns an error if format cannot hold a poster image
+func validatePosterFormat(format string) error {
+	if !posterFormats[strings.ToLower(format)] {
+		return fmt.Errorf("a poster cannot be attached to %q (use mp4, m4v or mov)", format)
+	}
+	return nil
+}
+
+// posterArgs builds the ffmpeg arguments that copy every stream of video and
+// add cover as the poster. Downloads have a single video track, so the cover
+// is the second video stream.
+func posterArgs(video string, cover string, dst string) []string {
+	return []string{
+		"-i", video,
+		"-i", cover,
+		"-map", "0",
+		"-map", "1",
+		"-c", "copy",
+		"-disposition:v:1", "attached_pic",
+		"-y", dst,
+	}
+}
+
+// attachPoster replaces the video at path with a copy carrying cover as its poster
+func attachPoster(ctx context.Context, path string, cover string, progressCb ProgressCallback) error {
+	return convertToFile(ctx, path, progressCb, func(dst string) []string {
+		return posterArgs(path, cover, dst)
+	})
+}
@@ -0,0 +1,132 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPosterArgs(t *testing.T) {
+	got := posterArgs("in.mp4", "cover.jpg", "out.mp4")
+	want := []string{
+		"-i", "in.mp4", "-i", "cover.jpg",
+		"-map", "0", "-map", "1",
+		"-c", "copy",
+		"-disposition:v:1", "attached_pic",
+		"-y", "out.mp4",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("posterArgs = %q, want %q", got, want)
+	}
+}
+
+func TestValidatePosterFormat(t *testing.T) {
+	for _, format := range []string{"mp4", "M4V", "mov"} {
+		if err := validatePosterFormat(format); err != nil {
+			t.Errorf("validatePosterFormat(%q) = %v", format, err)
+		}
+	}
+	for _, format := range []string{"webm", "mkv", ""} {
+		if err := validatePosterFormat(format); err == nil {
+			t.Errorf("validatePosterFormat(%q) accepted", format)
+		}
+	}
+}
+
+// posterCall returns the ffmpeg call attaching a poster, or nil
+func posterCall(calls [][]string) []string {
+	for _, args := range calls {
+		if hasArgs(args, "-disposition:v:1", "attached_pic") {
+			return args
+		}
+	}
+	return nil
+}
+
+func TestDownloadVideoPoster(t *testing.T) {
+	ytdlpLog, ytdlpCalls := argsLog(t)
+	useFakeYTDLP(t, ytdlpLog+"\n"+writeOutput("mp4")+"\n"+writeOutput("jpg"))
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg))
+	dir := t.TempDir()
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:          "mp4",
+		OutputDir:       dir,
+		PosterThumbnail: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasArgs(ytdlpCalls()[0], coverArtArgs()...) {
+		t.Errorf("yt-dlp args %v, want the thumbnail written as jpg", ytdlpCalls()[0])
+	}
+	poster := posterCall(ffmpegCalls())
+	if poster == nil {
+		t.Fatalf("no poster attached: %v", ffmpegCalls())
+	}
+	if cover := poster[3]; filepath.Ext(cover) != ".jpg" || !hasArgs(poster, "-map", "1", "-c", "copy") {
+		t.Errorf("poster args %v", poster)
+	}
+	if data, _ := os.ReadFile(result.Path); string(data) != "converted" {
+		t.Errorf("%s holds %q, want the ffmpeg output", result.Path, data)
+	}
+	// Only the video is left; the thumbnail was consumed
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("output directory holds %v", entries)
+	}
+}
+
+func TestDownloadVideoPosterWithoutThumbnail(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("mp4"))
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg))
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:          "mp4",
+		OutputDir:       t.TempDir(),
+		PosterThumbnail: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posterCall(ffmpegCalls()) != nil {
+		t.Error("poster attached without a thumbnail")
+	}
+	if !slices.ContainsFunc(result.Warnings, func(w string) bool { return strings.Contains(w, "no poster") }) {
+		t.Errorf("warnings %v, want the missing poster reported", result.Warnings)
+	}
+}
+
+func TestDownloadVideoPosterValidation(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine)
+	useFakeFFMPEG(t, "exit 0")
+
+	for _, opts := range []VideoOptions{
+		{Format: "webm", PosterThumbnail: true},
+		{Format: "mp4", AudioOnly: true, PosterThumbnail: true},
+	} {
+		opts.OutputDir = t.TempDir()
+		if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", opts); err == nil {
+			t.Errorf("%+v accepted", opts)
+		}
+	}
+
+	withoutFFMPEG(t)
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:          "mp4",
+		OutputDir:       t.TempDir(),
+		PosterThumbnail: true,
+	})
+	if !errors.Is(err, ErrFFMPEGRequired) {
+		t.Errorf("err = %v, want ErrFFMPEGRequired", err)
+	}
+	if len(calls()) != 0 {
+		t.Errorf("yt-dlp ran for an invalid request: %v", calls())
+	}
+}
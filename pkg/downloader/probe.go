@@ -0,0 +1,196 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ffprobePath returns the ffprobe binary next to FFMPEGPath, or "ffprobe" from PATH
+func ffprobePath() string {
+	dir := filepath.Dir(FFMPEGPath)
+	if dir == "." {
+		return "ffprobe"
+	}
+
+	name := "ffprobe"
+	if strings.HasSuffix(strings.ToLower(FFMPEGPath), ".exe") {
+		name = "ffprobe.exe"
+	}
+	candidate := filepath.Join(dir, name)
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+	return "ffprobe"
+}
+
+// probeDuration returns the duration of a local media file in seconds using ffprobe
+func probeDuration(ctx context.Context, path string) (float64, error) {
+	cmd := newCommand(ctx, ffprobePath(),
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return 0, fmt.Errorf("ffprobe failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return 0, fmt.Errorf("failed to execute ffprobe: %w", err)
+	}
+
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse duration %q: %w", strings.TrimSpace(string(output)), err)
+	}
+	return duration, nil
+}
+
+// MediaInfo describes a local media file, as reported by ffprobe
+type MediaInfo struct {
+	FormatName string        `json:"format_name"` // e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	Duration   float64       `json:"duration"`    // Seconds
+	Streams    []MediaStream `json:"streams"`
+}
+
+// MediaStream is one stream of a MediaInfo
+type MediaStream struct {
+	CodecType  string `json:"codec_type"` // "video", "audio", "subtitle", ...
+	CodecName  string `json:"codec_name"` // e.g. "h264", "opus"
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+}
+
+// ProbeFile returns the container and stream details of a local media file using ffprobe
+func ProbeFile(ctx context.Context, path string) (*MediaInfo, error) {
+	cmd := newCommand(ctx, ffprobePath(),
+		"-v", "error",
+		"-show_entries", "format=format_name,duration:stream=codec_type,codec_name,width,height,sample_rate,channels",
+		"-of", "json",
+		path,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("ffprobe failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to execute ffprobe: %w", err)
+	}
+	return parseProbeOutput(output)
+}
+
+// parseProbeOutput parses `ffprobe -of json` output. ffprobe reports
+// numbers such as duration and sample_rate as strings.
+func parseProbeOutput(output []byte) (*MediaInfo, error) {
+	var raw struct {
+		Format struct {
+			FormatName string `json:"format_name"`
+			Duration   string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType  string `json:"codec_type"`
+			CodecName  string `json:"codec_name"`
+			Width      int    `json:"width"`
+			Height     int    `json:"height"`
+			SampleRate string `json:"sample_rate"`
+			Channels   int    `json:"channels"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	info := &MediaInfo{FormatName: raw.Format.FormatName}
+	info.Duration, _ = strconv.ParseFloat(raw.Format.Duration, 64)
+	for _, stream := range raw.Streams {
+		sampleRate, _ := strconv.Atoi(stream.SampleRate)
+		info.Streams = append(info.Streams, MediaStream{
+			CodecType:  stream.CodecType,
+			CodecName:  stream.CodecName,
+			Width:      stream.Width,
+			Height:     stream.Height,
+			SampleRate: sampleRate,
+			Channels:   stream.Channels,
+		})
+	}
+	return info, nil
+}
+
+// nonMediaExtensions are files yt-dlp may write next to a download that are never the download itself
+var nonMediaExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true,
+	".vtt": true, ".srt": true, ".ass": true, ".json": true, ".description": true,
+}
+
+// findMediaByTemplate returns the newest file named like the output template
+// (any extension) that ffprobe reports has an audio or video stream, or ""
+func findMediaByTemplate(temp string) string {
+	dir := filepath.Dir(temp)
+	prefix := strings.TrimSuffix(filepath.Base(temp), "%(ext)s")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	type candidate struct {
+		path    string
+		modTime time.Time
+	}
+	var candidates []candidate
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || isTempFile(name) ||
+			nonMediaExtensions[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{filepath.Join(dir, name), info.ModTime()})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].modTime.After(candidates[j].modTime)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, c := range candidates {
+		if isMediaFile(ctx, c.path) {
+			return c.path
+		}
+	}
+	return ""
+}
+
+// isMediaFile reports whether ffprobe finds an audio or video stream in path
+func isMediaFile(ctx context.Context, path string) bool {
+	output, err := newCommand(ctx, ffprobePath(),
+		"-v", "error",
+		"-show_entries", "stream=codec_type",
+		"-of", "csv=p=0",
+		path,
+	).Output()
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if kind := strings.TrimSpace(line); kind == "audio" || kind == "video" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// probeByContent is a fake ffprobe reporting a video stream for files
+// containing "media"
+const probeByContent = `for a in "$@"; do last="$a"; done
+grep -q media "$last" && echo video`
+
+func TestFindMediaByTemplate(t *testing.T) {
+	useFakeFFMPEG(t, "exit 0", probeByContent)
+	dir := t.TempDir()
+	temp := filepath.Join(dir, "video.%(ext)s")
+	now := time.Now()
+	files := []struct {
+		name    string
+		content string
+		age     time.Duration
+	}{
+		{"video.nut", "media", 3 * time.Minute}, // Older media file
+		{"video.mka", "media", 2 * time.Minute}, // The newest media file
+		{"video.dat", "garbage", time.Minute},   // Newer, but not media
+		{"video.info.json", "media", 0},         // Never media, despite the content
+		{"video.partial.mkv", "media", 0},       // Still being written
+		{"other.mkv", "media", 0},               // Another download
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := findMediaByTemplate(temp); got != filepath.Join(dir, "video.mka") {
+		t.Errorf("findMediaByTemplate() = %q, want video.mka", got)
+	}
+	// Known extensions are found without probing
+	if got := findDownloadedFile(temp, []string{"nut"}); got != filepath.Join(dir, "video.nut") {
+		t.Errorf("findDownloadedFile() = %q, want video.nut", got)
+	}
+	if got := findDownloadedFile(temp, []string{"mp4"}); got != filepath.Join(dir, "video.mka") {
+		t.Errorf("findDownloadedFile() = %q, want the probed video.mka", got)
+	}
+}
+
+func TestFindMediaByTemplateNothingFound(t *testing.T) {
+	useFakeFFMPEG(t, "exit 0", probeByContent)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "video.dat"), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := findMediaByTemplate(filepath.Join(dir, "video.%(ext)s")); got != "" {
+		t.Errorf("findMediaByTemplate() = %q, want nothing", got)
+	}
+}
+
+func TestDownloadUnusualContainer(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("nut"))
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg), probeByContent)
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:    "mkv",
+		OutputDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(result.Path, ".mkv") {
+		t.Errorf("path = %s, want the .nut download converted to mkv", result.Path)
+	}
+}
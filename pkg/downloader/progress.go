@@ -0,0 +1,91 @@
+package downloader
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ProgressInterval is the minimum time between progress callbacks fired while
+// parsing yt-dlp output (default: 0, every update). Can be set using SetProgressInterval()
+var ProgressInterval time.Duration
+
+// SetProgressInterval coalesces progress events so the callback fires at most
+// once per interval. The final 100% event is always delivered.
+// Use this when forwarding progress to UIs or WebSocket clients.
+//
+// Example:
+//
+//	downloader.SetProgressInterval(500 * time.Millisecond)
+func SetProgressInterval(interval time.Duration) {
+	if interval >= 0 {
+		ProgressInterval = interval
+	}
+}
+
+// progressPattern matches yt-dlp progress lines such as
+//
+//	[download]  45.3% of ~ 12.34MiB at  1.23MiB/s ETA 00:10
+var progressPattern = regexp.MustCompile(`\[download\]\s+(\d+(?:\.\d+)?)%(?:\s+of\s+~?\s*(\d+(?:\.\d+)?)([KMGT]i?B|B))?`)
+
+// sizeUnits maps yt-dlp size units to bytes
+var sizeUnits = map[string]float64{
+	"B":   1,
+	"KiB": 1024,
+	"MiB": 1024 * 1024,
+	"GiB": 1024 * 1024 * 1024,
+	"TiB": 1024 * 1024 * 1024 * 1024,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+}
+
+// parseProgressLine extracts percentage and sizes from a yt-dlp progress line
+func parseProgressLine(line string, stage string) (DownloadProgress, bool) {
+	m := progressPattern.FindStringSubmatch(line)
+	if m == nil {
+		return DownloadProgress{}, false
+	}
+
+	progress := DownloadProgress{Stage: stage}
+	progress.Percentage, _ = strconv.ParseFloat(m[1], 64)
+	if m[2] != "" {
+		size, _ := strconv.ParseFloat(m[2], 64)
+		progress.TotalBytes = int64(size * sizeUnits[m[3]])
+		progress.BytesDownloaded = int64(float64(progress.TotalBytes) * progress.Percentage / 100)
+	}
+	return progress, true
+}
+
+// progressThrottle drops progress events arriving less than interval after
+// the previous delivered one, except events at 100%
+type progressThrottle struct {
+	cb       ProgressCallback
+	interval time.Duration
+	mu       sync.Mutex
+	last     time.Time
+}
+
+func newProgressThrottle(cb ProgressCallback, interval time.Duration) *progressThrottle {
+	return &progressThrottle{cb: cb, interval: interval}
+}
+
+// emit delivers progress if the interval has elapsed or it is the final event
+func (t *progressThrottle) emit(progress DownloadProgress) {
+	if t.cb == nil {
+		return
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	if progress.Percentage < 100 && t.interval > 0 && !t.last.IsZero() && now.Sub(t.last) < t.interval {
+		t.mu.Unlock()
+		return
+	}
+	t.last = now
+	t.mu.Unlock()
+
+	t.cb(progress)
+}
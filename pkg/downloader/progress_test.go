@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want DownloadProgress
+		ok   bool
+	}{
+		{"[download]  45.0% of ~ 10.00MiB at  1.23MiB/s ETA 00:10",
+			DownloadProgress{Percentage: 45, TotalBytes: 10 * 1024 * 1024, BytesDownloaded: 4718592, Stage: "downloading"}, true},
+		{"[download] 100% of 2.00KB", DownloadProgress{Percentage: 100, TotalBytes: 2000, BytesDownloaded: 2000, Stage: "downloading"}, true},
+		{"[download]  12.5%", DownloadProgress{Percentage: 12.5, Stage: "downloading"}, true},
+		{"[info] Downloading 1 format(s): 137+140", DownloadProgress{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseProgressLine(tt.line, "downloading")
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseProgressLine(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestProgressThrottle(t *testing.T) {
+	var delivered []float64
+	throttle := newProgressThrottle(func(p DownloadProgress) {
+		delivered = append(delivered, p.Percentage)
+	}, time.Hour)
+
+	for i := 0; i <= 100; i++ {
+		throttle.emit(DownloadProgress{Percentage: float64(i)})
+	}
+	// The first event and the final 100% one get through within the interval
+	if len(delivered) != 2 || delivered[0] != 0 || delivered[1] != 100 {
+		t.Errorf("delivered %v, want [0 100]", delivered)
+	}
+}
+
+func TestProgressThrottleDisabled(t *testing.T) {
+	count := 0
+	throttle := newProgressThrottle(func(DownloadProgress) { count++ }, 0)
+	for i := 0; i < 50; i++ {
+		throttle.emit(DownloadProgress{Percentage: float64(i)})
+	}
+	if count != 50 {
+		t.Errorf("delivered %d events, want all 50", count)
+	}
+	newProgressThrottle(nil, time.Second).emit(DownloadProgress{}) // No callback must not panic
+}
+
+func TestSetProgressInterval(t *testing.T) {
+	setForTest(t, &ProgressInterval, 0)
+	SetProgressInterval(250 * time.Millisecond)
+	SetProgressInterval(-time.Second) // Ignored
+	if ProgressInterval != 250*time.Millisecond {
+		t.Errorf("ProgressInterval = %v, want 250ms", ProgressInterval)
+	}
+}
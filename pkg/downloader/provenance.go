@@ -0,0 +1,26 @@
+package downloader
+
+import "sort"
+
+// SourceMetadataFields are the file metadata tags written when EmbedSourceMetadata
+// is set, mapped to the yt-dlp output template that fills them. Change it to
+// record different provenance fields.
+var SourceMetadataFields = map[string]string{
+	"comment": "%(webpage_url)s (%(extractor_key)s)",
+}
+
+// sourceMetadataArgs returns the yt-dlp flags embedding SourceMetadataFields
+// together with the standard metadata (title, uploader, date, ...)
+func sourceMetadataArgs() []string {
+	tags := make([]string, 0, len(SourceMetadataFields))
+	for tag := range SourceMetadataFields {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	args := []string{"--embed-metadata"}
+	for _, tag := range tags {
+		args = append(args, "--parse-metadata", SourceMetadataFields[tag]+":%(meta_"+tag+")s")
+	}
+	return args
+}
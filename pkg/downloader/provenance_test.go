@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSourceMetadataArgs(t *testing.T) {
+	setForTest(t, &SourceMetadataFields, map[string]string{
+		"comment": "%(webpage_url)s",
+		"artist":  "%(uploader)s",
+	})
+	want := []string{
+		"--embed-metadata",
+		"--parse-metadata", "%(uploader)s:%(meta_artist)s",
+		"--parse-metadata", "%(webpage_url)s:%(meta_comment)s",
+	}
+	if got := sourceMetadataArgs(); !slices.Equal(got, want) {
+		t.Errorf("sourceMetadataArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestDownloadEmbedSourceMetadata(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir:           t.TempDir(),
+		EmbedSourceMetadata: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	args := calls()[0]
+	if !slices.Contains(args, "--embed-metadata") || !hasArgs(args, "--parse-metadata", "%(webpage_url)s (%(extractor_key)s):%(meta_comment)s") {
+		t.Errorf("source metadata flags not passed: %v", args)
+	}
+
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()}); err != nil {
+		t.Fatal(err)
+	}
+	if args := calls()[1]; slices.Contains(args, "--embed-metadata") || slices.Contains(args, "--parse-metadata") {
+		t.Errorf("source metadata flags passed without EmbedSourceMetadata: %v", args)
+	}
+}
@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QualityPreset selects a trade-off between quality and bandwidth
+type QualityPreset string
+
+const (
+	// QualityBalanced picks the best format within the requested resolution and codec (default)
+	QualityBalanced QualityPreset = "balanced"
+
+	// QualityBest picks the best available video and audio, ignoring resolution and codec
+	QualityBest QualityPreset = "best"
+
+	// QualityDataSaver picks the smallest video and audio, for previews or metered links
+	QualityDataSaver QualityPreset = "data-saver"
+)
+
+// hdrFilter matches formats yt-dlp reports as HDR (HDR10, HDR10+, HDR12, HLG, ...).
+// Formats without a dynamic_range don't match either.
+const hdrFilter = "dynamic_range!=SDR"
+
+// BuildFormatSelector returns the yt-dlp -f selector DownloadVideoWithOptions
+// uses for the given options. Empty fields use the DownloadVideo defaults.
+func BuildFormatSelector(opts VideoOptions) (string, error) {
+	if opts.VideoOnly && opts.AudioOnly {
+		return "", fmt.Errorf("VideoOnly and AudioOnly are mutually exclusive")
+	}
+	if opts.AudioOnly {
+		return audioSelector(opts.Quality)
+	}
+
+	selector, err := mergeSelector(opts)
+	if err != nil || !opts.PreferProgressive || opts.VideoOnly {
+		return selector, err
+	}
+	return progressiveSelector(opts) + "/" + selector, nil
+}
+
+// progressiveSelector picks a single pre-muxed file already in Format, which
+// needs neither a merge nor a conversion
+func progressiveSelector(opts VideoOptions) string {
+	ext := strings.ToLower(opts.Format)
+	if ext == "" {
+		ext = "mp4"
+	}
+	switch opts.Quality {
+	case QualityBest:
+		return fmt.Sprintf("best[ext=%s]", ext)
+	case QualityDataSaver:
+		return fmt.Sprintf("worst[ext=%s]", ext)
+	}
+	resolution := opts.Resolution
+	if resolution == "" {
+		resolution = "720"
+	}
+	return fmt.Sprintf("best[height<=%s][ext=%s]", resolution, ext)
+}
+
+// mergeSelector returns the selector of BuildFormatSelector for video downloads
+// without PreferProgressive
+func mergeSelector(opts VideoOptions) (string, error) {
+	resolution := opts.Resolution
+	if resolution == "" {
+		resolution = "720"
+	}
+	codec := opts.Codec
+	if codec == "" {
+		codec = "avc1"
+	}
+
+	switch opts.Quality {
+	case "", QualityBalanced:
+		if opts.VideoOnly {
+			selector := fmt.Sprintf("bestvideo[height<=%s][vcodec*=%s]/bestvideo[height<=%s]", resolution, codec, resolution)
+			if opts.PreferHDR {
+				selector = fmt.Sprintf("bestvideo[height<=%s][%s]/", resolution, hdrFilter) + selector
+			}
+			return selector, nil
+		}
+		selector := fmt.Sprintf("bestvideo[height<=%s][vcodec*=%s]+bestaudio/best", resolution, codec)
+		if opts.PreferHDR {
+			// HDR streams are VP9.2/AV1, so the codec preference only applies to the SDR fallback
+			selector = fmt.Sprintf("bestvideo[height<=%s][%s]+bestaudio/", resolution, hdrFilter) + selector
+		}
+		return selector, nil
+	case QualityBest:
+		switch {
+		case opts.VideoOnly && opts.PreferHDR:
+			return fmt.Sprintf("bestvideo[%s]/bestvideo", hdrFilter), nil
+		case opts.VideoOnly:
+			return "bestvideo", nil
+		case opts.PreferHDR:
+			return fmt.Sprintf("bestvideo[%s]+bestaudio/bestvideo+bestaudio/best", hdrFilter), nil
+		}
+		return "bestvideo+bestaudio/best", nil
+	case QualityDataSaver:
+		if opts.VideoOnly {
+			return "worstvideo", nil
+		}
+		return "worstvideo+worstaudio/worst", nil
+	default:
+		return "", fmt.Errorf("unknown quality preset: %q", opts.Quality)
+	}
+}
+
+// audioSelector returns the yt-dlp -f selector for an audio download
+func audioSelector(quality QualityPreset) (string, error) {
+	switch quality {
+	case "", QualityBalanced, QualityBest:
+		return "bestaudio", nil
+	case QualityDataSaver:
+		return "worstaudio", nil
+	default:
+		return "", fmt.Errorf("unknown quality preset: %q", quality)
+	}
+}
@@ -0,0 +1,195 @@
+package downloader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildFormatSelectorPresets(t *testing.T) {
+	tests := []struct {
+		opts VideoOptions
+		want string
+	}{
+		{VideoOptions{}, "bestvideo[height<=720][vcodec*=avc1]+bestaudio/best"},
+		{VideoOptions{Quality: QualityBalanced, Resolution: "1080", Codec: "vp9"}, "bestvideo[height<=1080][vcodec*=vp9]+bestaudio/best"},
+		{VideoOptions{Quality: QualityBest, Resolution: "480"}, "bestvideo+bestaudio/best"},
+		{VideoOptions{Quality: QualityDataSaver}, "worstvideo+worstaudio/worst"},
+	}
+	for _, tt := range tests {
+		got, err := BuildFormatSelector(tt.opts)
+		if err != nil || got != tt.want {
+			t.Errorf("BuildFormatSelector(%+v) = %q, %v; want %q", tt.opts, got, err, tt.want)
+		}
+	}
+
+	if _, err := BuildFormatSelector(VideoOptions{Quality: "ultra"}); err == nil {
+		t.Error("unknown preset accepted")
+	}
+}
+
+func TestAudioSelector(t *testing.T) {
+	tests := map[QualityPreset]string{
+		"":               "bestaudio",
+		QualityBalanced:  "bestaudio",
+		QualityBest:      "bestaudio",
+		QualityDataSaver: "worstaudio",
+	}
+	for quality, want := range tests {
+		if got, err := audioSelector(quality); err != nil || got != want {
+			t.Errorf("audioSelector(%q) = %q, %v; want %q", quality, got, err, want)
+		}
+	}
+	if _, err := audioSelector("ultra"); err == nil {
+		t.Error("unknown preset accepted")
+	}
+}
+
+func TestBuildFormatSelectorPreferHDR(t *testing.T) {
+	tests := []struct {
+		opts VideoOptions
+		want string
+	}{
+		{VideoOptions{PreferHDR: true}, "bestvideo[height<=720][dynamic_range!=SDR]+bestaudio/bestvideo[height<=720][vcodec*=avc1]+bestaudio/best"},
+		{VideoOptions{PreferHDR: true, Resolution: "2160", Codec: "vp9"}, "bestvideo[height<=2160][dynamic_range!=SDR]+bestaudio/bestvideo[height<=2160][vcodec*=vp9]+bestaudio/best"},
+		{VideoOptions{PreferHDR: true, Quality: QualityBest}, "bestvideo[dynamic_range!=SDR]+bestaudio/bestvideo+bestaudio/best"},
+		// Data saver never picks HDR
+		{VideoOptions{PreferHDR: true, Quality: QualityDataSaver}, "worstvideo+worstaudio/worst"},
+	}
+	for _, tt := range tests {
+		got, err := BuildFormatSelector(tt.opts)
+		if err != nil || got != tt.want {
+			t.Errorf("BuildFormatSelector(%+v) = %q, %v; want %q", tt.opts, got, err, tt.want)
+		}
+		if err := ValidateFormatSelector(got); err != nil {
+			t.Errorf("selector %q rejected: %v", got, err)
+		}
+	}
+}
+
+func TestBuildFormatSelectorSingleTrack(t *testing.T) {
+	tests := []struct {
+		opts VideoOptions
+		want string
+	}{
+		{VideoOptions{VideoOnly: true}, "bestvideo[height<=720][vcodec*=avc1]/bestvideo[height<=720]"},
+		{VideoOptions{VideoOnly: true, Resolution: "1080", Codec: "vp9"}, "bestvideo[height<=1080][vcodec*=vp9]/bestvideo[height<=1080]"},
+		{VideoOptions{VideoOnly: true, Quality: QualityBest}, "bestvideo"},
+		{VideoOptions{VideoOnly: true, Quality: QualityDataSaver}, "worstvideo"},
+		{VideoOptions{AudioOnly: true}, "bestaudio"},
+		{VideoOptions{AudioOnly: true, Quality: QualityBest}, "bestaudio"},
+		{VideoOptions{AudioOnly: true, Quality: QualityDataSaver}, "worstaudio"},
+		// Resolution and codec have no meaning for an audio track
+		{VideoOptions{AudioOnly: true, Resolution: "1080", Codec: "vp9"}, "bestaudio"},
+	}
+	for _, tt := range tests {
+		got, err := BuildFormatSelector(tt.opts)
+		if err != nil || got != tt.want {
+			t.Errorf("BuildFormatSelector(%+v) = %q, %v; want %q", tt.opts, got, err, tt.want)
+		}
+	}
+
+	if _, err := BuildFormatSelector(VideoOptions{VideoOnly: true, AudioOnly: true}); err == nil {
+		t.Error("VideoOnly and AudioOnly together accepted")
+	}
+}
+
+func TestVideoSelectorWithoutFFMPEGSingleTrack(t *testing.T) {
+	tests := []struct {
+		opts   VideoOptions
+		format string
+		want   string
+	}{
+		{VideoOptions{VideoOnly: true}, "mp4", "bestvideo[height<=720][ext=mp4]/bestvideo[ext=mp4]"},
+		{VideoOptions{VideoOnly: true, Quality: QualityBest}, "webm", "bestvideo[ext=webm]"},
+		{VideoOptions{AudioOnly: true}, "m4a", "bestaudio[ext=m4a]"},
+		{VideoOptions{AudioOnly: true, Quality: QualityDataSaver}, "webm", "worstaudio[ext=webm]"},
+	}
+	for _, tt := range tests {
+		got, err := videoSelectorWithoutFFMPEG(tt.opts, tt.format)
+		if err != nil || got != tt.want {
+			t.Errorf("videoSelectorWithoutFFMPEG(%+v, %q) = %q, %v; want %q", tt.opts, tt.format, got, err, tt.want)
+		}
+	}
+}
+
+func TestDownloadVideoAudioOnly(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("m4a"))
+	useFakeFFMPEG(t, ffmpegScript(writeLastArg))
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir: t.TempDir(),
+		AudioOnly: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := argValue(calls()[0], "-f"); got != "bestaudio" {
+		t.Errorf("-f %q, want bestaudio", got)
+	}
+	if filepath.Ext(result.Path) != ".mp4" {
+		t.Errorf("path = %s, want the audio track in the mp4 default format", result.Path)
+	}
+}
+
+func TestBuildFormatSelectorPreferProgressive(t *testing.T) {
+	tests := []struct {
+		opts VideoOptions
+		want string
+	}{
+		{VideoOptions{PreferProgressive: true}, "best[height<=720][ext=mp4]/bestvideo[height<=720][vcodec*=avc1]+bestaudio/best"},
+		{VideoOptions{PreferProgressive: true, Format: "WebM", Resolution: "480", Codec: "vp9"}, "best[height<=480][ext=webm]/bestvideo[height<=480][vcodec*=vp9]+bestaudio/best"},
+		{VideoOptions{PreferProgressive: true, Quality: QualityBest}, "best[ext=mp4]/bestvideo+bestaudio/best"},
+		{VideoOptions{PreferProgressive: true, Quality: QualityDataSaver}, "worst[ext=mp4]/worstvideo+worstaudio/worst"},
+		// Single tracks are never pre-muxed
+		{VideoOptions{PreferProgressive: true, VideoOnly: true}, "bestvideo[height<=720][vcodec*=avc1]/bestvideo[height<=720]"},
+		{VideoOptions{PreferProgressive: true, AudioOnly: true}, "bestaudio"},
+	}
+	for _, tt := range tests {
+		got, err := BuildFormatSelector(tt.opts)
+		if err != nil || got != tt.want {
+			t.Errorf("BuildFormatSelector(%+v) = %q, %v; want %q", tt.opts, got, err, tt.want)
+		}
+		if err := ValidateFormatSelector(got); err != nil {
+			t.Errorf("selector %q rejected: %v", got, err)
+		}
+	}
+	if _, err := BuildFormatSelector(VideoOptions{PreferProgressive: true, Quality: "ultra"}); err == nil {
+		t.Error("unknown preset accepted")
+	}
+}
+
+func TestDownloadVideoPreferProgressive(t *testing.T) {
+	tests := []struct {
+		name       string
+		downloaded string // What yt-dlp picks from the selector
+		converted  bool
+	}{
+		{"pre-muxed file available", "mp4", false},
+		{"merge fallback", "mkv", true},
+	}
+	for _, tt := range tests {
+		ytdlpLog, ytdlpCalls := argsLog(t)
+		useFakeYTDLP(t, ytdlpLog+"\n"+writeOutput(tt.downloaded))
+		ffmpegLog, ffmpegCalls := argsLog(t)
+		useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg), probeH264)
+
+		result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+			OutputDir:         t.TempDir(),
+			PreferProgressive: true,
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if filepath.Ext(result.Path) != ".mp4" {
+			t.Errorf("%s: path %s", tt.name, result.Path)
+		}
+		if selector := argValue(ytdlpCalls()[0], "-f"); selector != "best[height<=720][ext=mp4]/bestvideo[height<=720][vcodec*=avc1]+bestaudio/best" {
+			t.Errorf("%s: selector %q", tt.name, selector)
+		}
+		if converted := len(ffmpegCalls()) > 0; converted != tt.converted {
+			t.Errorf("%s: ffmpeg calls %v, want converted %v", tt.name, ffmpegCalls(), tt.converted)
+		}
+	}
+}
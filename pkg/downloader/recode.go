@@ -0,0 +1,111 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// recodeCodecs maps output containers to the ffmpeg video and audio encoders
+// used when RecodeVideo is set
+var recodeCodecs = map[string][2]string{
+	"mp4":  {"libx264", "aac"},
+	"mov":  {"libx264", "aac"},
+	"mkv":  {"libx264", "aac"},
+	"flv":  {"libx264", "aac"},
+	"webm": {"libvpx-vp9", "libopus"},
+	"avi":  {"mpeg4", "libmp3lame"},
+}
+
+// videoConvertArgs builds the ffmpeg arguments for converting a downloaded video.
+// Without recode the streams are remuxed with -c copy, which is fast but fails when
+// the container can't hold the source codec (e.g. VP9/Opus into avi).
+// With recode the streams are re-encoded to codecs the container supports.
+func videoConvertArgs(input string, output string, format string, recode bool) []string {
+	args := []string{"-i", input}
+
+	codecs, ok := recodeCodecs[strings.ToLower(format)]
+	if recode && ok {
+		args = append(args, "-c:v", codecs[0], "-c:a", codecs[1])
+		if codecs[0] == "libx264" {
+			// Keep encoding time reasonable; quality is close to the default preset
+			args = append(args, "-preset", "veryfast", "-crf", "23")
+		}
+	} else {
+		args = append(args, "-c", "copy")
+	}
+
+	args = append(args, faststartArgs(format)...)
+	return append(args,
+		"-max_muxing_queue_size", "1024", // Handle large files
+		"-y",
+		output,
+	)
+}
+
+// faststartArgs moves the index of mp4/mov outputs to the front so playback
+// can start before the file is fully read. Other muxers reject the flag.
+func faststartArgs(format string) []string {
+	switch strings.ToLower(format) {
+	case "mp4", "m4v", "mov":
+		return []string{"-movflags", "+faststart"}
+	}
+	return nil
+}
+
+// copyableCodecs lists the video and audio codecs (ffprobe names) each
+// container holds with -c copy in a file players can open. Containers not
+// listed (mkv) hold anything. mp4 can technically carry VP9 and Opus, but
+// many players (QuickTime, older browsers and TVs) refuse such files.
+var copyableCodecs = map[string]map[string]bool{
+	"mp4":  {"h264": true, "hevc": true, "av1": true, "mpeg4": true, "aac": true, "mp3": true, "ac3": true, "eac3": true, "alac": true, "flac": true},
+	"mov":  {"h264": true, "hevc": true, "mpeg4": true, "prores": true, "aac": true, "mp3": true, "ac3": true, "alac": true, "pcm_s16le": true},
+	"webm": {"vp8": true, "vp9": true, "av1": true, "opus": true, "vorbis": true},
+	"flv":  {"h264": true, "flv1": true, "aac": true, "mp3": true},
+	"avi":  {"mpeg4": true, "h264": true, "mjpeg": true, "mp3": true, "ac3": true, "pcm_s16le": true},
+}
+
+// incompatibleCodec returns the first video or audio codec of streams that
+// format can't hold with -c copy, or "" if a remux works
+func incompatibleCodec(streams []MediaStream, format string) string {
+	allowed, known := copyableCodecs[strings.ToLower(format)]
+	if !known {
+		return ""
+	}
+	for _, stream := range streams {
+		if stream.CodecType != "video" && stream.CodecType != "audio" {
+			continue
+		}
+		if !allowed[stream.CodecName] {
+			return stream.CodecName
+		}
+	}
+	return ""
+}
+
+// needsRecode reports whether converting input to format must re-encode
+// because the container can't hold its codecs. The decision is logged, and
+// falls back to a remux if input can't be probed or ffmpeg lacks the encoders.
+func needsRecode(ctx context.Context, input string, format string) bool {
+	codecs, ok := recodeCodecs[strings.ToLower(format)]
+	if !ok {
+		return false
+	}
+	info, err := ProbeFile(ctx, input)
+	if err != nil {
+		return false
+	}
+	codec := incompatibleCodec(info.Streams, format)
+	if codec == "" {
+		return false
+	}
+	for _, encoder := range codecs {
+		if err := requireEncoder(encoder); err != nil {
+			fmt.Fprintf(os.Stderr, "[gostreampuller] ⚠ Warning: %s can't hold %s, but re-encoding is not possible: %v\n", format, codec, err)
+			return false
+		}
+	}
+	fmt.Fprintf(os.Stderr, "[gostreampuller] %s can't hold %s with -c copy, re-encoding to %s/%s\n", format, codec, codecs[0], codecs[1])
+	return true
+}
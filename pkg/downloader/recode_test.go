@@ -0,0 +1,167 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVideoConvertArgs(t *testing.T) {
+	tests := []struct {
+		format string
+		recode bool
+		want   []string
+	}{
+		{"mp4", false, []string{"-c", "copy", "-movflags", "+faststart"}},
+		{"mp4", true, []string{"-c:v", "libx264", "-c:a", "aac", "-preset", "veryfast", "-crf", "23", "-movflags", "+faststart"}},
+		{"webm", true, []string{"-c:v", "libvpx-vp9", "-c:a", "libopus"}},
+		{"avi", true, []string{"-c:v", "mpeg4", "-c:a", "libmp3lame"}},
+		{"mkv", false, []string{"-c", "copy"}},
+		{"ts", true, []string{"-c", "copy"}}, // No recode codecs for ts
+	}
+	for _, tt := range tests {
+		args := videoConvertArgs("in.webm", "out."+tt.format, tt.format, tt.recode)
+		if !hasArgs(args, "-i", "in.webm") || args[len(args)-1] != "out."+tt.format {
+			t.Errorf("%s (recode %v): bad input or output in %v", tt.format, tt.recode, args)
+		}
+		if !hasArgs(args, tt.want...) {
+			t.Errorf("%s (recode %v): args %v lack %v", tt.format, tt.recode, args, tt.want)
+		}
+		if tt.format != "mp4" && hasArgs(args, "-movflags") {
+			t.Errorf("%s: faststart set for a non-mp4 container: %v", tt.format, args)
+		}
+	}
+}
+
+func TestIncompatibleCodec(t *testing.T) {
+	vp9Opus := []MediaStream{{CodecType: "video", CodecName: "vp9"}, {CodecType: "audio", CodecName: "opus"}}
+	h264AAC := []MediaStream{{CodecType: "video", CodecName: "h264"}, {CodecType: "audio", CodecName: "aac"}, {CodecType: "subtitle", CodecName: "mov_text"}}
+	tests := []struct {
+		streams []MediaStream
+		format  string
+		want    string
+	}{
+		{vp9Opus, "mp4", "vp9"},
+		{vp9Opus, "webm", ""},
+		{vp9Opus, "mkv", ""},
+		{h264AAC, "MP4", ""},
+		{h264AAC, "webm", "h264"},
+		{h264AAC, "avi", "aac"},
+	}
+	for _, tt := range tests {
+		if got := incompatibleCodec(tt.streams, tt.format); got != tt.want {
+			t.Errorf("incompatibleCodec(%s) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+const (
+	probeVP9  = `echo '{"format":{"format_name":"matroska,webm"},"streams":[{"codec_type":"video","codec_name":"vp9"},{"codec_type":"audio","codec_name":"opus"}]}'`
+	probeH264 = `echo '{"format":{"format_name":"matroska,webm"},"streams":[{"codec_type":"video","codec_name":"h264"},{"codec_type":"audio","codec_name":"aac"}]}'`
+)
+
+func TestNeedsRecode(t *testing.T) {
+	useFakeFFMPEG(t, ffmpegScript("exit 0"), probeVP9)
+	if !needsRecode(context.Background(), "in.webm", "mp4") {
+		t.Error("VP9 into mp4 should be re-encoded")
+	}
+	if needsRecode(context.Background(), "in.webm", "mkv") {
+		t.Error("mkv holds VP9, no re-encode needed")
+	}
+
+	// Without the encoders, re-encoding is not attempted
+	useFakeFFMPEG(t, "echo 'Encoders:'", probeVP9)
+	if needsRecode(context.Background(), "in.webm", "mp4") {
+		t.Error("re-encode chosen without libx264")
+	}
+
+	// Unprobeable input falls back to a remux
+	useFakeFFMPEG(t, ffmpegScript("exit 0"), "exit 1")
+	if needsRecode(context.Background(), "in.webm", "mp4") {
+		t.Error("re-encode chosen for an unprobeable file")
+	}
+}
+
+func TestDownloadVideoRecodeVideo(t *testing.T) {
+	for _, recode := range []bool{false, true} {
+		ffmpegLog, ffmpegCalls := argsLog(t)
+		useFakeYTDLP(t, writeOutput("mkv"))
+		useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg), probeH264)
+
+		_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+			Format:      "mp4",
+			OutputDir:   t.TempDir(),
+			RecodeVideo: recode,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls := ffmpegCalls()
+		if len(calls) != 1 {
+			t.Fatalf("ffmpeg ran %d times, want 1: %v", len(calls), calls)
+		}
+		// h264/aac fits mp4, so only RecodeVideo re-encodes
+		if reencoded := hasArgs(calls[0], "-c:v", "libx264"); reencoded != recode {
+			t.Errorf("RecodeVideo %v: ffmpeg args %v", recode, calls[0])
+		}
+	}
+}
+
+func TestDownloadVideoRecodeVideoMissingEncoder(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("mkv"))
+	useFakeFFMPEG(t, "echo 'Encoders:'")
+
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:      "mp4",
+		OutputDir:   t.TempDir(),
+		RecodeVideo: true,
+	})
+	if err == nil {
+		t.Error("RecodeVideo accepted without libx264")
+	}
+}
+
+func TestNeedsRecodeCombinations(t *testing.T) {
+	probe := func(video, audio string) string {
+		return `echo '{"format":{"format_name":"matroska,webm"},"streams":[{"codec_type":"video","codec_name":"` + video + `"},{"codec_type":"audio","codec_name":"` + audio + `"}]}'`
+	}
+	tests := []struct {
+		video, audio, format string
+		want                 bool
+	}{
+		{"vp9", "opus", "mp4", true},
+		{"vp9", "opus", "mov", true},
+		{"vp9", "opus", "webm", false},
+		{"vp9", "opus", "mkv", false},
+		{"av1", "opus", "mp4", true}, // av1 fits, opus doesn't
+		{"av1", "opus", "webm", false},
+		{"h264", "aac", "mp4", false},
+		{"h264", "aac", "webm", true},
+		{"h264", "aac", "flv", false},
+		{"h264", "opus", "avi", true},
+		{"hevc", "aac", "ts", false}, // No re-encode codecs for ts
+	}
+	for _, tt := range tests {
+		useFakeFFMPEG(t, ffmpegScript("exit 0"), probe(tt.video, tt.audio))
+		if got := needsRecode(context.Background(), "in.mkv", tt.format); got != tt.want {
+			t.Errorf("%s/%s into %s: needsRecode = %v, want %v", tt.video, tt.audio, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadVideoRecodesIncompatibleCodecs(t *testing.T) {
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeYTDLP(t, writeOutput("webm"))
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg), probeVP9)
+
+	// VP9/Opus can't be copied into mp4, so it is re-encoded without RecodeVideo
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:    "mp4",
+		OutputDir: t.TempDir(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	calls := ffmpegCalls()
+	if len(calls) != 1 || !hasArgs(calls[0], "-c:v", "libx264", "-c:a", "aac") || hasArgs(calls[0], "-c", "copy") {
+		t.Errorf("ffmpeg calls %v, want a re-encode", calls)
+	}
+}
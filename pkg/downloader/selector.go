@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DownloadWithSelector downloads url with a yt-dlp format selector passed
+// verbatim to -f, bypassing the format/resolution/codec options and
+// BuildFormatSelector. The file keeps whatever container yt-dlp produces;
+// its absolute path is returned.
+//
+// Example:
+//
+//	path, err := downloader.DownloadWithSelector(url, "bv*[vcodec^=av01]+ba/b", "./downloads", nil)
+func DownloadWithSelector(url string, selector string, outputDir string, progressCb ProgressCallback) (string, error) {
+	if err := ValidateFormatSelector(selector); err != nil {
+		return "", err
+	}
+
+	// Auto-install binaries if needed (only happens once)
+	if err := ensureBinariesInstalled(); err != nil {
+		return "", fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	progressCb, untrack := trackDownload(url, progressCb)
+	defer untrack()
+
+	ctx, cancel := withDownloadDeadline(context.Background(), 0)
+	defer cancel()
+
+	release, err := acquireHostSlot(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	temp, err := outputTemplate(outputDir, "video")
+	if err != nil {
+		return "", err
+	}
+
+	// yt-dlp reports the final path (after any merge) to a sidecar file;
+	// --print itself would imply --quiet and hide progress
+	pathFile := strings.TrimSuffix(temp, "%(ext)s") + "path"
+	defer os.Remove(pathFile)
+
+	if progressCb != nil {
+		progressCb(DownloadProgress{Stage: "Downloading"})
+	}
+
+	existing := templateFiles(temp)
+	if _, err := runDownload(ctx, selector, temp, url, progressCb, "--print-to-file", "after_move:filepath", pathFile); err != nil {
+		removeNewTemplateFiles(temp, existing)
+		return "", fmt.Errorf("yt-dlp download failed: %w", err)
+	}
+
+	data, err := os.ReadFile(pathFile)
+	if err != nil {
+		return "", fmt.Errorf("could not determine downloaded file: %w", err)
+	}
+	path := lastLine(string(data))
+	if path == "" {
+		return "", fmt.Errorf("could not determine downloaded file")
+	}
+
+	if progressCb != nil {
+		progressCb(DownloadProgress{Stage: "Completed", Percentage: 100.0})
+	}
+
+	return filepath.Abs(path)
+}
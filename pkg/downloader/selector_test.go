@@ -0,0 +1,44 @@
+package downloader
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// writePrintedPath is a script snippet writing the output file's path to the
+// --print-to-file sidecar, as yt-dlp does after moving the file into place
+const writePrintedPath = `pathfile=""; prev=""
+for a in "$@"; do [ "$prev" = "after_move:filepath" ] && pathfile="$a"; prev="$a"; done
+[ -n "$pathfile" ] && printf '%s\n' "$(printf '%s' "$out" | sed 's/%(ext)s/webm/')" > "$pathfile"`
+
+func TestDownloadWithSelectorVerbatim(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("webm")+"\n"+writePrintedPath)
+	useFakeFFMPEG(t, "exit 0")
+	dir := t.TempDir()
+	selector := "bv*[vcodec^=av01][height<=?1440]+ba[acodec=opus]/b"
+
+	path, err := DownloadWithSelector("https://www.youtube.com/watch?v=aaaaaaaaaaa", selector, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := argValue(calls()[0], "-f"); got != selector {
+		t.Errorf("-f %q, want %q unmodified", got, selector)
+	}
+	if !filepath.IsAbs(path) || filepath.Dir(path) != dir || filepath.Ext(path) != ".webm" {
+		t.Errorf("path = %q, want the printed .webm file in %s", path, dir)
+	}
+}
+
+func TestDownloadWithSelectorInvalid(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine)
+	for _, selector := range []string{"", "--exec=rm", "best; rm -rf /"} {
+		if _, err := DownloadWithSelector("https://www.youtube.com/watch?v=aaaaaaaaaaa", selector, t.TempDir(), nil); err == nil {
+			t.Errorf("selector %q accepted", selector)
+		}
+	}
+	if len(calls()) != 0 {
+		t.Error("yt-dlp ran for an invalid selector")
+	}
+}
@@ -0,0 +1,162 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// SelfTestURL is the video used by SelfTest to verify metadata extraction.
+	// Defaults to a short, long-lived YouTube video ("Me at the zoo").
+	// Can be overridden using SetSelfTestURL()
+	SelfTestURL = "https://www.youtube.com/watch?v=jNQXAC9IVRw"
+
+	// SelfTestSkipNetwork disables the metadata step and binary auto-install of
+	// SelfTest so it can run in CI without network access.
+	// Also enabled by GOSTREAMPULLER_SELFTEST_OFFLINE=1
+	SelfTestSkipNetwork = false
+)
+
+// SetSelfTestURL sets the video URL fetched by SelfTest
+func SetSelfTestURL(url string) {
+	if url != "" {
+		SelfTestURL = url
+	}
+}
+
+// SetSelfTestSkipNetwork enables or disables the network part of SelfTest
+func SetSelfTestSkipNetwork(skip bool) {
+	SelfTestSkipNetwork = skip
+}
+
+// SelfTestStep is the outcome of a single SelfTest check
+type SelfTestStep struct {
+	Name     string
+	Output   string // First line of the step's output (e.g. binary version or video title)
+	Duration time.Duration
+	Skipped  bool
+	Err      error
+}
+
+// SelfTestReport collects the results and timing of every SelfTest step
+type SelfTestReport struct {
+	Steps    []SelfTestStep
+	Duration time.Duration
+}
+
+// Failed returns the first failed step, or nil if all steps passed
+func (r *SelfTestReport) Failed() *SelfTestStep {
+	for i := range r.Steps {
+		if r.Steps[i].Err != nil {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// selfTestCheck is one step of SelfTest; run returns a short description of the result
+type selfTestCheck struct {
+	name string
+	skip bool
+	run  func(ctx context.Context) (string, error)
+}
+
+// SelfTest verifies that yt-dlp and ffmpeg run and that metadata can be fetched
+// for SelfTestURL, without downloading any media.
+// Intended for deployment smoke tests: it catches "yt-dlp installed but broken
+// by a YouTube change" before the first real request does.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	if err := downloader.SelfTest(ctx); err != nil {
+//	    log.Fatalf("self-test failed: %v", err)
+//	}
+func SelfTest(ctx context.Context) error {
+	_, err := SelfTestWithReport(ctx)
+	return err
+}
+
+// SelfTestWithReport runs SelfTest and returns the per-step results and timing
+func SelfTestWithReport(ctx context.Context) (*SelfTestReport, error) {
+	skipNetwork := SelfTestSkipNetwork || os.Getenv("GOSTREAMPULLER_SELFTEST_OFFLINE") == "1"
+
+	// Auto-install binaries if needed (only happens once). Offline, installing
+	// would need the network too, so missing binaries fail their check instead.
+	if !skipNetwork {
+		if err := ensureBinariesInstalled(); err != nil {
+			return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+		}
+	}
+
+	checks := []selfTestCheck{
+		{"yt-dlp", false, func(ctx context.Context) (string, error) {
+			return binaryVersion(ctx, YTDLPPath, "--version")
+		}},
+		{"ffmpeg", false, func(ctx context.Context) (string, error) {
+			return binaryVersion(ctx, FFMPEGPath, "-version")
+		}},
+		{"metadata", skipNetwork, func(ctx context.Context) (string, error) {
+			metadata, err := GetVideoMetadataWithContext(ctx, SelfTestURL)
+			if err != nil {
+				return "", err
+			}
+			return metadata.Title, nil
+		}},
+	}
+
+	return runSelfTest(ctx, checks)
+}
+
+// runSelfTest executes the steps in order, stopping at the first failure
+func runSelfTest(ctx context.Context, checks []selfTestCheck) (*SelfTestReport, error) {
+	report := &SelfTestReport{}
+	start := time.Now()
+	defer func() { report.Duration = time.Since(start) }()
+
+	for _, check := range checks {
+		if check.skip {
+			report.Steps = append(report.Steps, SelfTestStep{Name: check.name, Skipped: true})
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return report, fmt.Errorf("self-test %s: %w", check.name, err)
+		}
+
+		stepStart := time.Now()
+		output, err := check.run(ctx)
+		report.Steps = append(report.Steps, SelfTestStep{
+			Name:     check.name,
+			Output:   output,
+			Duration: time.Since(stepStart),
+			Err:      err,
+		})
+		if err != nil {
+			return report, fmt.Errorf("self-test %s failed: %w", check.name, err)
+		}
+	}
+
+	return report, nil
+}
+
+// binaryVersion runs a binary with its version flag and returns the first output line
+func binaryVersion(ctx context.Context, path string, flag string) (string, error) {
+	output, err := newCommand(ctx, path, flag).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s %s: %w", path, flag, err)
+	}
+
+	line := strings.TrimSpace(string(output))
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if line == "" {
+		return "", fmt.Errorf("%s %s produced no output", path, flag)
+	}
+	return line, nil
+}
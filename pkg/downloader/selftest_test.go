@@ -0,0 +1,102 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunSelfTestStopsAtFirstFailure(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			ran = append(ran, name)
+			return name + " ok", err
+		}
+	}
+	boom := errors.New("boom")
+
+	report, err := runSelfTest(context.Background(), []selfTestCheck{
+		{"first", false, step("first", nil)},
+		{"skipped", true, step("skipped", nil)},
+		{"second", false, step("second", boom)},
+		{"third", false, step("third", nil)},
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+	if got := strings.Join(ran, ","); got != "first,second" {
+		t.Errorf("ran %s, want first,second", got)
+	}
+	if len(report.Steps) != 3 {
+		t.Fatalf("got %d steps, want 3", len(report.Steps))
+	}
+	if report.Steps[0].Output != "first ok" || report.Steps[0].Err != nil {
+		t.Errorf("first step = %+v", report.Steps[0])
+	}
+	if !report.Steps[1].Skipped {
+		t.Errorf("second step not skipped: %+v", report.Steps[1])
+	}
+	if failed := report.Failed(); failed == nil || failed.Name != "second" {
+		t.Errorf("Failed() = %+v, want step second", failed)
+	}
+}
+
+func TestRunSelfTestCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := runSelfTest(ctx, []selfTestCheck{
+		{"never", false, func(context.Context) (string, error) {
+			t.Error("step ran after cancellation")
+			return "", nil
+		}},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if len(report.Steps) != 0 {
+		t.Errorf("got steps %+v, want none", report.Steps)
+	}
+}
+
+func TestSelfTestOffline(t *testing.T) {
+	useFakeYTDLP(t, `echo 2024.08.06`)
+	useFakeFFMPEG(t, `echo "ffmpeg version 7.0 Copyright"; echo second line`)
+	setForTest(t, &SelfTestSkipNetwork, true)
+
+	report, err := SelfTestWithReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SelfTestStep{
+		{Name: "yt-dlp", Output: "2024.08.06"},
+		{Name: "ffmpeg", Output: "ffmpeg version 7.0 Copyright"},
+		{Name: "metadata", Skipped: true},
+	}
+	for i, step := range report.Steps {
+		if step.Name != want[i].Name || step.Output != want[i].Output || step.Skipped != want[i].Skipped {
+			t.Errorf("step %d = %+v, want %+v", i, step, want[i])
+		}
+	}
+}
+
+func TestSelfTestOfflineMissingBinaryFails(t *testing.T) {
+	t.Setenv("GOSTREAMPULLER_SELFTEST_OFFLINE", "1")
+	// Not marked as checked: offline, SelfTest must not try to install it
+	setForTest(t, &ytdlpInstallAttempted, false)
+	setForTest(t, &ffmpegInstallAttempted, false)
+	setForTest(t, &YTDLPPath, "/nonexistent/yt-dlp")
+
+	report, err := SelfTestWithReport(context.Background())
+	if err == nil {
+		t.Fatal("expected the yt-dlp check to fail")
+	}
+	if failed := report.Failed(); failed == nil || failed.Name != "yt-dlp" {
+		t.Errorf("Failed() = %+v, want step yt-dlp", failed)
+	}
+	if ytdlpInstallAttempted {
+		t.Error("offline self-test attempted to install binaries")
+	}
+}
@@ -0,0 +1,38 @@
+package downloader
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Defaults of the TrimSilence settings of AudioOptions
+const (
+	DefaultSilenceThreshold = -50.0                  // dB
+	DefaultSilenceDuration  = 500 * time.Millisecond // Shorter pauses are kept
+)
+
+// silenceTrimFilter returns the ffmpeg audio filter removing leading and
+// trailing silence quieter than threshold (dB) and lasting at least duration.
+// Zero values use the defaults. silenceremove can only trim the start of a
+// stream without also cutting pauses in the middle, so the trailing silence is
+// trimmed by reversing the audio, trimming its start and reversing it back.
+func silenceTrimFilter(threshold float64, duration time.Duration) (string, error) {
+	if threshold == 0 {
+		threshold = DefaultSilenceThreshold
+	}
+	if duration == 0 {
+		duration = DefaultSilenceDuration
+	}
+	if threshold > 0 || threshold < -120 {
+		return "", fmt.Errorf("silence threshold %g dB is out of range (-120 to 0)", threshold)
+	}
+	if duration < 0 {
+		return "", fmt.Errorf("silence duration must not be negative: %s", duration)
+	}
+
+	trim := fmt.Sprintf("silenceremove=start_periods=1:start_duration=%s:start_threshold=%sdB",
+		strconv.FormatFloat(duration.Seconds(), 'f', -1, 64),
+		strconv.FormatFloat(threshold, 'f', -1, 64))
+	return trim + ",areverse," + trim + ",areverse", nil
+}
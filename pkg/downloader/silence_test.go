@@ -0,0 +1,57 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSilenceTrimFilter(t *testing.T) {
+	tests := []struct {
+		threshold float64
+		duration  time.Duration
+		trim      string
+	}{
+		{0, 0, "silenceremove=start_periods=1:start_duration=0.5:start_threshold=-50dB"},
+		{-35, 2 * time.Second, "silenceremove=start_periods=1:start_duration=2:start_threshold=-35dB"},
+		{-60.5, 250 * time.Millisecond, "silenceremove=start_periods=1:start_duration=0.25:start_threshold=-60.5dB"},
+		{-120, time.Millisecond, "silenceremove=start_periods=1:start_duration=0.001:start_threshold=-120dB"},
+	}
+	for _, tt := range tests {
+		got, err := silenceTrimFilter(tt.threshold, tt.duration)
+		// The trailing silence is trimmed on the reversed audio
+		want := tt.trim + ",areverse," + tt.trim + ",areverse"
+		if err != nil || got != want {
+			t.Errorf("silenceTrimFilter(%g, %s) = %q, %v; want %q", tt.threshold, tt.duration, got, err, want)
+		}
+	}
+
+	for _, tt := range []struct {
+		threshold float64
+		duration  time.Duration
+	}{{1, 0}, {-121, 0}, {-50, -time.Second}} {
+		if _, err := silenceTrimFilter(tt.threshold, tt.duration); err == nil {
+			t.Errorf("silenceTrimFilter(%g, %s) accepted", tt.threshold, tt.duration)
+		}
+	}
+}
+
+func TestDownloadAudioTrimSilence(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("webm"))
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg))
+
+	_, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		OutputDir:        t.TempDir(),
+		TrimSilence:      true,
+		SilenceThreshold: -40,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := silenceTrimFilter(-40, 0)
+	convert := ffmpegCalls()[0]
+	if argValue(convert, "-af") != want || !hasArgs(convert, "-acodec", "libmp3lame") {
+		t.Errorf("ffmpeg args %v, want a re-encode with -af %q", convert, want)
+	}
+}
@@ -0,0 +1,178 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SiteInfo describes one yt-dlp extractor.
+// yt-dlp's CLI doesn't expose the extractors' URL regexes, so Domain is taken
+// from the name or description where they contain a host (e.g. "bbc.co.uk").
+// For the rest, callers building host allowlists match on Name (e.g. "youtube").
+type SiteInfo struct {
+	Name        string `json:"name"`                  // Extractor name, e.g. "youtube:tab"
+	Description string `json:"description,omitempty"` // As printed by --extractor-descriptions
+	Domain      string `json:"domain,omitempty"`      // Host the extractor handles, "" if yt-dlp doesn't name one
+	Broken      bool   `json:"broken"`                // yt-dlp marks the extractor as currently broken
+}
+
+// supportedSitesCacheFormat is bumped when SiteInfo gains fields, so caches
+// written by older versions are refreshed
+const supportedSitesCacheFormat = 2
+
+// supportedSitesCache is the on-disk cache of the extractor list for one yt-dlp version
+type supportedSitesCache struct {
+	Format  int        `json:"format"`
+	Version string     `json:"version"`
+	Sites   []SiteInfo `json:"sites"`
+}
+
+var (
+	sitesMutex sync.Mutex
+	sitesCache *supportedSitesCache
+)
+
+// brokenSuffix is appended by yt-dlp --list-extractors to broken extractors
+const brokenSuffix = "(CURRENTLY BROKEN)"
+
+// domainPattern matches a host name such as "abc.net.au" or "Vimeo.com"
+var domainPattern = regexp.MustCompile(`(?i)\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b`)
+
+// GetSupportedSites returns the extractors supported by the installed yt-dlp.
+// The list is cached in ~/.gostreampuller/supported_sites.json and refreshed
+// whenever the yt-dlp version changes. The returned slice is a copy the
+// caller may modify.
+func GetSupportedSites() ([]SiteInfo, error) {
+	// Auto-install yt-dlp if needed (only happens once)
+	if err := ensureYTDLPInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	version, err := binaryVersion(ctx, YTDLPPath, "--version")
+	if err != nil {
+		return nil, err
+	}
+
+	sitesMutex.Lock()
+	defer sitesMutex.Unlock()
+
+	if sitesCache == nil {
+		sitesCache = readSupportedSitesCache()
+	}
+	if sitesCache != nil && sitesCache.Version == version {
+		return slices.Clone(sitesCache.Sites), nil
+	}
+
+	output, err := newCommand(ctx, YTDLPPath, "--list-extractors").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list yt-dlp extractors: %w", err)
+	}
+	descriptions, err := newCommand(ctx, YTDLPPath, "--extractor-descriptions").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list yt-dlp extractor descriptions: %w", err)
+	}
+
+	sites := parseExtractorList(string(output))
+	addExtractorDescriptions(sites, string(descriptions))
+	sitesCache = &supportedSitesCache{Format: supportedSitesCacheFormat, Version: version, Sites: sites}
+	writeSupportedSitesCache(sitesCache)
+	return slices.Clone(sitesCache.Sites), nil
+}
+
+// parseExtractorList parses yt-dlp --list-extractors output, one extractor per line
+func parseExtractorList(output string) []SiteInfo {
+	var sites []SiteInfo
+	for _, line := range strings.Split(output, "\n") {
+		name := strings.TrimSpace(line)
+		if name == "" {
+			continue
+		}
+		site := SiteInfo{Name: name}
+		if strings.HasSuffix(name, brokenSuffix) {
+			site.Name = strings.TrimSpace(strings.TrimSuffix(name, brokenSuffix))
+			site.Broken = true
+		}
+		site.Domain = extractorDomain(site.Name)
+		sites = append(sites, site)
+	}
+	return sites
+}
+
+// addExtractorDescriptions fills in Description, and Domain where the name has
+// none, from yt-dlp --extractor-descriptions output. Lines are "name: description",
+// or just the name for extractors without one; broken extractors are not listed.
+func addExtractorDescriptions(sites []SiteInfo, output string) {
+	descriptions := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		name, description, found := strings.Cut(strings.TrimSpace(line), ": ")
+		if found {
+			descriptions[name] = strings.TrimSpace(description)
+		}
+	}
+	for i := range sites {
+		description, ok := descriptions[sites[i].Name]
+		if !ok {
+			continue
+		}
+		sites[i].Description = description
+		if sites[i].Domain == "" {
+			sites[i].Domain = extractorDomain(description)
+		}
+	}
+}
+
+// extractorDomain returns the first host name in s, lowercased, or ""
+func extractorDomain(s string) string {
+	return strings.ToLower(domainPattern.FindString(s))
+}
+
+// supportedSitesCachePath returns the cache file location
+func supportedSitesCachePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".gostreampuller", "supported_sites.json"), nil
+}
+
+// readSupportedSitesCache loads the cache file, returning nil if it is missing or invalid
+func readSupportedSitesCache() *supportedSitesCache {
+	path, err := supportedSitesCachePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cache supportedSitesCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.Version == "" || cache.Format != supportedSitesCacheFormat {
+		return nil
+	}
+	return &cache
+}
+
+// writeSupportedSitesCache saves the cache file; failures only cost a refresh next time
+func writeSupportedSitesCache(cache *supportedSitesCache) {
+	path, err := supportedSitesCachePath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseExtractorList(t *testing.T) {
+	sites := parseExtractorList("youtube\nyoutube:tab\n\nabc.net.au\nVimeo (CURRENTLY BROKEN)\n")
+	addExtractorDescriptions(sites, "youtube: YouTube\nyoutube:tab: YouTube Tabs\nabc.net.au: ABC\nGenericIE\n")
+	want := []SiteInfo{
+		{Name: "youtube", Description: "YouTube"},
+		{Name: "youtube:tab", Description: "YouTube Tabs"},
+		{Name: "abc.net.au", Description: "ABC", Domain: "abc.net.au"},
+		{Name: "Vimeo", Broken: true},
+	}
+	if len(sites) != len(want) {
+		t.Fatalf("got %+v, want %+v", sites, want)
+	}
+	for i := range want {
+		if sites[i] != want[i] {
+			t.Errorf("site %d = %+v, want %+v", i, sites[i], want[i])
+		}
+	}
+}
+
+func TestExtractorDomain(t *testing.T) {
+	tests := map[string]string{
+		"bbc.co.uk":                "bbc.co.uk",
+		"Clips from CBS.com":       "cbs.com",
+		"youtube:tab":              "",
+		"ARD:mediathek":            "",
+		"e.g. a search, v1.2":      "",
+		"9gag: 9GAG (9gag.com)":    "9gag.com",
+		"twitter:spaces (x.com)":   "x.com",
+		"netrc machine, no domain": "",
+	}
+	for s, want := range tests {
+		if got := extractorDomain(s); got != want {
+			t.Errorf("extractorDomain(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestGetSupportedSitesCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	setForTest(t, &sitesCache, nil)
+	versionFile := filepath.Join(t.TempDir(), "version")
+	if err := os.WriteFile(versionFile, []byte("2024.08.06"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, `case "$1" in
+--version) cat '`+versionFile+`' ;;
+--list-extractors) `+logLine+`; printf 'youtube\nvimeo\n' ;;
+--extractor-descriptions) printf 'youtube: YouTube\nvimeo: vimeo.com\n' ;;
+esac`)
+	listings := func() int { return len(calls()) }
+
+	sites, err := GetSupportedSites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sites) != 2 || sites[1].Domain != "vimeo.com" {
+		t.Fatalf("sites = %+v", sites)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".gostreampuller", "supported_sites.json")); err != nil {
+		t.Errorf("cache file not written: %v", err)
+	}
+
+	// Same version: served from the cache, and callers can't modify it
+	sites[0].Name = "changed"
+	cached, err := GetSupportedSites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listings() != 1 {
+		t.Errorf("extractors listed %d times, want 1", listings())
+	}
+	if cached[0].Name != "youtube" {
+		t.Errorf("cache modified through the returned slice: %+v", cached)
+	}
+
+	// A fresh process reads the cache file
+	sitesCache = nil
+	if _, err := GetSupportedSites(); err != nil {
+		t.Fatal(err)
+	}
+	if listings() != 1 {
+		t.Errorf("cache file not used: extractors listed %d times", listings())
+	}
+
+	// A new yt-dlp version refreshes the list
+	if err := os.WriteFile(versionFile, []byte("2024.09.01"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetSupportedSites(); err != nil {
+		t.Fatal(err)
+	}
+	if listings() != 2 {
+		t.Errorf("extractors listed %d times after a version change, want 2", listings())
+	}
+	if cache := readSupportedSitesCache(); cache == nil || cache.Version != "2024.09.01" {
+		t.Errorf("cache file = %+v, want version 2024.09.01", cache)
+	}
+}
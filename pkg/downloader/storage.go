@@ -0,0 +1,122 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Storage receives finished downloads.
+// Implement it to stream files to object storage (S3, GCS, ...) instead of local disk.
+type Storage interface {
+	// Put stores the content under name and returns its location
+	// (a path, URL, or object key - whatever the backend uses)
+	Put(name string, r io.Reader) (string, error)
+}
+
+// LocalStorage stores files in a directory on local disk
+type LocalStorage struct {
+	Dir string // Target directory (default: current working directory)
+}
+
+// NewLocalStorage returns a Storage writing into dir
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{Dir: dir}
+}
+
+// Put writes r to Dir/name and returns the absolute path of the file
+func (s *LocalStorage) Put(name string, r io.Reader) (string, error) {
+	// Never let a name escape the storage directory
+	base := filepath.Base(name)
+	if base == "." || base == ".." || base == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid storage name: %q", name)
+	}
+
+	if s.Dir != "" {
+		if err := makeOutputDir(s.Dir); err != nil {
+			return "", fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+	dst := filepath.Join(s.Dir, base)
+	partial := partialPath(dst)
+
+	out, err := os.Create(partial)
+	if err != nil {
+		return "", fmt.Errorf("failed to create destination file: %w", err)
+	}
+
+	buf := make([]byte, ChunkSize)
+	if _, err := io.CopyBuffer(out, r, buf); err != nil {
+		out.Close()
+		os.Remove(partial)
+		return "", fmt.Errorf("failed to write file: %w", diskFullError(err))
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(partial)
+		return "", fmt.Errorf("failed to sync file: %w", diskFullError(err))
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(partial)
+		return "", fmt.Errorf("failed to close file: %w", diskFullError(err))
+	}
+	if err := commitPartial(partial, dst); err != nil {
+		return "", err
+	}
+	if err := applyOutputFileMode(dst); err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(dst)
+}
+
+// finishDownload hands the final file to storage, removing the local copy once stored.
+// With no storage, the file is moved from TempWorkDir to outputDir (if a work
+// directory is set) and its absolute local path is returned.
+func finishDownload(ctx context.Context, path string, outputDir string, storage Storage, progressCb ProgressCallback) (string, error) {
+	if storage == nil && workDir(outputDir) != outputDir {
+		moved, err := moveFile(ctx, path, outputDir, progressCb)
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("failed to move file to output directory: %w", err)
+		}
+		path = moved
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if storage == nil {
+		if err := applyOutputFileMode(abs); err != nil {
+			return "", err
+		}
+		return abs, nil
+	}
+
+	// Storing into the directory the file is already in would truncate it
+	if local, ok := storage.(*LocalStorage); ok {
+		if dst, err := filepath.Abs(filepath.Join(local.Dir, filepath.Base(path))); err == nil && dst == abs {
+			if err := applyOutputFileMode(abs); err != nil {
+				return "", err
+			}
+			return abs, nil
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open downloaded file: %w", err)
+	}
+
+	location, err := storage.Put(filepath.Base(path), file)
+	file.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to store downloaded file: %w", err)
+	}
+
+	os.Remove(path)
+	return location, nil
+}
@@ -0,0 +1,113 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryStorage is a Storage keeping files in memory
+type memoryStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (s *memoryStorage) Put(name string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = make(map[string][]byte)
+	}
+	s.files[name] = data
+	return "mem://" + name, nil
+}
+
+func TestFinishDownloadToStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(path, []byte("media"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	storage := &memoryStorage{}
+
+	location, err := finishDownload(context.Background(), path, filepath.Dir(path), storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location != "mem://video.mp4" {
+		t.Errorf("location = %q, want mem://video.mp4", location)
+	}
+	if got := string(storage.files["video.mp4"]); got != "media" {
+		t.Errorf("stored %q, want media", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("local copy not removed after storing: %v", err)
+	}
+}
+
+func TestDownloadVideoWithStorage(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+	dir := t.TempDir()
+	storage := &memoryStorage{}
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir: dir,
+		Filename:  "clip",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Path != "mem://clip.mp4" || string(storage.files["clip.mp4"]) != "media" {
+		t.Errorf("path %q, stored %v", result.Path, storage.files)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files left in the output directory: %v", entries)
+	}
+}
+
+func TestLocalStoragePut(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	storage := NewLocalStorage(dir)
+
+	// Names never escape the storage directory
+	path, err := storage.Put("../../escape.mp4", strings.NewReader("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.Abs(filepath.Join(dir, "escape.mp4")); path != want {
+		t.Errorf("Put returned %s, want %s", path, want)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "data" {
+		t.Errorf("stored %q, %v", data, err)
+	}
+	if _, err := storage.Put("..", strings.NewReader("")); err == nil {
+		t.Error("Put(\"..\") succeeded")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, fmt.Errorf("read failed") }
+
+func TestLocalStoragePutFailureLeavesNoFile(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewLocalStorage(dir)
+
+	if _, err := storage.Put("video.mp4", io.MultiReader(bytes.NewReader([]byte("part")), failingReader{})); err == nil {
+		t.Fatal("Put succeeded with a failing reader")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files left behind: %v", entries)
+	}
+}
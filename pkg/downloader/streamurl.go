@@ -0,0 +1,162 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamURLs are direct media URLs for a video.
+// Adaptive formats (e.g. "bestvideo+bestaudio") have separate video and audio URLs;
+// combined formats only set VideoURL.
+type StreamURLs struct {
+	VideoURL  string     `json:"video_url"`
+	AudioURL  string     `json:"audio_url,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // When the URLs stop working, if known
+}
+
+// selectorPattern is the character set allowed in yt-dlp format selectors
+var selectorPattern = regexp.MustCompile(`^[A-Za-z0-9_.,+/()\[\]<>=!*^$~?:\- ]+$`)
+
+// ValidateFormatSelector checks that selector looks like a yt-dlp -f expression
+// and cannot be mistaken for a command-line flag
+func ValidateFormatSelector(selector string) error {
+	if selector == "" {
+		return fmt.Errorf("format selector must not be empty")
+	}
+	if len(selector) > 256 {
+		return fmt.Errorf("format selector is too long")
+	}
+	if strings.HasPrefix(selector, "-") {
+		return fmt.Errorf("format selector must not start with '-'")
+	}
+	if !selectorPattern.MatchString(selector) {
+		return fmt.Errorf("format selector %q contains invalid characters", selector)
+	}
+	return nil
+}
+
+// GetStreamURL returns the direct media URL(s) for the given yt-dlp format
+// selector without downloading anything, so clients can play or stream
+// the media themselves. The URLs are temporary; see StreamURLs.ExpiresAt.
+//
+// Example:
+//
+//	urls, err := downloader.GetStreamURL(ctx, videoURL, "bestvideo[height<=1080]+bestaudio")
+func GetStreamURL(ctx context.Context, videoURL string, selector string) (*StreamURLs, error) {
+	if selector == "" {
+		selector = "best"
+	}
+	if err := ValidateFormatSelector(selector); err != nil {
+		return nil, err
+	}
+
+	// Stream URLs only need yt-dlp
+	if err := ensureYTDLPInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	var lastErr error
+	for _, client := range playerClients {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		cmd := ytdlpCommand(ctx,
+			"-g",
+			"-f", selector,
+			"--no-playlist",
+			"--no-warnings",
+			"--extractor-args", fmt.Sprintf("youtube:player_client=%s", client),
+			"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			"--referer", "https://www.youtube.com/",
+			"--add-header", "Accept-Language:en-US,en;q=0.9",
+			videoURL,
+		)
+
+		output, err := cmd.Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				lastErr = fmt.Errorf("failed to get stream URL with client %s: %w", client, newDownloadError(cmd, "stream-url", string(exitErr.Stderr), err))
+				continue
+			}
+			return nil, fmt.Errorf("failed to execute yt-dlp: %w", err)
+		}
+
+		if urls := parseStreamURLs(string(output)); urls != nil {
+			return urls, nil
+		}
+		lastErr = fmt.Errorf("empty response from yt-dlp with client %s", client)
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("no stream URL found")
+}
+
+// GetDirectURL returns the direct media URL(s) for the given yt-dlp format
+// selector (default: best). Adaptive selectors such as
+// "bestvideo[height<=1080]+bestaudio" return separate video and audio URLs;
+// audioURL is empty for combined formats. Use GetStreamURL for the expiry.
+func GetDirectURL(url string, selector string) (videoURL string, audioURL string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	urls, err := GetStreamURL(ctx, url, selector)
+	if err != nil {
+		return "", "", err
+	}
+	return urls.VideoURL, urls.AudioURL, nil
+}
+
+// parseStreamURLs parses `yt-dlp -g` output: one URL for combined formats,
+// or a video URL followed by an audio URL for adaptive formats.
+// Returns nil if the output contains no URL.
+func parseStreamURLs(output string) *StreamURLs {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	urls := &StreamURLs{VideoURL: lines[0]}
+	if len(lines) > 1 {
+		urls.AudioURL = lines[1]
+	}
+
+	// Use the earliest expiry if both URLs carry one
+	for _, line := range lines {
+		if expiry, ok := urlExpiry(line); ok && (urls.ExpiresAt == nil || expiry.Before(*urls.ExpiresAt)) {
+			urls.ExpiresAt = &expiry
+		}
+	}
+	return urls
+}
+
+// urlExpiry reads the "expire" unix timestamp googlevideo.com URLs carry
+func urlExpiry(rawURL string) (time.Time, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	expire := parsed.Query().Get("expire")
+	if expire == "" {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(expire, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0).UTC(), true
+}
@@ -0,0 +1,114 @@
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestValidateFormatSelector(t *testing.T) {
+	valid := []string{"best", "bestvideo[height<=1080]+bestaudio/best", "137+140", "bv*[ext=mp4]+ba[ext=m4a]/b", "best[format_note^=720]"}
+	for _, selector := range valid {
+		if err := ValidateFormatSelector(selector); err != nil {
+			t.Errorf("ValidateFormatSelector(%q) = %v", selector, err)
+		}
+	}
+	invalid := []string{"", "--exec rm", "-o /etc/passwd", "best;rm -rf", "best`id`", "best\nworst", string(make([]byte, 300))}
+	for _, selector := range invalid {
+		if err := ValidateFormatSelector(selector); err == nil {
+			t.Errorf("ValidateFormatSelector(%q) accepted", selector)
+		}
+	}
+}
+
+func TestParseStreamURLs(t *testing.T) {
+	video := "https://rr1.googlevideo.com/videoplayback?expire=1700003600&itag=137"
+	audio := "https://rr1.googlevideo.com/videoplayback?expire=1700000000&itag=140"
+
+	if urls := parseStreamURLs("\n  \n"); urls != nil {
+		t.Errorf("empty output: got %+v", urls)
+	}
+
+	combined := parseStreamURLs("https://example.com/video.mp4\n")
+	if combined.VideoURL != "https://example.com/video.mp4" || combined.AudioURL != "" || combined.ExpiresAt != nil {
+		t.Errorf("one line: got %+v", combined)
+	}
+
+	adaptive := parseStreamURLs(video + "\n" + audio + "\n")
+	if adaptive.VideoURL != video || adaptive.AudioURL != audio {
+		t.Errorf("two lines: got %+v", adaptive)
+	}
+	// The earlier of both expiries applies
+	if want := time.Unix(1700000000, 0).UTC(); adaptive.ExpiresAt == nil || !adaptive.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", adaptive.ExpiresAt, want)
+	}
+}
+
+func TestGetStreamURL(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+echo "https://example.com/v.mp4"
+echo "https://example.com/a.m4a"`)
+
+	urls, err := GetStreamURL(context.Background(), "https://youtu.be/aaaaaaaaaaa", "bestvideo+bestaudio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls.VideoURL != "https://example.com/v.mp4" || urls.AudioURL != "https://example.com/a.m4a" {
+		t.Errorf("urls = %+v", urls)
+	}
+	if args := calls()[0]; argValue(args, "-f") != "bestvideo+bestaudio" || !hasArgs(args, "-g") {
+		t.Errorf("yt-dlp args %v", args)
+	}
+
+	if _, err := GetStreamURL(context.Background(), "https://youtu.be/aaaaaaaaaaa", "--exec"); err == nil {
+		t.Error("invalid selector accepted")
+	}
+}
+
+func TestGetStreamURLTriesEveryClient(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+echo "ERROR: Video unavailable" >&2
+exit 1`)
+
+	if _, err := GetStreamURL(context.Background(), "https://youtu.be/aaaaaaaaaaa", ""); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := len(calls()); got != len(playerClients) {
+		t.Errorf("yt-dlp ran %d times, want once per player client (%d)", got, len(playerClients))
+	}
+}
+
+func TestGetDirectURL(t *testing.T) {
+	tests := []struct {
+		selector     string
+		output       string
+		video, audio string
+	}{
+		{"best", `echo "https://example.com/combined.mp4"`, "https://example.com/combined.mp4", ""},
+		{"bestvideo[height<=1080]+bestaudio", `printf 'https://example.com/v.mp4\nhttps://example.com/a.m4a\n'`, "https://example.com/v.mp4", "https://example.com/a.m4a"},
+		{"", `echo "https://example.com/combined.mp4"`, "https://example.com/combined.mp4", ""},
+	}
+	for _, tt := range tests {
+		logLine, calls := argsLog(t)
+		useFakeYTDLP(t, logLine+"\n"+tt.output)
+
+		video, audio, err := GetDirectURL("https://youtu.be/aaaaaaaaaaa", tt.selector)
+		if err != nil || video != tt.video || audio != tt.audio {
+			t.Errorf("GetDirectURL(%q) = %q, %q, %v; want %q, %q", tt.selector, video, audio, err, tt.video, tt.audio)
+		}
+		want := tt.selector
+		if want == "" {
+			want = "best"
+		}
+		if args := calls()[0]; argValue(args, "-f") != want {
+			t.Errorf("GetDirectURL(%q): -f %q, want %q", tt.selector, argValue(args, "-f"), want)
+		}
+	}
+
+	useFakeYTDLP(t, "exit 0")
+	if _, _, err := GetDirectURL("https://youtu.be/aaaaaaaaaaa", "best"); err == nil {
+		t.Error("no error without URLs")
+	}
+}
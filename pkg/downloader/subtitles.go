@@ -0,0 +1,131 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// subtitleConversions are the formats yt-dlp's --convert-subs can produce
+var subtitleConversions = map[string]bool{
+	"srt": true,
+	"ass": true,
+	"vtt": true,
+	"lrc": true,
+}
+
+// subtitleExtensions are the subtitle files DownloadSubtitles returns
+var subtitleExtensions = map[string]bool{
+	".vtt": true, ".srt": true, ".ass": true, ".lrc": true,
+	".ttml": true, ".srv1": true, ".srv2": true, ".srv3": true, ".json3": true,
+}
+
+// SubtitleOptions configures DownloadSubtitles
+type SubtitleOptions struct {
+	Languages     []string // Subtitle languages, e.g. "en", "de" (default: en)
+	AutoGenerated bool     // Also fetch auto-generated captions if there are no uploaded ones
+	OutputDir     string   // Output directory (default: current working directory)
+
+	// ConvertSubsTo converts every subtitle file to one format: srt, ass, vtt or lrc.
+	// Requires ffmpeg. Empty keeps the format the site serves.
+	ConvertSubsTo string
+}
+
+// DownloadSubtitles downloads the video's subtitles without the video and
+// returns the paths of the subtitle files, one per language found.
+// yt-dlp names them <name>.<lang>.<ext>.
+//
+// Example:
+//
+//	paths, err := downloader.DownloadSubtitles(url, downloader.SubtitleOptions{
+//		Languages:     []string{"en", "de"},
+//		ConvertSubsTo: "srt",
+//	})
+func DownloadSubtitles(url string, opts SubtitleOptions) ([]string, error) {
+	convert := strings.ToLower(opts.ConvertSubsTo)
+	if convert != "" && !subtitleConversions[convert] {
+		return nil, fmt.Errorf("unsupported subtitle format %q (use srt, ass, vtt or lrc)", opts.ConvertSubsTo)
+	}
+
+	if convert != "" {
+		if err := ensureBinariesInstalled(); err != nil {
+			return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+		}
+		if !ffmpegAvailable() {
+			return nil, fmt.Errorf("%w: converting subtitles to %s", ErrFFMPEGRequired, convert)
+		}
+	} else if err := ensureYTDLPInstalled(); err != nil {
+		return nil, fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	languages := opts.Languages
+	if len(languages) == 0 {
+		languages = []string{"en"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	temp, err := outputTemplate(opts.OutputDir, "subs")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := ytdlpCommand(ctx, subtitleArgs(temp, languages, opts.AutoGenerated, convert, url)...)
+	if _, err := cmd.Output(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to fetch subtitles: %w", newDownloadError(cmd, "subtitles", string(exitErr.Stderr), err))
+		}
+		return nil, fmt.Errorf("failed to execute yt-dlp: %w", err)
+	}
+
+	paths := findSubtitleFiles(temp)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no subtitles available for %s", strings.Join(languages, ", "))
+	}
+	return paths, nil
+}
+
+// subtitleArgs builds the yt-dlp arguments for a subtitle-only download
+func subtitleArgs(temp string, languages []string, autoGenerated bool, convert string, url string) []string {
+	args := []string{
+		"--skip-download",
+		"--write-subs",
+		"--sub-langs", strings.Join(languages, ","),
+		"--no-playlist",
+		"--no-warnings",
+		"-o", temp,
+	}
+	if autoGenerated {
+		args = append(args, "--write-auto-subs")
+	}
+	if convert != "" {
+		args = append(args, "--convert-subs", convert)
+	}
+	return append(args,
+		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"--referer", "https://www.youtube.com/",
+		url,
+	)
+}
+
+// findSubtitleFiles returns the absolute paths of the subtitle files written
+// for the output template, sorted
+func findSubtitleFiles(temp string) []string {
+	var paths []string
+	for _, match := range templateFiles(temp) {
+		if !subtitleExtensions[strings.ToLower(filepath.Ext(match))] {
+			continue
+		}
+		if abs, err := filepath.Abs(match); err == nil {
+			match = abs
+		}
+		paths = append(paths, match)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,132 @@
+package downloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// subtitleScript is a fake yt-dlp writing an en and a de subtitle file for the
+// -o template, in the --convert-subs format if given and vtt otherwise
+const subtitleScript = `out=""; ext="vtt"; prev=""
+for a in "$@"; do
+	[ "$prev" = "-o" ] && out="$a"
+	[ "$prev" = "--convert-subs" ] && ext="$a"
+	prev="$a"
+done
+for lang in en de; do
+	printf 'subs' > "$(printf '%s' "$out" | sed "s/%(ext)s/$lang.$ext/")"
+done`
+
+func TestSubtitleArgsConvert(t *testing.T) {
+	args := subtitleArgs("subs.%(ext)s", []string{"en", "de"}, false, "srt", "https://youtu.be/x")
+	if !hasArgs(args, "--convert-subs", "srt") || !hasArgs(args, "--sub-langs", "en,de") {
+		t.Errorf("args %v, want --convert-subs srt", args)
+	}
+	if args[len(args)-1] != "https://youtu.be/x" {
+		t.Errorf("URL is not the last argument: %v", args)
+	}
+
+	args = subtitleArgs("subs.%(ext)s", []string{"en"}, true, "", "https://youtu.be/x")
+	if slices.Contains(args, "--convert-subs") || !slices.Contains(args, "--write-auto-subs") {
+		t.Errorf("args %v, want auto subs without conversion", args)
+	}
+}
+
+func TestDownloadSubtitlesConvert(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+subtitleScript)
+	useFakeFFMPEG(t, "exit 0")
+	// Glob metacharacters in the directory must not hide the files
+	dir := filepath.Join(t.TempDir(), "subs [1] *")
+
+	paths, err := DownloadSubtitles("https://www.youtube.com/watch?v=aaaaaaaaaaa", SubtitleOptions{
+		Languages:     []string{"en", "de"},
+		ConvertSubsTo: "SRT",
+		OutputDir:     dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if argValue(calls()[0], "--convert-subs") != "srt" {
+		t.Errorf("args %v, want --convert-subs srt", calls()[0])
+	}
+	if len(paths) != 2 {
+		t.Fatalf("paths = %v, want two subtitle files", paths)
+	}
+	for _, path := range paths {
+		if filepath.Ext(path) != ".srt" || !filepath.IsAbs(path) || filepath.Dir(path) != dir {
+			t.Errorf("path %s, want an absolute .srt file in %s", path, dir)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+	if !strings.HasSuffix(paths[0], ".de.srt") || !strings.HasSuffix(paths[1], ".en.srt") {
+		t.Errorf("paths = %v, want them sorted", paths)
+	}
+}
+
+func TestDownloadSubtitlesKeepsFormat(t *testing.T) {
+	useFakeYTDLP(t, subtitleScript)
+	withoutFFMPEG(t)
+
+	paths, err := DownloadSubtitles("https://www.youtube.com/watch?v=aaaaaaaaaaa", SubtitleOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if filepath.Ext(path) != ".vtt" {
+			t.Errorf("path %s, want the served .vtt", path)
+		}
+	}
+}
+
+func TestDownloadSubtitlesConvertValidation(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+subtitleScript)
+	withoutFFMPEG(t)
+
+	if _, err := DownloadSubtitles("https://www.youtube.com/watch?v=aaaaaaaaaaa", SubtitleOptions{
+		ConvertSubsTo: "txt",
+		OutputDir:     t.TempDir(),
+	}); err == nil || !strings.Contains(err.Error(), "unsupported subtitle format") {
+		t.Errorf("err = %v, want an unsupported format error", err)
+	}
+	if _, err := DownloadSubtitles("https://www.youtube.com/watch?v=aaaaaaaaaaa", SubtitleOptions{
+		ConvertSubsTo: "ass",
+		OutputDir:     t.TempDir(),
+	}); !errors.Is(err, ErrFFMPEGRequired) {
+		t.Errorf("err = %v, want ErrFFMPEGRequired", err)
+	}
+	if len(calls()) != 0 {
+		t.Errorf("yt-dlp ran for an invalid request: %v", calls())
+	}
+}
+
+func TestTemplateFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a[b]?")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"v_1.mp4", "v_1.mp4.part", "v_1.en.vtt", "v_12.mp4", "other.mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := templateFiles(filepath.Join(dir, "v_1.%(ext)s"))
+	var names []string
+	for _, path := range got {
+		names = append(names, filepath.Base(path))
+	}
+	slices.Sort(names)
+	if want := []string{"v_1.en.vtt", "v_1.mp4", "v_1.mp4.part"}; !slices.Equal(names, want) {
+		t.Errorf("templateFiles = %v, want %v", names, want)
+	}
+	if got := templateFiles(filepath.Join(dir, "missing", "v.%(ext)s")); got != nil {
+		t.Errorf("templateFiles in a missing directory = %v", got)
+	}
+}
@@ -0,0 +1,37 @@
+package downloader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validateTags returns an error if a tag name can't be passed to ffmpeg's -metadata
+func validateTags(tags map[string]string) error {
+	for key := range tags {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("tag names must not be empty")
+		}
+		if strings.ContainsAny(key, "=\r\n") {
+			return fmt.Errorf("invalid tag name %q", key)
+		}
+	}
+	return nil
+}
+
+// tagArgs returns the ffmpeg -metadata flags setting tags, sorted by name so
+// the arguments are deterministic. ffmpeg copies the source's metadata by
+// default; these override it key by key.
+func tagArgs(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		args = append(args, "-metadata", key+"="+tags[key])
+	}
+	return args
+}
@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestTagArgs(t *testing.T) {
+	tests := []struct {
+		tags map[string]string
+		want []string
+	}{
+		{nil, []string{}},
+		{map[string]string{"title": "Song"}, []string{"-metadata", "title=Song"}},
+		{
+			map[string]string{"title": "Song", "artist": "Band", "album": "Live = Loud"},
+			[]string{"-metadata", "album=Live = Loud", "-metadata", "artist=Band", "-metadata", "title=Song"},
+		},
+		{map[string]string{"comment": ""}, []string{"-metadata", "comment="}}, // Clears the source's tag
+	}
+	for _, tt := range tests {
+		if got := tagArgs(tt.tags); !slices.Equal(got, tt.want) {
+			t.Errorf("tagArgs(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	if err := validateTags(map[string]string{"title": "a=b\nc"}); err != nil {
+		t.Errorf("values may contain anything: %v", err)
+	}
+	for _, key := range []string{"", " ", "a=b", "line\nbreak"} {
+		if err := validateTags(map[string]string{key: "x"}); err == nil {
+			t.Errorf("tag name %q accepted", key)
+		}
+	}
+}
+
+func TestDownloadAudioTags(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("webm"))
+	ffmpegLog, ffmpegCalls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(ffmpegLog+"\n"+writeLastArg))
+
+	_, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		Format:    "mp3",
+		OutputDir: t.TempDir(),
+		Tags:      map[string]string{"artist": "Band", "title": "Song"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	convert := ffmpegCalls()[0]
+	if !hasArgs(convert, "-metadata", "artist=Band", "-metadata", "title=Song") {
+		t.Errorf("ffmpeg args %v lack the tags", convert)
+	}
+	// Source metadata is merged, not dropped
+	if hasArgs(convert, "-map_metadata", "-1") {
+		t.Errorf("source metadata dropped: %v", convert)
+	}
+
+	if _, err := DownloadAudioWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", AudioOptions{
+		OutputDir: t.TempDir(),
+		Tags:      map[string]string{"a=b": "x"},
+	}); err == nil {
+		t.Error("invalid tag name accepted")
+	}
+}
@@ -0,0 +1,69 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// GenerateThumbnail extracts a single frame at atSeconds from a local video
+// and writes it as an image to outputPath (jpg, png or webp by extension).
+// Useful for platforms that don't provide a thumbnail.
+// atSeconds must be within the video's duration.
+//
+// Example:
+//
+//	err := downloader.GenerateThumbnail("video.mp4", 12.5, "thumb.jpg")
+func GenerateThumbnail(videoPath string, atSeconds float64, outputPath string) error {
+	if atSeconds < 0 {
+		return fmt.Errorf("timestamp must not be negative: %v", atSeconds)
+	}
+	if _, err := os.Stat(videoPath); err != nil {
+		return fmt.Errorf("video file not found: %w", err)
+	}
+
+	// Auto-install binaries if needed (only happens once)
+	if err := ensureBinariesInstalled(); err != nil {
+		return fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	duration, err := probeDuration(ctx, videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read video duration: %w", err)
+	}
+	if atSeconds >= duration {
+		return fmt.Errorf("timestamp %.2fs is beyond the video duration (%.2fs)", atSeconds, duration)
+	}
+
+	if dir := filepath.Dir(outputPath); dir != "." {
+		if err := makeOutputDir(dir); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	cmd := newCommand(ctx, FFMPEGPath, thumbnailArgs(videoPath, atSeconds, outputPath)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("ffmpeg thumbnail extraction failed: %v: %s", err, lastLine(string(output)))
+	}
+	return nil
+}
+
+// thumbnailArgs builds the ffmpeg arguments for extracting one frame.
+// -ss before -i seeks on the input, which is fast even for long videos.
+func thumbnailArgs(videoPath string, atSeconds float64, outputPath string) []string {
+	return []string{
+		"-ss", strconv.FormatFloat(atSeconds, 'f', 3, 64),
+		"-i", videoPath,
+		"-frames:v", "1",
+		"-q:v", "2", // High quality for jpg output
+		"-y",
+		outputPath,
+	}
+}
@@ -0,0 +1,65 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThumbnailArgs(t *testing.T) {
+	got := strings.Join(thumbnailArgs("in.mp4", 12.5, "out.jpg"), " ")
+	want := "-ss 12.500 -i in.mp4 -frames:v 1 -q:v 2 -y out.jpg"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestGenerateThumbnail(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, logLine+"\n"+writeLastArg, `echo 60.0`)
+	dir := t.TempDir()
+	video := filepath.Join(dir, "in.mp4")
+	os.WriteFile(video, []byte("media"), 0644)
+	output := filepath.Join(dir, "thumbs", "frame.png")
+
+	if err := GenerateThumbnail(video, 30, output); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("thumbnail not written: %v", err)
+	}
+	if c := calls(); len(c) != 1 || argValue(c[0], "-ss") != "30.000" {
+		t.Errorf("ffmpeg calls %v", c)
+	}
+
+	if err := GenerateThumbnail(video, 60, output); err == nil || !strings.Contains(err.Error(), "beyond the video duration") {
+		t.Errorf("timestamp at the end: err = %v", err)
+	}
+	if err := GenerateThumbnail(video, -1, output); err == nil {
+		t.Error("negative timestamp accepted")
+	}
+	if err := GenerateThumbnail(filepath.Join(dir, "missing.mp4"), 1, output); err == nil {
+		t.Error("missing video accepted")
+	}
+}
+
+func TestParseProbeOutput(t *testing.T) {
+	info, err := parseProbeOutput([]byte(`{
+		"streams": [
+			{"codec_type": "video", "codec_name": "h264", "width": 1280, "height": 720},
+			{"codec_type": "audio", "codec_name": "aac", "sample_rate": "44100", "channels": 2}
+		],
+		"format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "212.091"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.FormatName != "mov,mp4,m4a,3gp,3g2,mj2" || info.Duration != 212.091 || len(info.Streams) != 2 {
+		t.Fatalf("info = %+v", info)
+	}
+	want := MediaStream{CodecType: "audio", CodecName: "aac", SampleRate: 44100, Channels: 2}
+	if info.Streams[1] != want {
+		t.Errorf("audio stream = %+v, want %+v", info.Streams[1], want)
+	}
+}
@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	tiersMu sync.RWMutex
+
+	// qualityTiers maps tier names to curated yt-dlp selectors. Each prefers
+	// H.264/AAC (which plays everywhere) and falls back to any codec, then to a
+	// single pre-merged file. Extend it using RegisterTier()
+	qualityTiers = map[string]string{
+		"4k":    "bestvideo[height<=2160]+bestaudio/best[height<=2160]",
+		"1080p": "bestvideo[height<=1080][vcodec^=avc1]+bestaudio[acodec^=mp4a]/bestvideo[height<=1080]+bestaudio/best[height<=1080]",
+		"720p":  "bestvideo[height<=720][vcodec^=avc1]+bestaudio[acodec^=mp4a]/bestvideo[height<=720]+bestaudio/best[height<=720]",
+		"480p":  "bestvideo[height<=480][vcodec^=avc1]+bestaudio[acodec^=mp4a]/bestvideo[height<=480]+bestaudio/best[height<=480]",
+		"audio": "bestaudio/best",
+	}
+)
+
+// RegisterTier adds or replaces a named quality tier for DownloadTier. Names
+// are case-insensitive and selector is a yt-dlp format selector.
+//
+// Example:
+//
+//	err := downloader.RegisterTier("mobile", "best[height<=360][ext=mp4]/best[height<=360]")
+func RegisterTier(name string, selector string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return fmt.Errorf("tier name is required")
+	}
+	if err := ValidateFormatSelector(selector); err != nil {
+		return err
+	}
+
+	tiersMu.Lock()
+	defer tiersMu.Unlock()
+	qualityTiers[name] = selector
+	return nil
+}
+
+// TierSelector returns the yt-dlp selector of a quality tier
+func TierSelector(tier string) (string, error) {
+	tiersMu.RLock()
+	defer tiersMu.RUnlock()
+	selector, ok := qualityTiers[strings.ToLower(tier)]
+	if !ok {
+		names := make([]string, 0, len(qualityTiers))
+		for name := range qualityTiers {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return "", fmt.Errorf("unknown quality tier %q (use one of: %s)", tier, strings.Join(names, ", "))
+	}
+	return selector, nil
+}
+
+// DownloadTier downloads url at a named quality tier ("4k", "1080p", "720p",
+// "480p", "audio", or one added with RegisterTier) without dealing with
+// resolutions and codecs. Like DownloadWithSelector, the file keeps the
+// container yt-dlp produces; its absolute path is returned.
+//
+// Example:
+//
+//	path, err := downloader.DownloadTier(url, "1080p", "./downloads")
+func DownloadTier(url string, tier string, outputDir string) (string, error) {
+	selector, err := TierSelector(tier)
+	if err != nil {
+		return "", err
+	}
+	return DownloadWithSelector(url, selector, outputDir, nil)
+}
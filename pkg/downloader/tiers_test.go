@@ -0,0 +1,105 @@
+package downloader
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// removeTierAfterTest unregisters name when the test ends
+func removeTierAfterTest(t *testing.T, name string) {
+	t.Helper()
+	t.Cleanup(func() {
+		tiersMu.Lock()
+		defer tiersMu.Unlock()
+		delete(qualityTiers, name)
+	})
+}
+
+func TestTierSelectorBuiltIn(t *testing.T) {
+	tests := map[string]string{
+		"4k":    "bestvideo[height<=2160]+bestaudio/best[height<=2160]",
+		"1080p": "bestvideo[height<=1080][vcodec^=avc1]+bestaudio[acodec^=mp4a]/bestvideo[height<=1080]+bestaudio/best[height<=1080]",
+		"720p":  "bestvideo[height<=720][vcodec^=avc1]+bestaudio[acodec^=mp4a]/bestvideo[height<=720]+bestaudio/best[height<=720]",
+		"480p":  "bestvideo[height<=480][vcodec^=avc1]+bestaudio[acodec^=mp4a]/bestvideo[height<=480]+bestaudio/best[height<=480]",
+		"audio": "bestaudio/best",
+	}
+	for tier, want := range tests {
+		got, err := TierSelector(tier)
+		if err != nil || got != want {
+			t.Errorf("TierSelector(%q) = %q, %v; want %q", tier, got, err, want)
+		}
+		if err := ValidateFormatSelector(got); err != nil {
+			t.Errorf("tier %q selector is invalid: %v", tier, err)
+		}
+	}
+	if got, err := TierSelector("1080P"); err != nil || got != tests["1080p"] {
+		t.Errorf("tier names are case-sensitive: %q, %v", got, err)
+	}
+}
+
+func TestTierSelectorUnknown(t *testing.T) {
+	_, err := TierSelector("8k")
+	if err == nil {
+		t.Fatal("unknown tier accepted")
+	}
+	if !strings.Contains(err.Error(), "1080p, 480p, 4k, 720p, audio") {
+		t.Errorf("err = %v, want the known tiers listed", err)
+	}
+}
+
+func TestRegisterTier(t *testing.T) {
+	removeTierAfterTest(t, "mobile")
+	if err := RegisterTier(" Mobile ", "best[height<=360][ext=mp4]/best[height<=360]"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := TierSelector("mobile"); err != nil || got != "best[height<=360][ext=mp4]/best[height<=360]" {
+		t.Errorf("registered tier = %q, %v", got, err)
+	}
+
+	// Built-in tiers can be replaced
+	old, _ := TierSelector("720p")
+	t.Cleanup(func() { RegisterTier("720p", old) })
+	if err := RegisterTier("720p", "best[height<=720]"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := TierSelector("720p"); got != "best[height<=720]" {
+		t.Errorf("720p = %q after replacing it", got)
+	}
+
+	if err := RegisterTier("", "best"); err == nil {
+		t.Error("empty name accepted")
+	}
+	if err := RegisterTier("evil", "--exec id"); err == nil {
+		t.Error("invalid selector accepted")
+	}
+	if _, err := TierSelector("evil"); err == nil {
+		t.Error("rejected tier was registered")
+	}
+}
+
+func TestDownloadTier(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+"\n"+writeOutput("webm")+"\n"+writePrintedPath)
+	useFakeFFMPEG(t, "exit 0")
+	dir := t.TempDir()
+
+	path, err := DownloadTier("https://www.youtube.com/watch?v=aaaaaaaaaaa", "480p", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := TierSelector("480p")
+	if got := argValue(calls()[0], "-f"); got != want {
+		t.Errorf("-f %q, want the 480p selector", got)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("path = %s", path)
+	}
+
+	if _, err := DownloadTier("https://www.youtube.com/watch?v=aaaaaaaaaaa", "potato", dir); err == nil {
+		t.Error("unknown tier downloaded")
+	}
+	if len(calls()) != 1 {
+		t.Error("yt-dlp ran for an unknown tier")
+	}
+}
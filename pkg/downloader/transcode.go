@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// TranscodeResolution re-encodes a local video to the given height (e.g. "480")
+// with codec, which is an encoder (e.g. "libx264") or a codec name ffmpeg can
+// encode (e.g. "h264", "av1", or a yt-dlp tag such as "avc1"). The width is
+// computed from the aspect ratio, rounded to an even number as most encoders
+// require. Audio is copied unchanged, so outputPath's container must be able
+// to hold it.
+//
+// Example:
+//
+//	err := downloader.TranscodeResolution("video.mp4", "480", "h264", "video_480p.mp4")
+func TranscodeResolution(srcPath string, resolution string, codec string, outputPath string) error {
+	return TranscodeResolutionWithContext(context.Background(), srcPath, resolution, codec, outputPath)
+}
+
+// TranscodeResolutionWithContext is TranscodeResolution with a caller-supplied context
+func TranscodeResolutionWithContext(ctx context.Context, srcPath string, resolution string, codec string, outputPath string) error {
+	height, err := strconv.Atoi(resolution)
+	if err != nil || height <= 0 {
+		return fmt.Errorf("invalid resolution %q: expected a height in pixels", resolution)
+	}
+	if _, err := os.Stat(srcPath); err != nil {
+		return fmt.Errorf("video file not found: %w", err)
+	}
+
+	// Auto-install binaries if needed (only happens once)
+	if err := ensureBinariesInstalled(); err != nil {
+		return fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+	if !ffmpegAvailable() {
+		return fmt.Errorf("%w: transcoding video", ErrFFMPEGRequired)
+	}
+	encoder, err := resolveVideoEncoder(codec)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(outputPath); dir != "." {
+		if err := makeOutputDir(dir); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	err = convertToFile(ctx, outputPath, nil, func(dst string) []string {
+		return transcodeArgs(srcPath, height, encoder, dst)
+	})
+	if err != nil {
+		return fmt.Errorf("ffmpeg transcode failed: %w", diskFullError(err))
+	}
+	return nil
+}
+
+// resolveVideoEncoder returns the ffmpeg encoder for codec, which is either an
+// encoder name or a codec ffmpeg has an encoder for
+func resolveVideoEncoder(codec string) (string, error) {
+	if codec == "" {
+		return "", fmt.Errorf("codec is required")
+	}
+	encoders, err := ffmpegEncoders()
+	if err != nil {
+		return "", err
+	}
+	if encoders.names[codec] {
+		return codec, nil
+	}
+	if candidates := encoders.forCodec(codec); len(candidates) > 0 {
+		return candidates[0], nil
+	}
+	return "", fmt.Errorf("ffmpeg at %s cannot encode %s", FFMPEGPath, codec)
+}
+
+// transcodeArgs builds the ffmpeg arguments for scaling input to height.
+// scale=-2:H keeps the aspect ratio with an even width.
+func transcodeArgs(input string, height int, encoder string, output string) []string {
+	return []string{
+		"-i", input,
+		"-vf", fmt.Sprintf("scale=-2:%d", height),
+		"-c:v", encoder,
+		"-c:a", "copy",
+		"-movflags", "+faststart",
+		"-y",
+		output,
+	}
+}
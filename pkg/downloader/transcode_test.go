@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTranscodeArgs(t *testing.T) {
+	got := transcodeArgs("in.mkv", 480, "libx264", "out.mp4")
+	want := []string{"-i", "in.mkv", "-vf", "scale=-2:480", "-c:v", "libx264", "-c:a", "copy", "-movflags", "+faststart", "-y", "out.mp4"}
+	if !slices.Equal(got, want) {
+		t.Errorf("transcodeArgs = %q, want %q", got, want)
+	}
+}
+
+func TestResolveVideoEncoder(t *testing.T) {
+	useFakeFFMPEG(t, ffmpegScript("exit 0"))
+	tests := []struct {
+		codec, want string
+		ok          bool
+	}{
+		{"libx264", "libx264", true},
+		{"h264", "libx264", true},
+		{"vp9", "libvpx-vp9", true},
+		{"hevc", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := resolveVideoEncoder(tt.codec)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("resolveVideoEncoder(%q) = %q, %v; want %q", tt.codec, got, err, tt.want)
+		}
+	}
+}
+
+func TestTranscodeResolution(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(logLine+"\n"+writeLastArg))
+	dir := t.TempDir()
+	src := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(src, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "scaled", "video_480p.mp4")
+
+	if err := TranscodeResolution(src, "480", "h264", out); err != nil {
+		t.Fatal(err)
+	}
+	args := calls()[0]
+	if argValue(args, "-i") != src || argValue(args, "-vf") != "scale=-2:480" || argValue(args, "-c:v") != "libx264" {
+		t.Errorf("ffmpeg args %v", args)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "converted" {
+		t.Errorf("%s holds %q", out, data)
+	}
+	if data, _ := os.ReadFile(src); string(data) != "video" {
+		t.Error("source modified")
+	}
+}
+
+func TestTranscodeResolutionValidation(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeFFMPEG(t, ffmpegScript(logLine))
+	src := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(src, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "out.mp4")
+
+	for _, resolution := range []string{"", "480p", "-480", "0", "4.5"} {
+		if err := TranscodeResolution(src, resolution, "h264", out); err == nil || !strings.Contains(err.Error(), "invalid resolution") {
+			t.Errorf("resolution %q: err = %v", resolution, err)
+		}
+	}
+	if err := TranscodeResolution(src, "480", "hevc", out); err == nil || !strings.Contains(err.Error(), "cannot encode") {
+		t.Errorf("unsupported codec: err = %v", err)
+	}
+	if err := TranscodeResolution(filepath.Join(t.TempDir(), "missing.mp4"), "480", "h264", out); err == nil {
+		t.Error("missing source accepted")
+	}
+	if len(calls()) != 0 {
+		t.Errorf("ffmpeg ran for invalid input: %v", calls())
+	}
+
+	withoutFFMPEG(t)
+	if err := TranscodeResolution(src, "480", "h264", out); !errors.Is(err, ErrFFMPEGRequired) {
+		t.Errorf("err = %v, want ErrFFMPEGRequired", err)
+	}
+}
@@ -0,0 +1,139 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// GetTranscript downloads the caption track for lang (e.g. "en") and returns it
+// as plain text with timestamps and formatting removed, for summarization or search.
+// Uploaded subtitles are preferred; auto-generated captions are used otherwise.
+// Returns an error if the video has no captions in that language.
+//
+// Example:
+//
+//	text, err := downloader.GetTranscript("https://www.youtube.com/watch?v=dQw4w9WgXcQ", "en")
+func GetTranscript(url string, lang string) (string, error) {
+	if lang == "" {
+		return "", fmt.Errorf("language is required")
+	}
+
+	// Auto-install yt-dlp if needed (only happens once)
+	if err := ensureYTDLPInstalled(); err != nil {
+		return "", fmt.Errorf("failed to ensure binaries are installed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	workDir, err := os.MkdirTemp("", "gostreampuller-subs-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	cmd := ytdlpCommand(ctx,
+		"--skip-download",
+		"--write-subs",
+		"--write-auto-subs",
+		"--sub-langs", lang,
+		"--sub-format", "vtt/srt/best",
+		"--no-playlist",
+		"--no-warnings",
+		"-o", filepath.Join(workDir, "subs.%(ext)s"),
+		"--user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"--referer", "https://www.youtube.com/",
+		url,
+	)
+
+	if _, err := cmd.Output(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("failed to fetch subtitles: %w", newDownloadError(cmd, "subtitles", string(exitErr.Stderr), err))
+		}
+		return "", fmt.Errorf("failed to execute yt-dlp: %w", err)
+	}
+
+	// yt-dlp names the file subs.<lang>.<ext>
+	var subtitleFile string
+	for _, ext := range []string{"vtt", "srt"} {
+		matches, _ := filepath.Glob(filepath.Join(workDir, "subs.*."+ext))
+		if len(matches) > 0 {
+			subtitleFile = matches[0]
+			break
+		}
+	}
+	if subtitleFile == "" {
+		return "", fmt.Errorf("no subtitles available for language %q", lang)
+	}
+
+	data, err := os.ReadFile(subtitleFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read subtitles: %w", err)
+	}
+
+	return subtitlesToText(string(data)), nil
+}
+
+var (
+	// subtitleTagPattern matches VTT/SRT inline markup such as <c>, <i>, <00:00:01.000>
+	subtitleTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+	// cueIndexPattern matches SRT cue numbers (and numeric VTT cue identifiers)
+	cueIndexPattern = regexp.MustCompile(`^\d+$`)
+)
+
+// subtitlesToText converts a WebVTT or SRT document into plain text, one caption
+// line per output line. Consecutive duplicate lines (common in auto-generated
+// captions, which repeat the previous line as they scroll) are collapsed.
+func subtitlesToText(data string) string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+
+	var lines []string
+	inHeaderBlock := false
+	blockStart := true
+	rawLines := strings.Split(data, "\n")
+	for i, raw := range rawLines {
+		line := strings.TrimSpace(raw)
+		first := blockStart
+		blockStart = line == ""
+
+		switch {
+		case line == "":
+			inHeaderBlock = false
+			continue
+		case strings.HasPrefix(line, "WEBVTT"),
+			strings.HasPrefix(line, "NOTE"),
+			strings.HasPrefix(line, "STYLE"),
+			strings.HasPrefix(line, "REGION"):
+			// Metadata blocks run until the next blank line
+			inHeaderBlock = true
+			continue
+		case inHeaderBlock:
+			continue
+		case strings.Contains(line, "-->"):
+			continue
+		case first && cueIndexPattern.MatchString(line) && i+1 < len(rawLines) && strings.Contains(rawLines[i+1], "-->"):
+			// A cue number opens its block, right before the timing line;
+			// numbers elsewhere are captions ("2019", "100")
+			continue
+		}
+
+		text := strings.TrimSpace(html.UnescapeString(subtitleTagPattern.ReplaceAllString(line, "")))
+		if text == "" {
+			continue
+		}
+		if len(lines) > 0 && lines[len(lines)-1] == text {
+			continue
+		}
+		lines = append(lines, text)
+	}
+
+	return strings.Join(lines, "\n")
+}
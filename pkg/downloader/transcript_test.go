@@ -0,0 +1,77 @@
+package downloader
+
+import "testing"
+
+func TestSubtitlesToTextVTT(t *testing.T) {
+	vtt := "WEBVTT\r\nKind: captions\r\nLanguage: en\r\n\r\n" +
+		"STYLE\n::cue { color: white }\n\n" +
+		"00:00:00.000 --> 00:00:02.000 align:start position:0%\n" +
+		"Hello <c.colorE5E5E5><00:00:00.500><c>world</c></c>\n\n" +
+		"00:00:02.000 --> 00:00:04.000\n" +
+		"Hello world\n" +
+		"Tom &amp; Jerry <i>again</i>\n\n" +
+		"NOTE this is a comment\nspanning lines\n\n" +
+		"00:00:04.000 --> 00:00:06.000\n" +
+		"<b></b>\n" +
+		"The end\n"
+	want := "Hello world\nTom & Jerry again\nThe end"
+	if got := subtitlesToText(vtt); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestSubtitlesToTextSRT(t *testing.T) {
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nFirst line\n\n" +
+		"2\n00:00:02,000 --> 00:00:03,000\nSecond line\nstill second\n"
+	want := "First line\nSecond line\nstill second"
+	if got := subtitlesToText(srt); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestSubtitlesToTextNumericCaptions(t *testing.T) {
+	// Cue numbers are dropped, captions that are only a number are kept
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nIt was\n2019\n\n" +
+		"2\n00:00:02,000 --> 00:00:03,000\n100\n\n" +
+		"3\n00:00:03,000 --> 00:00:04,000\npercent\n"
+	if got, want := subtitlesToText(srt), "It was\n2019\n100\npercent"; got != want {
+		t.Errorf("SRT: got  %q\nwant %q", got, want)
+	}
+
+	vtt := "WEBVTT\n\n" +
+		"1\n00:00:00.000 --> 00:00:02.000\n42\n\n" +
+		"00:00:02.000 --> 00:00:04.000\n7\nseven\n"
+	if got, want := subtitlesToText(vtt), "42\n7\nseven"; got != want {
+		t.Errorf("VTT: got  %q\nwant %q", got, want)
+	}
+}
+
+func TestGetTranscript(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+out=""; prev=""
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nHi there\n' > "$(printf '%s' "$out" | sed 's/%(ext)s/en.vtt/')"`)
+
+	text, err := GetTranscript("https://www.youtube.com/watch?v=aaaaaaaaaaa", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Hi there" {
+		t.Errorf("text = %q, want %q", text, "Hi there")
+	}
+	if args := calls()[0]; argValue(args, "--sub-langs") != "en" || !hasArgs(args, "--skip-download") {
+		t.Errorf("yt-dlp args %v", args)
+	}
+
+	if _, err := GetTranscript("https://www.youtube.com/watch?v=aaaaaaaaaaa", ""); err == nil {
+		t.Error("empty language accepted")
+	}
+}
+
+func TestGetTranscriptNoSubtitles(t *testing.T) {
+	useFakeYTDLP(t, "exit 0")
+	if _, err := GetTranscript("https://www.youtube.com/watch?v=aaaaaaaaaaa", "de"); err == nil {
+		t.Error("expected an error when no subtitles were written")
+	}
+}
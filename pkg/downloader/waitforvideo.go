@@ -0,0 +1,67 @@
+package downloader
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// waitForVideoArgs returns yt-dlp's --wait-for-video flag re-checking an
+// upcoming video every interval, or at a random interval up to maxInterval
+// if it is set. A zero interval disables waiting.
+func waitForVideoArgs(interval time.Duration, maxInterval time.Duration) ([]string, error) {
+	if interval == 0 && maxInterval == 0 {
+		return nil, nil
+	}
+	if interval < time.Second {
+		return nil, fmt.Errorf("wait-for-video interval must be at least 1s, got %s", interval)
+	}
+	value := strconv.Itoa(int(math.Ceil(interval.Seconds())))
+	if maxInterval != 0 {
+		if maxInterval < interval {
+			return nil, fmt.Errorf("maximum wait-for-video interval %s is shorter than %s", maxInterval, interval)
+		}
+		value += "-" + strconv.Itoa(int(math.Ceil(maxInterval.Seconds())))
+	}
+	return []string{"--wait-for-video", value}, nil
+}
+
+// TimeUntilStart estimates how long until an upcoming premiere or live stream
+// starts. Returns 0 if the video is not upcoming, has no scheduled start, or
+// is already past it.
+func (m *VideoMetadata) TimeUntilStart() time.Duration {
+	if !m.IsUpcoming || m.ScheduledAt == nil {
+		return 0
+	}
+	if wait := time.Until(*m.ScheduledAt); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// waitPattern matches yt-dlp's countdown while waiting for a video:
+//
+//	[wait] Waiting for 01:02:03 - Press Ctrl+C to try now
+//	[wait] Remaining time until next attempt: 04:59
+var waitPattern = regexp.MustCompile(`\[wait\] (?:Waiting for|Remaining time until next attempt:) ((?:\d+:)?\d+:\d+)`)
+
+// parseWaitLine returns the remaining wait from a yt-dlp countdown line. The
+// countdown is redrawn with carriage returns, so the last one on the line wins.
+func parseWaitLine(line string) (time.Duration, bool) {
+	matches := waitPattern.FindAllStringSubmatch(line, -1)
+	if matches == nil {
+		return 0, false
+	}
+	var wait time.Duration
+	for _, part := range strings.Split(matches[len(matches)-1][1], ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+		wait = wait*60 + time.Duration(n)*time.Second
+	}
+	return wait, true
+}
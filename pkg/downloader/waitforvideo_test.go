@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaitForVideoArgs(t *testing.T) {
+	tests := []struct {
+		interval, max time.Duration
+		want          []string
+		ok            bool
+	}{
+		{0, 0, nil, true},
+		{time.Minute, 0, []string{"--wait-for-video", "60"}, true},
+		{90 * time.Second, 10 * time.Minute, []string{"--wait-for-video", "90-600"}, true},
+		{1500 * time.Millisecond, 0, []string{"--wait-for-video", "2"}, true},
+		{time.Minute, time.Minute, []string{"--wait-for-video", "60-60"}, true},
+		{500 * time.Millisecond, 0, nil, false},
+		{0, time.Minute, nil, false},
+		{time.Minute, 30 * time.Second, nil, false},
+		{-time.Minute, 0, nil, false},
+	}
+	for _, tt := range tests {
+		got, err := waitForVideoArgs(tt.interval, tt.max)
+		if (err == nil) != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("waitForVideoArgs(%v, %v) = %v, %v; want %v", tt.interval, tt.max, got, err, tt.want)
+		}
+	}
+}
+
+func TestTimeUntilStart(t *testing.T) {
+	future := time.Now().Add(3 * time.Hour).Unix()
+	upcoming := parseMetadata(t, `{"live_status": "is_upcoming", "release_timestamp": `+strconv.FormatInt(future, 10)+`}`)
+	if !upcoming.IsUpcoming {
+		t.Fatal("is_upcoming not detected")
+	}
+	if wait := upcoming.TimeUntilStart(); wait < 2*time.Hour || wait > 3*time.Hour {
+		t.Errorf("TimeUntilStart() = %v, want about 3h", wait)
+	}
+
+	for _, data := range []string{
+		`{"live_status": "is_upcoming"}`,
+		`{"live_status": "is_upcoming", "release_timestamp": 1000000000}`,
+		`{"live_status": "not_live", "release_timestamp": 4000000000}`,
+	} {
+		if wait := parseMetadata(t, data).TimeUntilStart(); wait != 0 {
+			t.Errorf("%s: TimeUntilStart() = %v, want 0", data, wait)
+		}
+	}
+}
+
+func TestParseWaitLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want time.Duration
+		ok   bool
+	}{
+		{"[wait] Waiting for 01:02:03 - Press Ctrl+C to try now", time.Hour + 2*time.Minute + 3*time.Second, true},
+		{"[wait] Remaining time until next attempt: 04:59", 4*time.Minute + 59*time.Second, true},
+		{"[wait] Remaining time until next attempt: 05:00\r[wait] Remaining time until next attempt: 04:58", 4*time.Minute + 58*time.Second, true},
+		{"[download]  10.0% of 1.00MiB at 1.00MiB/s ETA 00:01", 0, false},
+		{"[wait] Waiting for video", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseWaitLine(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseWaitLine(%q) = %v, %v; want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDownloadWaitsForVideo(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine+`
+echo "[wait] Remaining time until next attempt: 04:59"
+`+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+
+	var mu sync.Mutex
+	var stages []string
+	_, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir:       t.TempDir(),
+		WaitForVideo:    time.Minute,
+		WaitForVideoMax: 5 * time.Minute,
+		Progress: func(p DownloadProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			stages = append(stages, p.Stage)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasArgs(calls()[0], "--wait-for-video", "60-300") {
+		t.Errorf("args %v, want --wait-for-video 60-300", calls()[0])
+	}
+	if !slices.Contains(stages, "Waiting for the video to start (4m59s left)") {
+		t.Errorf("stages %q, want the remaining wait", stages)
+	}
+}
+
+func TestDownloadWaitForVideoCancelled(t *testing.T) {
+	useFakeYTDLP(t, `echo "[wait] Waiting for 02:00:00 - Press Ctrl+C to try now"
+exec sleep 30`)
+	useFakeFFMPEG(t, "exit 0")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	_, err := DownloadVideoWithOptions(ctx, "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir:    t.TempDir(),
+		WaitForVideo: time.Minute,
+		Progress: func(p DownloadProgress) {
+			if strings.HasPrefix(p.Stage, "Waiting for the video") {
+				cancel()
+			}
+		},
+	})
+	if err == nil {
+		t.Error("cancelled download succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("cancelled wait took %v", elapsed)
+	}
+}
+
+func TestDownloadWaitForVideoValidation(t *testing.T) {
+	logLine, calls := argsLog(t)
+	useFakeYTDLP(t, logLine)
+	useFakeFFMPEG(t, "exit 0")
+
+	if _, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		OutputDir:       t.TempDir(),
+		WaitForVideo:    time.Minute,
+		WaitForVideoMax: time.Second,
+	}); err == nil {
+		t.Error("maximum shorter than the interval accepted")
+	}
+	if len(calls()) != 0 {
+		t.Errorf("yt-dlp ran for an invalid request: %v", calls())
+	}
+}
@@ -0,0 +1,60 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestParseWarning(t *testing.T) {
+	tests := []struct{ line, want string }{
+		{"WARNING: [youtube] aaaaaaaaaaa: Requested format is not available, falling back", "[youtube] aaaaaaaaaaa: Requested format is not available, falling back"},
+		{"  WARNING:   padded  ", "padded"},
+		{"WARNING: [download] Got error: timed out. Retrying (1/10)...", ""},
+		{"WARNING: The download speed shown is only of one thread. This is a known issue", ""},
+		{"WARNING: [youtube] Falling back to generic n function search", ""},
+		{"ERROR: [youtube] aaaaaaaaaaa: Private video", ""},
+		{"[download]  45.3% of 12.34MiB", ""},
+	}
+	for _, tt := range tests {
+		if got := parseWarning(tt.line); got != tt.want {
+			t.Errorf("parseWarning(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadReportsWarnings(t *testing.T) {
+	useFakeYTDLP(t, `echo "WARNING: [youtube] aaaaaaaaaaa: Requested format is not available, falling back to 720p" >&2
+echo "WARNING: [youtube] aaaaaaaaaaa: Requested format is not available, falling back to 720p" >&2
+echo "WARNING: [youtube] Falling back to generic n function search" >&2
+echo "[info] aaaaaaaaaaa: Downloading 1 format(s): 22"
+`+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("warnings made the download fail: %v", err)
+	}
+	want := []string{"[youtube] aaaaaaaaaaa: Requested format is not available, falling back to 720p"}
+	if !slices.Equal(result.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", result.Warnings, want)
+	}
+}
+
+func TestDownloadWarningsCapped(t *testing.T) {
+	script := ""
+	for i := range maxWarnings + 5 {
+		script += fmt.Sprintf("echo 'WARNING: warning %d' >&2\n", i)
+	}
+	useFakeYTDLP(t, script+writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != maxWarnings || result.Warnings[0] != "warning 0" {
+		t.Errorf("got %d warnings %q, want the first %d", len(result.Warnings), result.Warnings, maxWarnings)
+	}
+}
@@ -0,0 +1,136 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// TempWorkDir is where downloads and conversions write their working files
+// (e.g. a fast local disk or tmpfs). The finished file is then moved to the
+// requested output directory. Empty means work directly in the output directory.
+// Can be set using SetTempWorkDir()
+var TempWorkDir string
+
+// SetTempWorkDir sets the directory for working files, creating it if needed.
+// An empty dir works directly in the output directory.
+func SetTempWorkDir(dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create temp work directory: %w", err)
+		}
+	}
+	TempWorkDir = dir
+	return nil
+}
+
+// workDir returns the directory working files for a download into outputDir go to
+func workDir(outputDir string) string {
+	if TempWorkDir != "" {
+		return TempWorkDir
+	}
+	return outputDir
+}
+
+// moveFile moves src into dstDir, copying when a rename is not possible
+// (e.g. across filesystems). Returns the new path.
+func moveFile(ctx context.Context, src string, dstDir string, progressCb ProgressCallback) (string, error) {
+	if dstDir != "" {
+		if err := makeOutputDir(dstDir); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	dst := filepath.Join(dstDir, filepath.Base(src))
+
+	if err := os.Rename(src, dst); err == nil {
+		return dst, nil
+	}
+
+	if err := copyFileStreaming(ctx, src, dst, progressCb); err != nil {
+		return "", err
+	}
+	os.Remove(src)
+	return dst, nil
+}
+
+// writeCopy writes a chunk of a streaming copy; a variable so tests can make
+// the disk run full
+var writeCopy = (*os.File).Write
+
+// copyFileStreaming copies a file using streaming to handle large files efficiently.
+// Progress is reported to progressCb (if set) with the "moving" stage. If ctx is
+// cancelled or the copy fails, the partial destination is removed.
+func copyFileStreaming(ctx context.Context, src string, dst string, progressCb ProgressCallback) (err error) {
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer sourceFile.Close()
+
+	info, err := sourceFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	// Write next to dst and rename on success so dst is never half-written
+	partial := partialPath(dst)
+	destFile, err := os.Create(partial)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer func() {
+		destFile.Close()
+		if err != nil {
+			os.Remove(partial)
+		}
+	}()
+
+	throttle := newProgressThrottle(progressCb, ProgressInterval)
+	total := info.Size()
+	var written int64
+
+	// Use buffered I/O for better performance with large files
+	buf := make([]byte, ChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("copy cancelled: %w", err)
+		}
+
+		n, readErr := sourceFile.Read(buf)
+		if n > 0 {
+			if _, err := writeCopy(destFile, buf[:n]); err != nil {
+				return fmt.Errorf("failed to copy file: %w", diskFullError(err))
+			}
+			written += int64(n)
+
+			if progressCb != nil {
+				progress := DownloadProgress{
+					Stage:           "moving",
+					BytesDownloaded: written,
+					TotalBytes:      total,
+				}
+				if total > 0 {
+					progress.Percentage = float64(written) / float64(total) * 100
+				}
+				throttle.emit(progress)
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to copy file: %w", readErr)
+		}
+	}
+
+	if err := destFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", diskFullError(err))
+	}
+	if err := destFile.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", diskFullError(err))
+	}
+	return commitPartial(partial, dst)
+}
@@ -0,0 +1,103 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyFileStreamingProgress(t *testing.T) {
+	setForTest(t, &ChunkSize, 4)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.mp4")
+	dst := filepath.Join(dir, "out", "dst.mp4")
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []DownloadProgress
+	if err := copyFileStreaming(context.Background(), src, dst, func(p DownloadProgress) {
+		events = append(events, p)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "0123456789" {
+		t.Errorf("copied %q", data)
+	}
+
+	var written []int64
+	for _, event := range events {
+		if event.Stage != "moving" || event.TotalBytes != 10 {
+			t.Errorf("event %+v", event)
+		}
+		written = append(written, event.BytesDownloaded)
+	}
+	if len(written) != 3 || written[0] != 4 || written[1] != 8 || written[2] != 10 {
+		t.Errorf("progress reported %v bytes, want [4 8 10]", written)
+	}
+	if last := events[len(events)-1]; last.Percentage != 100 {
+		t.Errorf("last event at %.1f%%, want 100", last.Percentage)
+	}
+}
+
+func TestCopyFileStreamingCancelled(t *testing.T) {
+	setForTest(t, &ChunkSize, 4)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.mp4")
+	dst := filepath.Join(dir, "dst.mp4")
+	if err := os.WriteFile(src, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := copyFileStreaming(ctx, src, dst, func(DownloadProgress) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	// Neither the destination nor the partial file are left behind
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.Name() != "src.mp4" {
+			t.Errorf("left behind %s", entry.Name())
+		}
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("source removed on cancellation: %v", err)
+	}
+}
+
+func TestDownloadWithTempWorkDir(t *testing.T) {
+	useFakeYTDLP(t, writeOutput("mp4"))
+	useFakeFFMPEG(t, "exit 0")
+	work := t.TempDir()
+	setForTest(t, &TempWorkDir, "")
+	if err := SetTempWorkDir(filepath.Join(work, "nested")); err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+
+	result, err := DownloadVideoWithOptions(context.Background(), "https://www.youtube.com/watch?v=aaaaaaaaaaa", VideoOptions{
+		Format:    "mp4",
+		OutputDir: output,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(result.Path) != output {
+		t.Errorf("path %s, want it moved into %s", result.Path, output)
+	}
+	entries, _ := os.ReadDir(filepath.Join(work, "nested"))
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			t.Errorf("working file %s left in the temp work dir", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+var (
+	// ErrArchiveCorrupt means a downloaded archive could not be read, usually
+	// because the download was truncated; retrying the install downloads it again
+	ErrArchiveCorrupt = errors.New("archive is corrupt or incomplete")
+
+	// ErrBinaryNotInArchive means the archive was read completely but holds no
+	// ffmpeg binary, e.g. because the release layout changed
+	ErrBinaryNotInArchive = errors.New("ffmpeg binary not found in archive")
+)
+
+// archiveReader records read errors so they can be told apart from write errors
+type archiveReader struct {
+	r   io.Reader
+	err error
+}
+
+func (a *archiveReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if err != nil && err != io.EOF {
+		a.err = err
+	}
+	return n, err
+}
+
+// writeBinary extracts an executable from src to destPath. A failed read is
+// reported as ErrArchiveCorrupt and never leaves a partial binary behind.
+func writeBinary(destPath string, src io.Reader) error {
+	outFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+
+	archive := &archiveReader{r: src}
+	_, err = io.Copy(outFile, archive)
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(destPath)
+		if archive.err != nil {
+			return fmt.Errorf("%w: %v", ErrArchiveCorrupt, archive.err)
+		}
+		return err
+	}
+
+	if runtime.GOOS != "windows" {
+		// OpenFile's mode is reduced by the umask and ignored for existing files
+		return os.Chmod(destPath, 0755)
+	}
+	return nil
+}
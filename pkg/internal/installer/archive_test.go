@@ -0,0 +1,175 @@
+package installer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// archiveEntry is a file to put in a test archive
+type archiveEntry struct{ name, body string }
+
+// ffmpegName is the name extractFFMPEGFromZip looks for on this platform
+func ffmpegName() string {
+	if runtime.GOOS == "windows" {
+		return "ffmpeg.exe"
+	}
+	return "ffmpeg"
+}
+
+// zipArchive returns a zip holding entries, stored uncompressed so tests can
+// corrupt file contents in place
+func zipArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(entry.body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tarGzArchive returns a tar.gz holding entries
+func tarGzArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0755, Size: int64(len(entry.body)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(entry.body))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// writeArchive saves data as name in a new directory and returns its path
+func writeArchive(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// assertNoBinary fails if an extraction left a binary in dir
+func assertNoBinary(t *testing.T, dir string) {
+	t.Helper()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("extraction left %v behind", entries)
+	}
+}
+
+func TestExtractFFMPEGFromZip(t *testing.T) {
+	binary := "ffmpeg binary contents"
+	archive := writeArchive(t, "ffmpeg.zip", zipArchive(t,
+		archiveEntry{"ffmpeg-7.0/doc/" + ffmpegName() + ".html", "docs"},
+		archiveEntry{"ffmpeg-7.0/bin/" + ffmpegName(), binary},
+	))
+	dest := t.TempDir()
+
+	if err := extractFFMPEGFromZip(archive, dest, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, ffmpegName())); string(data) != binary {
+		t.Errorf("extracted %q", data)
+	}
+}
+
+func TestExtractFFMPEGFromZipWithoutBinary(t *testing.T) {
+	archive := writeArchive(t, "ffmpeg.zip", zipArchive(t,
+		archiveEntry{"ffmpeg-7.0/bin/ffprobe-only", "probe"},
+		archiveEntry{"ffmpeg-7.0/README.txt", "readme"},
+	))
+	dest := t.TempDir()
+
+	err := extractFFMPEGFromZip(archive, dest, nil)
+	if !errors.Is(err, ErrBinaryNotInArchive) || errors.Is(err, ErrArchiveCorrupt) {
+		t.Errorf("err = %v, want ErrBinaryNotInArchive", err)
+	}
+	assertNoBinary(t, dest)
+}
+
+func TestExtractFFMPEGFromTruncatedZip(t *testing.T) {
+	data := zipArchive(t, archiveEntry{"ffmpeg-7.0/bin/" + ffmpegName(), "ffmpeg binary contents"})
+	// A download cut short loses the central directory at the end
+	archive := writeArchive(t, "ffmpeg.zip", data[:len(data)/2])
+	dest := t.TempDir()
+
+	err := extractFFMPEGFromZip(archive, dest, nil)
+	if !errors.Is(err, ErrArchiveCorrupt) || errors.Is(err, ErrBinaryNotInArchive) {
+		t.Errorf("err = %v, want ErrArchiveCorrupt", err)
+	}
+	assertNoBinary(t, dest)
+}
+
+func TestExtractFFMPEGFromZipCorruptBinary(t *testing.T) {
+	binary := "ffmpeg binary contents"
+	data := zipArchive(t, archiveEntry{"ffmpeg-7.0/bin/" + ffmpegName(), binary})
+	// Damage the stored contents; the checksum no longer matches
+	i := bytes.Index(data, []byte(binary))
+	data[i] ^= 0xff
+	archive := writeArchive(t, "ffmpeg.zip", data)
+	dest := t.TempDir()
+
+	if err := extractFFMPEGFromZip(archive, dest, nil); !errors.Is(err, ErrArchiveCorrupt) {
+		t.Errorf("err = %v, want ErrArchiveCorrupt", err)
+	}
+	assertNoBinary(t, dest)
+}
+
+func TestExtractFFMPEGFromTarGzErrors(t *testing.T) {
+	valid := tarGzArchive(t, archiveEntry{"release/ffmpeg", "ffmpeg binary contents"})
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated", valid[:len(valid)/2], ErrArchiveCorrupt},
+		{"not gzip", []byte("not a gzip stream"), ErrArchiveCorrupt},
+		{"no binary", tarGzArchive(t, archiveEntry{"release/ffprobe", "probe"}), ErrBinaryNotInArchive},
+	}
+	for _, tt := range tests {
+		archive := writeArchive(t, "ffmpeg.tar.gz", tt.data)
+		dest := t.TempDir()
+		if err := extractFFMPEGFromTar(archive, dest, nil); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+		assertNoBinary(t, dest)
+	}
+}
+
+func TestIsExecutableEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"ffmpeg", true},
+		{"ffmpeg-7.1-amd64-static/ffmpeg", true},
+		{"ffmpeg-7.1/bin/ffmpeg", true},
+		{"ffmpeg-7.1/ffmpeg/", false},
+		{"ffmpeg-7.1/ffmpeg.1", false},
+		{"ffmpeg-7.1/ffmpeg/README", false},
+		{"ffmpeg-7.1/ffprobe", false},
+	}
+	for _, tt := range tests {
+		if got := isExecutableEntry(tt.name, "ffmpeg"); got != tt.want {
+			t.Errorf("isExecutableEntry(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
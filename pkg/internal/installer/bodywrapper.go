@@ -0,0 +1,30 @@
+package installer
+
+import (
+	"io"
+	"sync"
+)
+
+var (
+	bodyWrapperMu sync.RWMutex
+	// bodyWrapper wraps the body of every binary download, e.g. to cap its bandwidth
+	bodyWrapper func(io.Reader) io.Reader
+)
+
+// SetBodyWrapper installs a function wrapping the response body of every
+// binary download, such as a rate limiter; nil removes it
+func SetBodyWrapper(wrap func(io.Reader) io.Reader) {
+	bodyWrapperMu.Lock()
+	defer bodyWrapperMu.Unlock()
+	bodyWrapper = wrap
+}
+
+// wrapBody applies the installed body wrapper to r
+func wrapBody(r io.Reader) io.Reader {
+	bodyWrapperMu.RLock()
+	defer bodyWrapperMu.RUnlock()
+	if bodyWrapper == nil {
+		return r
+	}
+	return bodyWrapper(r)
+}
@@ -0,0 +1,97 @@
+package installer
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	httpClientMu sync.RWMutex
+	// customClient is set once SetHTTPClient installed a caller's client
+	customClient bool
+	// insecureSkipVerify disables certificate verification on the default client
+	insecureSkipVerify bool
+	// httpClient is shared by every binary download so connections are pooled.
+	// Certificates are verified as usual; hosts with pinned keys must
+	// additionally present one of them (see SetPinnedKeys).
+	httpClient = &http.Client{Transport: defaultTransport()}
+)
+
+// defaultTransport returns the pooled transport of the default client. There is
+// no overall timeout since binaries can be large; stalled connections are
+// caught by the dial, handshake and response header timeouts instead.
+func defaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          20,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: insecureSkipVerify,
+			VerifyConnection:   verifyPinnedKeys,
+		},
+	}
+}
+
+// HTTPClient returns the client used for all network requests
+func HTTPClient() *http.Client {
+	httpClientMu.RLock()
+	defer httpClientMu.RUnlock()
+	return httpClient
+}
+
+// SetHTTPClient replaces the client used for all network requests; nil
+// restores the default. If the client's transport is an *http.Transport (or
+// nil, meaning http.DefaultTransport) without its own VerifyConnection, pinned
+// keys keep being enforced on a copy of it.
+func SetHTTPClient(client *http.Client) {
+	custom := client != nil
+	if client == nil {
+		client = &http.Client{Transport: defaultTransport()}
+	} else if transport, ok := transportOf(client).(*http.Transport); ok {
+		transport = transport.Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		if transport.TLSClientConfig.VerifyConnection == nil {
+			transport.TLSClientConfig.VerifyConnection = verifyPinnedKeys
+		}
+		copied := *client
+		copied.Transport = transport
+		client = &copied
+	}
+
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+	httpClient = client
+	customClient = custom
+}
+
+// SetInsecureSkipVerify turns certificate verification of the default client
+// on or off. Pinned keys are still enforced. A client installed with
+// SetHTTPClient is left alone; configure its TLS directly.
+func SetInsecureSkipVerify(enabled bool) {
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+	insecureSkipVerify = enabled
+	if !customClient {
+		httpClient = &http.Client{Transport: defaultTransport()}
+	}
+}
+
+// transportOf returns the round tripper client actually uses
+func transportOf(client *http.Client) http.RoundTripper {
+	if client.Transport == nil {
+		return http.DefaultTransport
+	}
+	return client.Transport
+}
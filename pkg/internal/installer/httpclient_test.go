@@ -0,0 +1,70 @@
+package installer
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc serves requests without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSetHTTPClientHonoredByDownloadFile(t *testing.T) {
+	var requested []string
+	SetHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("from the custom client")),
+			Request:    req,
+		}, nil
+	})})
+	t.Cleanup(func() { SetHTTPClient(nil) })
+
+	dst := filepath.Join(t.TempDir(), "yt-dlp")
+	if err := downloadFile("https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp", dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 1 || requested[0] != "https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp" {
+		t.Errorf("custom client requests %v", requested)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "from the custom client" {
+		t.Errorf("wrote %q", data)
+	}
+}
+
+func TestSetHTTPClientNilRestoresDefault(t *testing.T) {
+	custom := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, io.EOF })}
+	SetHTTPClient(custom)
+	SetHTTPClient(nil)
+
+	transport, ok := HTTPClient().Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig == nil || transport.TLSClientConfig.VerifyConnection == nil || transport.MaxIdleConnsPerHost != 4 {
+		t.Errorf("client after SetHTTPClient(nil) is not the pooled default: %#v", HTTPClient().Transport)
+	}
+	if customClient {
+		t.Error("still marked as a custom client")
+	}
+}
+
+func TestSetHTTPClientKeepsCallerTransport(t *testing.T) {
+	original := &http.Transport{MaxIdleConnsPerHost: 11}
+	SetHTTPClient(&http.Client{Transport: original})
+	t.Cleanup(func() { SetHTTPClient(nil) })
+
+	installed, ok := HTTPClient().Transport.(*http.Transport)
+	if !ok || installed.MaxIdleConnsPerHost != 11 || installed.TLSClientConfig.VerifyConnection == nil {
+		t.Errorf("installed transport %#v, want the caller's settings with pinning", HTTPClient().Transport)
+	}
+	// Pinning is added to a copy; the caller's transport is left alone
+	if original.TLSClientConfig != nil && original.TLSClientConfig.VerifyConnection != nil {
+		t.Error("caller's transport modified")
+	}
+}
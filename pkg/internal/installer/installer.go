@@ -4,14 +4,17 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 )
 
 const (
@@ -198,8 +201,8 @@ func InstallFFMPEG(progressFn func(string)) error {
 	}
 
 	if needsExtraction {
-		// Download to temp file
-		tmpFile := filepath.Join(os.TempDir(), "ffmpeg-download")
+		// Download to temp file, keeping the archive extension so extraction can detect it
+		tmpFile := filepath.Join(os.TempDir(), "ffmpeg-download."+archiveType)
 		if err := downloadFile(downloadURL, tmpFile, progressFn); err != nil {
 			return fmt.Errorf("failed to download ffmpeg: %w", err)
 		}
@@ -257,7 +260,7 @@ func InstallFFMPEG(progressFn func(string)) error {
 
 // downloadFile downloads a file from url to filepath
 func downloadFile(url, filepath string, progressFn func(string)) error {
-	resp, err := http.Get(url)
+	resp, err := HTTPClient().Get(url)
 	if err != nil {
 		return err
 	}
@@ -274,12 +277,13 @@ func downloadFile(url, filepath string, progressFn func(string)) error {
 	defer out.Close()
 
 	// Download with progress
+	body := wrapBody(resp.Body)
 	buf := make([]byte, 32*1024)
 	var downloaded int64
 	total := resp.ContentLength
 
 	for {
-		n, err := resp.Body.Read(buf)
+		n, err := body.Read(buf)
 		if n > 0 {
 			if _, writeErr := out.Write(buf[:n]); writeErr != nil {
 				return writeErr
@@ -300,6 +304,11 @@ func downloadFile(url, filepath string, progressFn func(string)) error {
 		}
 	}
 
+	// A dropped connection can end the body early without an error
+	if total > 0 && downloaded != total {
+		return fmt.Errorf("incomplete download: got %d of %d bytes", downloaded, total)
+	}
+
 	return nil
 }
 
@@ -307,7 +316,7 @@ func downloadFile(url, filepath string, progressFn func(string)) error {
 func extractFFMPEGFromZip(zipPath, destDir string, progressFn func(string)) error {
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %v", ErrArchiveCorrupt, err)
 	}
 	defer r.Close()
 
@@ -318,36 +327,20 @@ func extractFFMPEGFromZip(zipPath, destDir string, progressFn func(string)) erro
 
 	// Find and extract ffmpeg binary
 	for _, f := range r.File {
-		// Look for ffmpeg binary in the archive
-		if strings.Contains(f.Name, executable) && !strings.Contains(f.Name, "doc") {
+		// Look for ffmpeg binary in the archive. Match the base name only:
+		// release directories are named ffmpeg-<version> too.
+		if isExecutableEntry(f.Name, executable) && !f.FileInfo().IsDir() {
 			rc, err := f.Open()
 			if err != nil {
-				return err
+				return fmt.Errorf("%w: %v", ErrArchiveCorrupt, err)
 			}
 			defer rc.Close()
 
-			destPath := filepath.Join(destDir, executable)
-			outFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				return err
-			}
-			defer outFile.Close()
-
-			if _, err := io.Copy(outFile, rc); err != nil {
-				return err
-			}
-
-			if runtime.GOOS != "windows" {
-				if err := os.Chmod(destPath, 0755); err != nil {
-					return err
-				}
-			}
-
-			return nil
+			return writeBinary(filepath.Join(destDir, executable), rc)
 		}
 	}
 
-	return fmt.Errorf("ffmpeg binary not found in archive")
+	return ErrBinaryNotInArchive
 }
 
 // extractFFMPEGFromTar extracts ffmpeg binary from tar.gz or tar.xz archive
@@ -358,18 +351,22 @@ func extractFFMPEGFromTar(tarPath, destDir string, progressFn func(string)) erro
 	}
 	defer file.Close()
 
+	// Go has no xz reader in the standard library, so use the system tar
+	if strings.HasSuffix(tarPath, ".xz") {
+		file.Close()
+		return extractFFMPEGWithSystemTar(tarPath, destDir, progressFn)
+	}
+
 	// Handle gzip compression
 	var reader io.Reader = file
 	if strings.HasSuffix(tarPath, ".gz") {
 		gzr, err := gzip.NewReader(file)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: %v", ErrArchiveCorrupt, err)
 		}
 		defer gzr.Close()
 		reader = gzr
 	}
-	// Note: tar.xz would need xz decompression library
-	// For production, consider using exec to call tar command
 
 	tr := tar.NewReader(reader)
 
@@ -382,33 +379,90 @@ func extractFFMPEGFromTar(tarPath, destDir string, progressFn func(string)) erro
 			break
 		}
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: %v", ErrArchiveCorrupt, err)
 		}
 
 		// Look for ffmpeg binary
-		if strings.Contains(header.Name, "ffmpeg") &&
-		   !strings.Contains(header.Name, "doc") &&
-		   header.Typeflag == tar.TypeReg {
+		if isExecutableEntry(header.Name, executable) && header.Typeflag == tar.TypeReg {
+			return writeBinary(destPath, tr)
+		}
+	}
 
-			outFile, err := os.Create(destPath)
-			if err != nil {
-				return err
-			}
-			defer outFile.Close()
+	return ErrBinaryNotInArchive
+}
 
-			if _, err := io.Copy(outFile, tr); err != nil {
-				return err
-			}
+// isExecutableEntry reports whether the archive member name is the binary
+// executable itself, in any folder of the archive. Archive names always use
+// forward slashes; directories end in one.
+func isExecutableEntry(name string, executable string) bool {
+	return !strings.HasSuffix(name, "/") && path.Base(name) == executable
+}
 
-			if err := os.Chmod(destPath, 0755); err != nil {
-				return err
-			}
+// systemTarTimeout bounds each system tar run, so a stuck tar can't hang the
+// installation
+var systemTarTimeout = 5 * time.Minute
 
-			return nil
+// extractFFMPEGWithSystemTar extracts only the ffmpeg binary from a tar.xz
+// archive by shelling out to the system tar (tar -xJf)
+func extractFFMPEGWithSystemTar(tarPath, destDir string, progressFn func(string)) error {
+	tarBin, err := exec.LookPath("tar")
+	if err != nil {
+		return fmt.Errorf("extracting %s requires the system 'tar' command, which was not found: install tar (with xz support) or install ffmpeg manually", filepath.Base(tarPath))
+	}
+
+	// List the archive to find the ffmpeg binary's path inside it
+	ctx, cancel := context.WithTimeout(context.Background(), systemTarTimeout)
+	defer cancel()
+	listing, err := exec.CommandContext(ctx, tarBin, "-tJf", tarPath).Output()
+	if ctx.Err() != nil {
+		return fmt.Errorf("listing %s with system tar timed out after %s", filepath.Base(tarPath), systemTarTimeout)
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("%w: failed to list archive: %s", ErrArchiveCorrupt, strings.TrimSpace(string(exitErr.Stderr)))
 		}
+		return fmt.Errorf("failed to list archive: %w", err)
+	}
+
+	var member string
+	for _, name := range strings.Split(string(listing), "\n") {
+		name = strings.TrimSpace(name)
+		if isExecutableEntry(name, "ffmpeg") {
+			member = name
+			break
+		}
+	}
+	if member == "" {
+		return ErrBinaryNotInArchive
+	}
+
+	workDir, err := os.MkdirTemp("", "ffmpeg-extract-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	if progressFn != nil {
+		progressFn(fmt.Sprintf("Extracting %s with system tar...", member))
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), systemTarTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, tarBin, "-xJf", tarPath, "-C", workDir, member).CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("extracting %s with system tar timed out after %s", filepath.Base(tarPath), systemTarTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: tar extraction failed: %v: %s", ErrArchiveCorrupt, err, strings.TrimSpace(string(output)))
+	}
+
+	src, err := os.Open(filepath.Join(workDir, member))
+	if err != nil {
+		return err
 	}
+	defer src.Close()
 
-	return fmt.Errorf("ffmpeg binary not found in archive")
+	return writeBinary(filepath.Join(destDir, "ffmpeg"), src)
 }
 
 // CheckInstallation verifies if yt-dlp and ffmpeg are installed
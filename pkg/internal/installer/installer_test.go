@@ -0,0 +1,174 @@
+package installer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ffmpegTree creates the layout of a static ffmpeg release below a new
+// directory and returns it
+func ffmpegTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	release := filepath.Join(root, "ffmpeg-7.0-amd64-static")
+	os.MkdirAll(filepath.Join(release, "manpages"), 0755)
+	os.WriteFile(filepath.Join(release, "manpages", "ffmpeg.txt"), []byte("manual"), 0644)
+	os.WriteFile(filepath.Join(release, "ffprobe"), []byte("probe"), 0755)
+	os.WriteFile(filepath.Join(release, "ffmpeg"), []byte("#!/bin/sh\necho ffmpeg\n"), 0755)
+	return root
+}
+
+func TestExtractFFMPEGWithSystemTar(t *testing.T) {
+	tarBin, err := exec.LookPath("tar")
+	if err != nil {
+		t.Skip("system tar not available")
+	}
+	archive := filepath.Join(t.TempDir(), "ffmpeg-release-amd64-static.tar.xz")
+	if output, err := exec.Command(tarBin, "-cJf", archive, "-C", ffmpegTree(t), "ffmpeg-7.0-amd64-static").CombinedOutput(); err != nil {
+		t.Skipf("system tar cannot create xz archives: %v: %s", err, output)
+	}
+	dest := t.TempDir()
+
+	if err := extractFFMPEGFromTar(archive, dest, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "ffmpeg"))
+	if err != nil || string(data) != "#!/bin/sh\necho ffmpeg\n" {
+		t.Errorf("extracted %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "ffmpeg")); err == nil && info.Mode()&0111 == 0 {
+		t.Errorf("extracted binary is not executable: %v", info.Mode())
+	}
+}
+
+func TestExtractFFMPEGWithSystemTarCorrupt(t *testing.T) {
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("system tar not available")
+	}
+	archive := filepath.Join(t.TempDir(), "ffmpeg.tar.xz")
+	os.WriteFile(archive, []byte("not an xz archive"), 0644)
+
+	err := extractFFMPEGFromTar(archive, t.TempDir(), nil)
+	if !errors.Is(err, ErrArchiveCorrupt) {
+		t.Errorf("err = %v, want ErrArchiveCorrupt", err)
+	}
+}
+
+func TestExtractFFMPEGWithSystemTarSkipsDirectories(t *testing.T) {
+	tarBin, err := exec.LookPath("tar")
+	if err != nil {
+		t.Skip("system tar not available")
+	}
+	// A directory named ffmpeg is listed before the binary
+	root := t.TempDir()
+	release := filepath.Join(root, "release")
+	os.MkdirAll(filepath.Join(release, "ffmpeg"), 0755)
+	os.WriteFile(filepath.Join(release, "ffmpeg", "README"), []byte("docs"), 0644)
+	os.MkdirAll(filepath.Join(release, "zbin"), 0755)
+	os.WriteFile(filepath.Join(release, "zbin", "ffmpeg"), []byte("binary"), 0755)
+	archive := filepath.Join(t.TempDir(), "ffmpeg.tar.xz")
+	if output, err := exec.Command(tarBin, "-cJf", archive, "-C", root, "release").CombinedOutput(); err != nil {
+		t.Skipf("system tar cannot create xz archives: %v: %s", err, output)
+	}
+	dest := t.TempDir()
+
+	if err := extractFFMPEGWithSystemTar(archive, dest, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "ffmpeg")); err != nil || string(data) != "binary" {
+		t.Errorf("extracted %q, %v", data, err)
+	}
+}
+
+func TestExtractFFMPEGWithSystemTarTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tar is a shell script")
+	}
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not available")
+	}
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "tar"), []byte("#!/bin/sh\nexec "+sleep+" 10\n"), 0755)
+	t.Setenv("PATH", bin)
+	old := systemTarTimeout
+	systemTarTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { systemTarTimeout = old })
+	archive := filepath.Join(t.TempDir(), "ffmpeg.tar.xz")
+	os.WriteFile(archive, []byte("xz"), 0644)
+
+	start := time.Now()
+	err = extractFFMPEGWithSystemTar(archive, t.TempDir(), nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stuck tar held the install for %s", elapsed)
+	}
+}
+
+func TestExtractFFMPEGFromTarGz(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "ffmpeg.tar.gz")
+	file, _ := os.Create(archive)
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for _, entry := range []struct{ name, body string }{
+		{"release/doc/ffmpeg.html", "docs"},
+		{"release/ffmpeg", "binary"},
+	} {
+		tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0755, Size: int64(len(entry.body)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(entry.body))
+	}
+	tw.Close()
+	gz.Close()
+	file.Close()
+	dest := t.TempDir()
+
+	if err := extractFFMPEGFromTar(archive, dest, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "ffmpeg")); string(data) != "binary" {
+		t.Errorf("extracted %q, want the binary rather than the docs", data)
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n *int
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += n
+	return n, err
+}
+
+func TestDownloadFileUsesBodyWrapper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("binary contents"))
+	}))
+	defer server.Close()
+	var read int
+	SetBodyWrapper(func(r io.Reader) io.Reader { return countingReader{r, &read} })
+	t.Cleanup(func() { SetBodyWrapper(nil) })
+
+	dst := filepath.Join(t.TempDir(), "yt-dlp")
+	if err := downloadFile(server.URL, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "binary contents" || read != len(data) {
+		t.Errorf("wrote %q, %d bytes through the wrapper", data, read)
+	}
+}
@@ -0,0 +1,90 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	pinsMu sync.RWMutex
+	// pinnedKeys maps a download host to the base64 SHA-256 hashes of the
+	// SubjectPublicKeyInfo it may present (anywhere in its verified chain, or
+	// as the leaf when verification is disabled)
+	pinnedKeys = make(map[string][]string)
+)
+
+// SetPinnedKeys pins the TLS public keys host (e.g. "github.com") may present
+// when downloading binaries. Each pin is the base64 SHA-256 of a certificate's
+// SubjectPublicKeyInfo, as printed by:
+//
+//	openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+//
+// Pinning an intermediate keeps working when the leaf is renewed, but only
+// while certificates are verified; with verification disabled just the leaf's
+// key is trusted. Redirect
+// targets (GitHub serves releases from objects.githubusercontent.com) are
+// separate hosts and need their own pins. No pins removes pinning for host.
+func SetPinnedKeys(host string, pins []string) error {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return fmt.Errorf("host is required")
+	}
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return fmt.Errorf("invalid pin %q: expected a base64 SHA-256 hash", pin)
+		}
+	}
+
+	pinsMu.Lock()
+	defer pinsMu.Unlock()
+	if len(pins) == 0 {
+		delete(pinnedKeys, host)
+		return nil
+	}
+	pinnedKeys[host] = append([]string(nil), pins...)
+	return nil
+}
+
+// verifyPinnedKeys rejects connections to a pinned host whose verified chain
+// contains none of its pinned keys
+func verifyPinnedKeys(state tls.ConnectionState) error {
+	pinsMu.RLock()
+	pins := pinnedKeys[strings.ToLower(state.ServerName)]
+	pinsMu.RUnlock()
+	if len(pins) == 0 {
+		return nil
+	}
+
+	chains := state.VerifiedChains
+	if len(chains) == 0 {
+		// InsecureSkipVerify skips chain building. The server only proved it
+		// holds the leaf's key; any other certificate it sent could be a copy.
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("%s presented no certificate", state.ServerName)
+		}
+		chains = [][]*x509.Certificate{state.PeerCertificates[:1]}
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			hash := spkiHash(cert)
+			for _, pin := range pins {
+				if pin == hash {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("certificate of %s does not match any pinned key", state.ServerName)
+}
+
+// spkiHash returns the base64 SHA-256 of cert's SubjectPublicKeyInfo
+func spkiHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,169 @@
+package installer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pinnedServer starts a TLS server whose certificate chain has extra
+// appended after the leaf, and removes every pin and client change when the
+// test ends
+func pinnedServer(t *testing.T, extra ...*x509.Certificate) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("binary contents"))
+	}))
+	server.StartTLS()
+	for _, cert := range extra {
+		server.TLS.Certificates[0].Certificate = append(server.TLS.Certificates[0].Certificate, cert.Raw)
+	}
+	t.Cleanup(func() {
+		server.Close()
+		SetPinnedKeys(pinnedHost, nil)
+		SetHTTPClient(nil)
+	})
+	return server
+}
+
+// pinnedHost is the host the tests pin; the test server's certificate is
+// valid for it
+const pinnedHost = "example.com"
+
+// useServerClient installs a client sending requests for pinnedHost to
+// server, optionally without certificate verification
+func useServerClient(server *httptest.Server, insecure bool) {
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = insecure
+	SetHTTPClient(&http.Client{Transport: transport})
+}
+
+// foreignCert returns a certificate for a key the test server does not hold
+func foreignCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Pinned Intermediate"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// fetch downloads from pinnedHost with the installer's client
+func fetch(t *testing.T) error {
+	t.Helper()
+	return downloadFile("https://"+pinnedHost+"/yt-dlp", filepath.Join(t.TempDir(), "yt-dlp"), nil)
+}
+
+func TestSetPinnedKeysValidation(t *testing.T) {
+	t.Cleanup(func() { SetPinnedKeys("example.com", nil) })
+	if err := SetPinnedKeys("", []string{strings.Repeat("A", 43) + "="}); err == nil {
+		t.Error("empty host accepted")
+	}
+	for _, pin := range []string{"not base64!", "c2hvcnQ=", ""} {
+		if err := SetPinnedKeys("example.com", []string{pin}); err == nil {
+			t.Errorf("pin %q accepted", pin)
+		}
+	}
+	if err := SetPinnedKeys(" Example.COM ", []string{strings.Repeat("A", 43) + "="}); err != nil {
+		t.Fatal(err)
+	}
+	if len(pinnedKeys["example.com"]) != 1 {
+		t.Errorf("pins = %v, want them under the normalized host", pinnedKeys)
+	}
+}
+
+func TestPinnedKeyMatching(t *testing.T) {
+	server := pinnedServer(t)
+	useServerClient(server, false)
+	if err := SetPinnedKeys(pinnedHost, []string{spkiHash(server.Certificate())}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetch(t); err != nil {
+		t.Errorf("download with the pinned key failed: %v", err)
+	}
+}
+
+func TestPinnedKeyMismatch(t *testing.T) {
+	server := pinnedServer(t)
+	useServerClient(server, false)
+	if err := SetPinnedKeys(pinnedHost, []string{spkiHash(foreignCert(t))}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetch(t); err == nil || !strings.Contains(err.Error(), "pinned key") {
+		t.Errorf("err = %v, want a pin mismatch", err)
+	}
+
+	// Removing the pin lets the server through again
+	SetPinnedKeys(pinnedHost, nil)
+	if err := fetch(t); err != nil {
+		t.Errorf("download without pins failed: %v", err)
+	}
+}
+
+func TestPinnedKeyWithoutVerification(t *testing.T) {
+	// The server appends a certificate carrying the pinned key, without holding
+	// that key. With verification off only the leaf may match.
+	pinned := foreignCert(t)
+	server := pinnedServer(t, pinned)
+	useServerClient(server, true)
+
+	if err := SetPinnedKeys(pinnedHost, []string{spkiHash(pinned)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetch(t); err == nil || !strings.Contains(err.Error(), "pinned key") {
+		t.Errorf("err = %v, want the appended certificate ignored", err)
+	}
+
+	if err := SetPinnedKeys(pinnedHost, []string{spkiHash(server.Certificate())}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetch(t); err != nil {
+		t.Errorf("download with the pinned leaf failed: %v", err)
+	}
+}
+
+func TestSetHTTPClientKeepsOwnVerifyConnection(t *testing.T) {
+	server := pinnedServer(t)
+	useServerClient(server, false)
+	transport := HTTPClient().Transport.(*http.Transport)
+	transport.TLSClientConfig.VerifyConnection = func(tls.ConnectionState) error { return nil }
+	SetHTTPClient(&http.Client{Transport: transport})
+
+	if err := SetPinnedKeys(pinnedHost, []string{spkiHash(foreignCert(t))}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetch(t); err != nil {
+		t.Errorf("client's own VerifyConnection replaced: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimiter is a per-client-IP token bucket limiter
+type rateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket holds the remaining requests of one client
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// idleBucketTTL is how long an idle client's bucket is kept before being dropped
+const idleBucketTTL = 10 * time.Minute
+
+// newRateLimiter allows perMinute requests per client IP, with bursts up to
+// perMinute. A perMinute of 0 or less disables limiting.
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{perMinute: perMinute, clients: make(map[string]*tokenBucket)}
+}
+
+// intFromEnv reads an integer setting (e.g. a requests-per-minute limit) from the environment
+func intFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: Invalid %s %q, using %d", name, value, fallback)
+		return fallback
+	}
+	return limit
+}
+
+// allow takes a token for ip, returning how long to wait if none is left
+func (l *rateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > idleBucketTTL {
+		for key, bucket := range l.clients {
+			if now.Sub(bucket.last) > idleBucketTTL {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	capacity := float64(l.perMinute)
+	ratePerSecond := capacity / 60
+
+	bucket, ok := l.clients[ip]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		l.clients[ip] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*ratePerSecond)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bucket.tokens) / ratePerSecond * float64(time.Second))
+	return false, wait
+}
+
+// middleware rejects requests over the limit with 429 and a Retry-After header
+func (l *rateLimiter) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.perMinute <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, wait := l.allow(c.ClientIP(), time.Now())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(429, gin.H{"error": "Too many requests, please slow down"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitedRouter serves /limited behind a limiter of perMinute requests
+func limitedRouter(perMinute int) *gin.Engine {
+	router := gin.New()
+	router.GET("/limited", newRateLimiter(perMinute).middleware(), func(c *gin.Context) {
+		c.Status(200)
+	})
+	return router
+}
+
+// get issues a GET from the client at remoteAddr
+func get(router *gin.Engine, target string, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitRejectsRapidRequests(t *testing.T) {
+	router := limitedRouter(3)
+
+	for i := 0; i < 3; i++ {
+		if w := get(router, "/limited", "192.0.2.1:1234"); w.Code != 200 {
+			t.Fatalf("request %d: status %d, want 200", i+1, w.Code)
+		}
+	}
+	w := get(router, "/limited", "192.0.2.1:1234")
+	if w.Code != 429 {
+		t.Fatalf("status %d, want 429", w.Code)
+	}
+	// One token comes back every 20s at 3 per minute
+	if retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retryAfter < 1 || retryAfter > 20 {
+		t.Errorf("Retry-After %q, want 1-20 seconds", w.Header().Get("Retry-After"))
+	}
+
+	// Other clients have their own budget
+	if w := get(router, "/limited", "192.0.2.2:1234"); w.Code != 200 {
+		t.Errorf("other client: status %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	router := limitedRouter(0)
+	for i := 0; i < 100; i++ {
+		if w := get(router, "/limited", "192.0.2.1:1234"); w.Code != 200 {
+			t.Fatalf("request %d: status %d with limiting disabled", i+1, w.Code)
+		}
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := newRateLimiter(60)
+	now := time.Now()
+	for i := 0; i < 60; i++ {
+		if ok, _ := limiter.allow("ip", now); !ok {
+			t.Fatalf("request %d rejected within the burst", i+1)
+		}
+	}
+	if ok, wait := limiter.allow("ip", now); ok || wait != time.Second {
+		t.Errorf("over the limit: allowed %v, wait %v; want a 1s wait", ok, wait)
+	}
+	if ok, _ := limiter.allow("ip", now.Add(time.Second)); !ok {
+		t.Error("no token after waiting 1s")
+	}
+}
+
+func TestIntFromEnv(t *testing.T) {
+	t.Setenv("TEST_LIMIT", "")
+	if got := intFromEnv("TEST_LIMIT", 10); got != 10 {
+		t.Errorf("unset: %d, want 10", got)
+	}
+	t.Setenv("TEST_LIMIT", "25")
+	if got := intFromEnv("TEST_LIMIT", 10); got != 25 {
+		t.Errorf("set: %d, want 25", got)
+	}
+	t.Setenv("TEST_LIMIT", "lots")
+	if got := intFromEnv("TEST_LIMIT", 10); got != 10 {
+		t.Errorf("invalid: %d, want the fallback 10", got)
+	}
+}
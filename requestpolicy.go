@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Operator limits on what the download endpoints accept, read from the environment:
+// ALLOWED_FORMATS is a comma-separated list of output formats (default: any) and
+// MAX_RESOLUTION caps the requested height (default: no cap)
+var (
+	allowedFormats = formatsFromEnv("ALLOWED_FORMATS")
+	maxResolution  = intFromEnv("MAX_RESOLUTION", 0)
+)
+
+// formatsFromEnv reads a comma-separated format list; nil allows every format
+func formatsFromEnv(name string) map[string]bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	formats := make(map[string]bool)
+	for _, format := range strings.Split(value, ",") {
+		if format = strings.ToLower(strings.TrimSpace(format)); format != "" {
+			formats[format] = true
+		}
+	}
+	if len(formats) == 0 {
+		log.Printf("Warning: %s is empty, allowing every format", name)
+		return nil
+	}
+	return formats
+}
+
+// applyDefaults fills in empty fields and checks the request against the
+// operator's allowed formats and maximum resolution. The returned error is
+// meant for a 400 response.
+func (req *DownloadRequest) applyDefaults() error {
+	if req.Format == "" {
+		req.Format = "mp4"
+	}
+	if req.Resolution == "" {
+		req.Resolution = "720"
+	}
+	if req.Codec == "" {
+		req.Codec = "avc1"
+	}
+
+	if allowedFormats != nil && !allowedFormats[strings.ToLower(req.Format)] {
+		return fmt.Errorf("format %q is not allowed", req.Format)
+	}
+	height, err := strconv.Atoi(req.Resolution)
+	if err != nil || height <= 0 {
+		return fmt.Errorf("invalid resolution %q", req.Resolution)
+	}
+	if maxResolution > 0 && height > maxResolution {
+		return fmt.Errorf("resolution %sp exceeds the maximum of %dp", req.Resolution, maxResolution)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postJSON runs handler for a single POST request with body and returns the
+// recorded response
+func postJSON(handler gin.HandlerFunc, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/", handler)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// usePolicy restricts the download endpoints for the rest of the test
+func usePolicy(t *testing.T, formats map[string]bool, resolution int) {
+	t.Helper()
+	oldFormats, oldResolution := allowedFormats, maxResolution
+	allowedFormats, maxResolution = formats, resolution
+	t.Cleanup(func() { allowedFormats, maxResolution = oldFormats, oldResolution })
+}
+
+func TestFormatsFromEnv(t *testing.T) {
+	t.Setenv("TEST_ALLOWED_FORMATS", " MP4, webm ,,")
+	if got := formatsFromEnv("TEST_ALLOWED_FORMATS"); len(got) != 2 || !got["mp4"] || !got["webm"] {
+		t.Errorf("formatsFromEnv = %v, want mp4 and webm", got)
+	}
+	t.Setenv("TEST_ALLOWED_FORMATS", " , ")
+	if got := formatsFromEnv("TEST_ALLOWED_FORMATS"); got != nil {
+		t.Errorf("formatsFromEnv = %v for an empty list, want nil", got)
+	}
+}
+
+func TestApplyDefaultsPolicy(t *testing.T) {
+	usePolicy(t, map[string]bool{"mp4": true}, 1080)
+	tests := []struct {
+		req DownloadRequest
+		ok  bool
+	}{
+		{DownloadRequest{}, true}, // mp4 at 720p
+		{DownloadRequest{Format: "MP4", Resolution: "1080"}, true},
+		{DownloadRequest{Format: "webm"}, false},
+		{DownloadRequest{Resolution: "4320"}, false},
+		{DownloadRequest{Resolution: "high"}, false},
+		{DownloadRequest{Resolution: "-1"}, false},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		if err := req.applyDefaults(); (err == nil) != tt.ok {
+			t.Errorf("applyDefaults(%+v) = %v", tt.req, err)
+		}
+	}
+}
+
+func TestHandlersEnforcePolicy(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	useFakeYTDLP(t, `echo call >> '`+calls+`'
+echo '{"id": "dQw4w9WgXcQ", "title": "Video", "formats": []}'`)
+	usePolicy(t, map[string]bool{"mp4": true, "mp3": true}, 1080)
+
+	disallowed := []struct {
+		name    string
+		handler gin.HandlerFunc
+		body    string
+	}{
+		{"download format", downloadStreamHandler, `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "format": "webm"}`},
+		{"download 8K", downloadStreamHandler, `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "resolution": "4320"}`},
+		{"info format", downloadInfoHandler, `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "format": "mkv"}`},
+		{"info 8K", downloadInfoHandler, `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "resolution": "4320"}`},
+		{"audio format", downloadAudioStreamHandler, `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "format": "m4a"}`},
+	}
+	for _, tt := range disallowed {
+		if w := postJSON(tt.handler, tt.body); w.Code != 400 || !strings.Contains(w.Body.String(), "not allowed") && !strings.Contains(w.Body.String(), "exceeds") {
+			t.Errorf("%s: status %d, body %s; want a 400 policy error", tt.name, w.Code, w.Body)
+		}
+	}
+	if _, err := os.Stat(calls); !os.IsNotExist(err) {
+		t.Error("yt-dlp ran for a disallowed request")
+	}
+
+	w := postJSON(downloadInfoHandler, `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "format": "mp4", "resolution": "1080"}`)
+	if w.Code != 200 {
+		t.Fatalf("allowed request: status %d, body %s", w.Code, w.Body)
+	}
+	var resp DownloadResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !resp.Success {
+		t.Errorf("allowed request: %s, %v", w.Body, err)
+	}
+}